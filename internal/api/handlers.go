@@ -2,13 +2,18 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"mybench/internal/crypto"
 	"mybench/internal/database"
@@ -24,8 +29,9 @@ type Handlers struct {
 	Vault   *crypto.Vault
 	ConnMgr *database.Manager
 
-	cancelMu sync.Mutex
-	cancels  map[string]context.CancelFunc
+	cancelMu   sync.Mutex
+	cancels    map[string]context.CancelFunc
+	queryConns map[string]int64 // tab ID -> MySQL connection ID of the running query
 
 	// SSE: per-tab event channels
 	sseMu    sync.Mutex
@@ -38,13 +44,21 @@ type sseEvent struct {
 }
 
 func NewHandlers(version string, s *store.Store, connMgr *database.Manager) *Handlers {
-	return &Handlers{
-		Version: version,
-		Store:   s,
-		ConnMgr: connMgr,
-		cancels: make(map[string]context.CancelFunc),
-		sseChans: make(map[string][]chan sseEvent),
+	h := &Handlers{
+		Version:    version,
+		Store:      s,
+		ConnMgr:    connMgr,
+		cancels:    make(map[string]context.CancelFunc),
+		queryConns: make(map[string]int64),
+		sseChans:   make(map[string][]chan sseEvent),
+	}
+	if connMgr != nil {
+		connMgr.OnEvent = h.emitEvent
+		if s != nil {
+			h.applyKeepAlive()
+		}
 	}
+	return h
 }
 
 func (h *Handlers) Shutdown() {
@@ -69,6 +83,32 @@ func (h *Handlers) emitEvent(tabID, event string, data interface{}) {
 	}
 }
 
+// sseSendTimeout bounds how long sendEvent waits on a listener that has
+// stopped reading.
+const sseSendTimeout = 10 * time.Second
+
+// sendEvent delivers an event to every listener for a tab, waiting for
+// slow ones where emitEvent would drop it. It fails when no listener is
+// connected, ctx is done, or a listener stops reading.
+func (h *Handlers) sendEvent(ctx context.Context, tabID, event string, data interface{}) error {
+	h.sseMu.Lock()
+	chans := append([]chan sseEvent(nil), h.sseChans[tabID]...)
+	h.sseMu.Unlock()
+	if len(chans) == 0 {
+		return fmt.Errorf("no event stream is open for tab %s", tabID)
+	}
+	for _, ch := range chans {
+		select {
+		case ch <- sseEvent{Event: event, Data: data}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sseSendTimeout):
+			return fmt.Errorf("event stream for tab %s stopped reading", tabID)
+		}
+	}
+	return nil
+}
+
 // --- Health ---
 
 func (h *Handlers) ping(c echo.Context) error {
@@ -146,29 +186,115 @@ func (h *Handlers) vaultUnlock(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]bool{"ok": false})
 	}
 
-	h.Vault = crypto.NewVault(body.Password, salt)
+	// Derive the key with the parameters the vault was created with.
+	params, err := crypto.HashParams(hash)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	h.Vault = crypto.NewVaultWithParams(body.Password, salt, params)
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// --- Settings ---
+
+// settingDefaults lists the user-tunable app_config keys and their defaults.
+// All settings are non-negative integers.
+var settingDefaults = map[string]int{
+	"query_timeout_seconds":           0,      // 0 disables the timeout
+	"page_size":                       1000,   // rows per page for SELECTs without a LIMIT; 0 disables paging
+	"keepalive_seconds":               60,     // connection heartbeat interval; 0 disables it
+	"max_cell_length":                 1024,   // bytes of a text cell sent to the grid; 0 sends them whole
+	"max_result_rows":                 100000, // rows a SELECT may return before it is cut off; 0 is unlimited
+	"test_connection_timeout_seconds": 5,      // how long Test Connection waits; 0 uses the default
+}
+
+// settingInt returns a setting's stored value, or its default.
+func (h *Handlers) settingInt(key string) int {
+	n, err := h.Store.GetConfigInt(key, settingDefaults[key])
+	if err != nil {
+		return settingDefaults[key]
+	}
+	return n
+}
+
+// applyKeepAlive (re)starts the connection heartbeat with the configured
+// interval.
+func (h *Handlers) applyKeepAlive() {
+	h.ConnMgr.SetHeartbeat(time.Duration(h.settingInt("keepalive_seconds")) * time.Second)
+}
+
+func (h *Handlers) getSettings(c echo.Context) error {
+	settings := make(map[string]int, len(settingDefaults))
+	for key := range settingDefaults {
+		settings[key] = h.settingInt(key)
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handlers) updateSettings(c echo.Context) error {
+	var body map[string]int
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	for key, val := range body {
+		if _, ok := settingDefaults[key]; !ok {
+			return jsonErr(c, fmt.Errorf("unknown setting: %s", key))
+		}
+		if val < 0 {
+			return jsonErr(c, fmt.Errorf("setting %s must not be negative", key))
+		}
+	}
+	for key, val := range body {
+		if err := h.Store.SetConfig(key, strconv.Itoa(val)); err != nil {
+			return jsonErr(c, err)
+		}
+	}
+	if _, ok := body["keepalive_seconds"]; ok {
+		h.applyKeepAlive()
+	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
 // --- Connections ---
 
 type connectionProfile struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Host       string `json:"host"`
-	Port       int    `json:"port"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	DefaultDB  string `json:"defaultDb"`
-	UseSSL     bool   `json:"useSsl"`
-	SSHEnabled bool   `json:"sshEnabled"`
-	SSHHost    string `json:"sshHost"`
-	SSHPort    int    `json:"sshPort"`
-	SSHUser    string `json:"sshUser"`
-	SSHAuth    string `json:"sshAuth"`
-	SSHKeyPath string `json:"sshKeyPath"`
-	SSHPass    string `json:"sshPassword"`
-	SortOrder  int    `json:"sortOrder"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	SocketPath  string `json:"socketPath"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DefaultDB   string `json:"defaultDb"`
+	UseSSL      bool   `json:"useSsl"`
+	SSLMode     string `json:"sslMode"`
+	SSLCAPath   string `json:"sslCaPath"`
+	SSLCertPath string `json:"sslCertPath"`
+	SSLKeyPath  string `json:"sslKeyPath"`
+	SSHEnabled  bool   `json:"sshEnabled"`
+	SSHHost     string `json:"sshHost"`
+	SSHPort     int    `json:"sshPort"`
+	SSHUser     string `json:"sshUser"`
+	SSHAuth     string `json:"sshAuth"`
+	SSHKeyPath  string `json:"sshKeyPath"`
+	SSHPass     string `json:"sshPassword"`
+	Color       string `json:"color"`
+	Environment string `json:"environment"`
+	ReadOnly    bool   `json:"readOnly"`
+	GroupID     string `json:"groupId"`
+	SortOrder   int    `json:"sortOrder"`
+
+	MaxOpenConns           int `json:"maxOpenConns"`
+	MaxIdleConns           int `json:"maxIdleConns"`
+	ConnMaxLifetimeSeconds int `json:"connMaxLifetimeSeconds"`
+
+	Charset   string `json:"charset"`
+	Collation string `json:"collation"`
+	TimeZone  string `json:"timeZone"`
+
+	InitialSQL string `json:"initialSql"`
+
+	SSHPassphrase string `json:"sshKeyPassphrase"`
 }
 
 func (h *Handlers) listConnections(c echo.Context) error {
@@ -179,345 +305,1572 @@ func (h *Handlers) listConnections(c echo.Context) error {
 
 	result := make([]connectionProfile, len(conns))
 	for i, conn := range conns {
-		pwd := conn.Password
-		sshPwd := conn.SSHPass
-		if h.Vault != nil {
-			if dec, err := h.Vault.Decrypt(pwd); err == nil {
-				pwd = dec
-			}
-			if dec, err := h.Vault.Decrypt(sshPwd); err == nil {
-				sshPwd = dec
-			}
-		}
-		result[i] = connectionProfile{
-			ID:         conn.ID,
-			Name:       conn.Name,
-			Host:       conn.Host,
-			Port:       conn.Port,
-			Username:   conn.Username,
-			Password:   pwd,
-			DefaultDB:  conn.DefaultDB,
-			UseSSL:     conn.UseSSL,
-			SSHEnabled: conn.SSHEnabled,
-			SSHHost:    conn.SSHHost,
-			SSHPort:    conn.SSHPort,
-			SSHUser:    conn.SSHUser,
-			SSHAuth:    conn.SSHAuth,
-			SSHKeyPath: conn.SSHKeyPath,
-			SSHPass:    sshPwd,
-			SortOrder:  conn.SortOrder,
-		}
+		result[i] = h.decryptProfile(conn)
+	}
+	if c.QueryParam("grouped") != "true" {
+		return c.JSON(http.StatusOK, result)
 	}
-	return c.JSON(http.StatusOK, result)
-}
 
-func (h *Handlers) saveConnection(c echo.Context) error {
-	var cp connectionProfile
-	if err := c.Bind(&cp); err != nil {
+	// Grouped form for the sidebar tree: each group with its connections,
+	// then the connections not in any group.
+	groups, err := h.Store.ListGroups()
+	if err != nil {
 		return jsonErr(c, err)
 	}
-	id, err := h.saveConn(cp)
+	type groupNode struct {
+		store.ConnectionGroup
+		Connections []connectionProfile `json:"connections"`
+	}
+	nodes := make([]groupNode, len(groups))
+	index := make(map[string]int, len(groups))
+	for i, g := range groups {
+		nodes[i] = groupNode{ConnectionGroup: g, Connections: []connectionProfile{}}
+		index[g.ID] = i
+	}
+	ungrouped := []connectionProfile{}
+	for _, cp := range result {
+		if i, ok := index[cp.GroupID]; ok {
+			nodes[i].Connections = append(nodes[i].Connections, cp)
+		} else {
+			ungrouped = append(ungrouped, cp)
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"groups": nodes, "ungrouped": ungrouped})
+}
+
+func (h *Handlers) listGroups(c echo.Context) error {
+	groups, err := h.Store.ListGroups()
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, map[string]string{"id": id})
+	return c.JSON(http.StatusOK, groups)
 }
 
-func (h *Handlers) updateConnection(c echo.Context) error {
-	var cp connectionProfile
-	if err := c.Bind(&cp); err != nil {
+func (h *Handlers) createGroup(c echo.Context) error {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
 	}
-	cp.ID = c.Param("id")
-	id, err := h.saveConn(cp)
+	if body.Name == "" {
+		return jsonErr(c, errors.New("group needs a name"))
+	}
+	g, err := h.Store.CreateGroup(body.Name)
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, map[string]string{"id": id})
+	return c.JSON(http.StatusOK, g)
 }
 
-func (h *Handlers) saveConn(cp connectionProfile) (string, error) {
-	pwd := cp.Password
-	sshPwd := cp.SSHPass
-	if h.Vault != nil {
-		if enc, err := h.Vault.Encrypt(pwd); err == nil {
-			pwd = enc
-		}
-		if enc, err := h.Vault.Encrypt(sshPwd); err == nil {
-			sshPwd = enc
-		}
+func (h *Handlers) renameGroup(c echo.Context) error {
+	var body struct {
+		Name string `json:"name"`
 	}
-
-	sc := &store.ConnectionProfile{
-		ID:         cp.ID,
-		Name:       cp.Name,
-		Host:       cp.Host,
-		Port:       cp.Port,
-		Username:   cp.Username,
-		Password:   pwd,
-		DefaultDB:  cp.DefaultDB,
-		UseSSL:     cp.UseSSL,
-		SSHEnabled: cp.SSHEnabled,
-		SSHHost:    cp.SSHHost,
-		SSHPort:    cp.SSHPort,
-		SSHUser:    cp.SSHUser,
-		SSHAuth:    cp.SSHAuth,
-		SSHKeyPath: cp.SSHKeyPath,
-		SSHPass:    sshPwd,
-		SortOrder:  cp.SortOrder,
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
 	}
-
-	if err := h.Store.SaveConnection(sc); err != nil {
-		return "", err
+	if body.Name == "" {
+		return jsonErr(c, errors.New("group needs a name"))
 	}
-	return sc.ID, nil
-}
-
-func (h *Handlers) deleteConnection(c echo.Context) error {
-	id := c.Param("id")
-	if err := h.Store.DeleteConnection(id); err != nil {
+	if err := h.Store.RenameGroup(c.Param("id"), body.Name); err != nil {
 		return jsonErr(c, err)
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
-func (h *Handlers) testConnection(c echo.Context) error {
-	var cp connectionProfile
-	if err := c.Bind(&cp); err != nil {
-		return jsonErr(c, err)
-	}
-
-	cfg := database.ConnConfig{
-		Host:     cp.Host,
-		Port:     cp.Port,
-		Username: cp.Username,
-		Password: cp.Password,
-		Database: cp.DefaultDB,
-		UseSSL:   cp.UseSSL,
-	}
-
-	err := h.ConnMgr.Connect("__test__", "", cfg)
-	if err != nil {
+func (h *Handlers) deleteGroup(c echo.Context) error {
+	if err := h.Store.DeleteGroup(c.Param("id")); err != nil {
 		return jsonErr(c, err)
 	}
-	h.ConnMgr.Disconnect("__test__")
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
-// --- Tabs / Active Connections ---
-
-func (h *Handlers) connect(c echo.Context) error {
-	tabID := c.Param("id")
+func (h *Handlers) assignConnectionGroup(c echo.Context) error {
 	var body struct {
-		ProfileID string `json:"profileId"`
+		GroupID string `json:"groupId"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
 	}
-
-	conns, err := h.Store.ListConnections()
-	if err != nil {
+	if err := h.Store.AssignConnectionGroup(c.Param("id"), body.GroupID); err != nil {
 		return jsonErr(c, err)
 	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
 
-	var profile *store.ConnectionProfile
-	for _, conn := range conns {
-		if conn.ID == body.ProfileID {
-			profile = &conn
-			break
-		}
-	}
-	if profile == nil {
-		return jsonErr(c, fmt.Errorf("connection profile not found: %s", body.ProfileID))
-	}
+// --- Pinned objects ---
 
-	pwd := profile.Password
-	if h.Vault != nil {
-		if dec, err := h.Vault.Decrypt(pwd); err == nil {
-			pwd = dec
-		}
+func (h *Handlers) listPins(c echo.Context) error {
+	pins, err := h.Store.ListPins(c.Param("id"))
+	if err != nil {
+		return jsonErr(c, err)
 	}
+	return c.JSON(http.StatusOK, pins)
+}
 
-	cfg := database.ConnConfig{
-		Host:     profile.Host,
-		Port:     profile.Port,
-		Username: profile.Username,
-		Password: pwd,
-		Database: profile.DefaultDB,
-		UseSSL:   profile.UseSSL,
+func (h *Handlers) addPin(c echo.Context) error {
+	var p store.PinnedObject
+	if err := c.Bind(&p); err != nil {
+		return jsonErr(c, err)
 	}
-
-	if err := h.ConnMgr.Connect(tabID, body.ProfileID, cfg); err != nil {
+	p.ProfileID = c.Param("id")
+	if err := h.Store.AddPin(&p); err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+	return c.JSON(http.StatusOK, p)
 }
 
-func (h *Handlers) disconnect(c echo.Context) error {
-	tabID := c.Param("id")
-	if err := h.ConnMgr.Disconnect(tabID); err != nil {
+func (h *Handlers) reorderPins(c echo.Context) error {
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := h.Store.ReorderPins(c.Param("id"), body.IDs); err != nil {
 		return jsonErr(c, err)
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
-func (h *Handlers) pingConnection(c echo.Context) error {
-	tabID := c.Param("id")
-	if err := h.ConnMgr.Ping(tabID); err != nil {
+func (h *Handlers) deletePin(c echo.Context) error {
+	if err := h.Store.DeletePin(c.Param("id"), c.Param("pinId")); err != nil {
 		return jsonErr(c, err)
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
-// --- Schema ---
+// --- Recently used ---
 
-func (h *Handlers) getConn(c echo.Context) (*database.Connection, error) {
-	tabID := c.Param("id")
-	conn := h.ConnMgr.Get(tabID)
-	if conn == nil {
-		return nil, fmt.Errorf("not connected on tab %s", tabID)
+func (h *Handlers) listRecent(c echo.Context) error {
+	recent, err := h.Store.ListRecent(c.Param("id"))
+	if err != nil {
+		return jsonErr(c, err)
 	}
-	return conn, nil
+	return c.JSON(http.StatusOK, recent)
 }
 
-func (h *Handlers) getDatabases(c echo.Context) error {
-	conn, err := h.getConn(c)
-	if err != nil {
+func (h *Handlers) clearRecent(c echo.Context) error {
+	if err := h.Store.ClearRecent(c.Param("id")); err != nil {
 		return jsonErr(c, err)
 	}
-	dbs, err := database.ListDatabases(conn.DB)
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// --- SSH host keys ---
+
+func (h *Handlers) listSSHHostKeys(c echo.Context) error {
+	keys, err := h.Store.ListSSHHostKeys()
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, dbs)
+	return c.JSON(http.StatusOK, keys)
 }
 
-func (h *Handlers) getTables(c echo.Context) error {
-	conn, err := h.getConn(c)
-	if err != nil {
-		return jsonErr(c, err)
+// acceptSSHHostKey trusts the host key a connection attempt reported, as
+// the host and key of the error's hostKey.
+func (h *Handlers) acceptSSHHostKey(c echo.Context) error {
+	var body struct {
+		Host string `json:"host"`
+		Key  string `json:"key"`
 	}
-	tables, err := database.ListTables(conn.DB, c.Param("db"))
-	if err != nil {
+	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, tables)
-}
-
-func (h *Handlers) getTableDetail(c echo.Context) error {
-	conn, err := h.getConn(c)
+	keyType, err := database.SSHKeyType(body.Key)
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	detail, err := database.GetTableDetail(conn.DB, c.Param("db"), c.Param("table"))
-	if err != nil {
+	k := &store.SSHHostKey{Host: strings.TrimSpace(body.Host), KeyType: keyType, Key: strings.TrimSpace(body.Key)}
+	if err := h.Store.SaveSSHHostKey(k); err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, detail)
+	return c.JSON(http.StatusOK, k)
 }
 
-func (h *Handlers) getTableColumns(c echo.Context) error {
-	conn, err := h.getConn(c)
-	if err != nil {
+func (h *Handlers) deleteSSHHostKey(c echo.Context) error {
+	if err := h.Store.DeleteSSHHostKey(c.QueryParam("host"), c.QueryParam("keyType")); err != nil {
 		return jsonErr(c, err)
 	}
-	detail, err := database.GetTableDetail(conn.DB, c.Param("db"), c.Param("table"))
-	if err != nil {
-		return jsonErr(c, err)
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// decryptProfile converts a stored profile into its API form, decrypting
+// passwords when the vault is unlocked.
+func (h *Handlers) decryptProfile(conn store.ConnectionProfile) connectionProfile {
+	pwd := conn.Password
+	sshPwd := conn.SSHPass
+	sshPhrase := conn.SSHPassphrase
+	if h.Vault != nil {
+		if dec, err := h.Vault.Decrypt(pwd); err == nil {
+			pwd = dec
+		}
+		if dec, err := h.Vault.Decrypt(sshPwd); err == nil {
+			sshPwd = dec
+		}
+		if dec, err := h.Vault.Decrypt(sshPhrase); err == nil {
+			sshPhrase = dec
+		}
 	}
-	cols := make([]string, len(detail.Columns))
-	for i, col := range detail.Columns {
-		cols[i] = col.Name
+	return connectionProfile{
+		ID:          conn.ID,
+		Name:        conn.Name,
+		Host:        conn.Host,
+		Port:        conn.Port,
+		SocketPath:  conn.SocketPath,
+		Username:    conn.Username,
+		Password:    pwd,
+		DefaultDB:   conn.DefaultDB,
+		UseSSL:      conn.UseSSL,
+		SSLMode:     conn.SSLMode,
+		SSLCAPath:   conn.SSLCAPath,
+		SSLCertPath: conn.SSLCertPath,
+		SSLKeyPath:  conn.SSLKeyPath,
+		SSHEnabled:  conn.SSHEnabled,
+		SSHHost:     conn.SSHHost,
+		SSHPort:     conn.SSHPort,
+		SSHUser:     conn.SSHUser,
+		SSHAuth:     conn.SSHAuth,
+		SSHKeyPath:  conn.SSHKeyPath,
+		SSHPass:     sshPwd,
+		Color:       conn.Color,
+		Environment: conn.Environment,
+		ReadOnly:    conn.ReadOnly,
+		GroupID:     conn.GroupID,
+		SortOrder:   conn.SortOrder,
+
+		MaxOpenConns:           conn.MaxOpenConns,
+		MaxIdleConns:           conn.MaxIdleConns,
+		ConnMaxLifetimeSeconds: conn.ConnMaxLifetimeSeconds,
+		Charset:                conn.Charset,
+		Collation:              conn.Collation,
+		TimeZone:               conn.TimeZone,
+		InitialSQL:             conn.InitialSQL,
+		SSHPassphrase:          sshPhrase,
 	}
-	return c.JSON(http.StatusOK, cols)
 }
 
-func (h *Handlers) getRoutines(c echo.Context) error {
-	conn, err := h.getConn(c)
-	if err != nil {
-		return jsonErr(c, err)
-	}
-	routines, err := database.ListRoutines(conn.DB, c.Param("db"))
-	if err != nil {
-		return jsonErr(c, err)
+// connConfig builds the database connection parameters for a profile.
+func (cp connectionProfile) connConfig() database.ConnConfig {
+	return database.ConnConfig{
+		Host:        cp.Host,
+		Port:        cp.Port,
+		SocketPath:  cp.SocketPath,
+		Username:    cp.Username,
+		Password:    cp.Password,
+		Database:    cp.DefaultDB,
+		UseSSL:      cp.UseSSL,
+		SSLMode:     cp.SSLMode,
+		SSLCAPath:   cp.SSLCAPath,
+		SSLCertPath: cp.SSLCertPath,
+		SSLKeyPath:  cp.SSLKeyPath,
+		SSHEnabled:  cp.SSHEnabled,
+		SSHHost:     cp.SSHHost,
+		SSHPort:     cp.SSHPort,
+		SSHUser:     cp.SSHUser,
+		SSHAuth:     cp.SSHAuth,
+		SSHKeyPath:  cp.SSHKeyPath,
+		SSHPass:     cp.SSHPass,
+		ReadOnly:    cp.ReadOnly,
+
+		MaxOpenConns:           cp.MaxOpenConns,
+		MaxIdleConns:           cp.MaxIdleConns,
+		ConnMaxLifetimeSeconds: cp.ConnMaxLifetimeSeconds,
+		Charset:                cp.Charset,
+		Collation:              cp.Collation,
+		TimeZone:               cp.TimeZone,
+		InitialSQL:             cp.InitialSQL,
+		SSHPassphrase:          cp.SSHPassphrase,
 	}
-	return c.JSON(http.StatusOK, routines)
 }
 
-func (h *Handlers) getTriggers(c echo.Context) error {
-	conn, err := h.getConn(c)
-	if err != nil {
+func (h *Handlers) saveConnection(c echo.Context) error {
+	var cp connectionProfile
+	if err := c.Bind(&cp); err != nil {
 		return jsonErr(c, err)
 	}
-	triggers, err := database.ListTriggers(conn.DB, c.Param("db"))
+	id, err := h.saveConn(cp)
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, triggers)
+	return c.JSON(http.StatusOK, map[string]string{"id": id})
 }
 
-func (h *Handlers) getSchemaCompletions(c echo.Context) error {
-	conn, err := h.getConn(c)
-	if err != nil {
+func (h *Handlers) updateConnection(c echo.Context) error {
+	var cp connectionProfile
+	if err := c.Bind(&cp); err != nil {
 		return jsonErr(c, err)
 	}
-	schema, err := database.GetCompletionSchema(conn.DB)
+	cp.ID = c.Param("id")
+	id, err := h.saveConn(cp)
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, schema)
+	return c.JSON(http.StatusOK, map[string]string{"id": id})
 }
 
-// --- Queries ---
+func (h *Handlers) saveConn(cp connectionProfile) (string, error) {
+	pwd := cp.Password
+	sshPwd := cp.SSHPass
+	sshPhrase := cp.SSHPassphrase
+	if h.Vault != nil {
+		if enc, err := h.Vault.Encrypt(pwd); err == nil {
+			pwd = enc
+		}
+		if enc, err := h.Vault.Encrypt(sshPwd); err == nil {
+			sshPwd = enc
+		}
+		if enc, err := h.Vault.Encrypt(sshPhrase); err == nil {
+			sshPhrase = enc
+		}
+	}
 
-func (h *Handlers) executeQuery(c echo.Context) error {
-	tabID := c.Param("id")
-	conn, err := h.getConn(c)
-	if err != nil {
-		return jsonErr(c, err)
+	sc := &store.ConnectionProfile{
+		ID:          cp.ID,
+		Name:        cp.Name,
+		Host:        cp.Host,
+		Port:        cp.Port,
+		SocketPath:  cp.SocketPath,
+		Username:    cp.Username,
+		Password:    pwd,
+		DefaultDB:   cp.DefaultDB,
+		UseSSL:      cp.UseSSL,
+		SSLMode:     cp.SSLMode,
+		SSLCAPath:   cp.SSLCAPath,
+		SSLCertPath: cp.SSLCertPath,
+		SSLKeyPath:  cp.SSLKeyPath,
+		SSHEnabled:  cp.SSHEnabled,
+		SSHHost:     cp.SSHHost,
+		SSHPort:     cp.SSHPort,
+		SSHUser:     cp.SSHUser,
+		SSHAuth:     cp.SSHAuth,
+		SSHKeyPath:  cp.SSHKeyPath,
+		SSHPass:     sshPwd,
+		Color:       cp.Color,
+		Environment: cp.Environment,
+		ReadOnly:    cp.ReadOnly,
+		GroupID:     cp.GroupID,
+		SortOrder:   cp.SortOrder,
+
+		MaxOpenConns:           cp.MaxOpenConns,
+		MaxIdleConns:           cp.MaxIdleConns,
+		ConnMaxLifetimeSeconds: cp.ConnMaxLifetimeSeconds,
+		Charset:                cp.Charset,
+		Collation:              cp.Collation,
+		TimeZone:               cp.TimeZone,
+		InitialSQL:             cp.InitialSQL,
+		SSHPassphrase:          sshPhrase,
+	}
+
+	if err := h.Store.SaveConnection(sc); err != nil {
+		return "", err
 	}
+	return sc.ID, nil
+}
 
+func (h *Handlers) reorderConnections(c echo.Context) error {
 	var body struct {
-		SQL string `json:"sql"`
+		IDs []string `json:"ids"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
 	}
+	if err := h.Store.ReorderConnections(body.IDs); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	h.cancelMu.Lock()
-	h.cancels[tabID] = cancel
-	h.cancelMu.Unlock()
-
-	defer func() {
-		cancel()
-		h.cancelMu.Lock()
-		delete(h.cancels, tabID)
+func (h *Handlers) deleteConnection(c echo.Context) error {
+	id := c.Param("id")
+	if err := h.Store.DeleteConnection(id); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// connectionBackup is the file format of an encrypted connection export.
+// Data decrypts to a JSON array of connectionProfile with plaintext
+// passwords.
+type connectionBackup struct {
+	Format  string `json:"format"` // always "mybench-connections"
+	Version int    `json:"version"`
+	*crypto.Sealed
+}
+
+const connectionBackupFormat = "mybench-connections"
+
+// exportConnections downloads every saved connection, with passwords
+// re-encrypted under an export password instead of the master key.
+func (h *Handlers) exportConnections(c echo.Context) error {
+	var body struct {
+		Password string `json:"password"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if h.Vault == nil {
+		return jsonErr(c, errors.New("unlock the vault before exporting connections"))
+	}
+
+	conns, err := h.Store.ListConnections()
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	profiles := make([]connectionProfile, len(conns))
+	for i, conn := range conns {
+		profiles[i] = h.decryptProfile(conn)
+	}
+	plain, err := json.Marshal(profiles)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	sealed, err := crypto.Seal(body.Password, string(plain))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="mybench-connections.json"`)
+	return c.JSON(http.StatusOK, connectionBackup{Format: connectionBackupFormat, Version: 1, Sealed: sealed})
+}
+
+// importConnections restores an exportConnections file. A connection whose
+// ID already exists gets a new ID unless onConflict is "overwrite".
+func (h *Handlers) importConnections(c echo.Context) error {
+	if h.Vault == nil {
+		return jsonErr(c, errors.New("unlock the vault before importing connections"))
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return jsonErr(c, fmt.Errorf("no file uploaded: %w", err))
+	}
+	src, err := file.Open()
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	defer src.Close()
+
+	var backup connectionBackup
+	if err := json.NewDecoder(src).Decode(&backup); err != nil {
+		return jsonErr(c, fmt.Errorf("invalid connections file: %w", err))
+	}
+	if backup.Format != connectionBackupFormat || backup.Sealed == nil {
+		return jsonErr(c, errors.New("not a mybench connections export"))
+	}
+	if backup.Version != 1 {
+		return jsonErr(c, fmt.Errorf("unsupported connections export version %d", backup.Version))
+	}
+
+	plain, err := backup.Open(c.FormValue("password"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var profiles []connectionProfile
+	if err := json.Unmarshal([]byte(plain), &profiles); err != nil {
+		return jsonErr(c, fmt.Errorf("invalid connections file: %w", err))
+	}
+
+	overwrite := c.FormValue("onConflict") == "overwrite"
+	var imported, renamed int
+	for _, cp := range profiles {
+		if _, err := h.Store.GetConnection(cp.ID); err == nil && !overwrite {
+			cp.ID = ""
+			renamed++
+		}
+		if _, err := h.saveConn(cp); err != nil {
+			return jsonErr(c, fmt.Errorf("importing %s: %w", cp.Name, err))
+		}
+		imported++
+	}
+	return c.JSON(http.StatusOK, map[string]int{"imported": imported, "renamed": renamed})
+}
+
+func (h *Handlers) testConnection(c echo.Context) error {
+	var cp connectionProfile
+	if err := c.Bind(&cp); err != nil {
+		return jsonErr(c, err)
+	}
+
+	timeout := time.Duration(h.settingInt("test_connection_timeout_seconds")) * time.Second
+	cfg := cp.connConfig()
+	cfg.SSHHostKeys = h.Store
+	result := database.TestConnection(c.Request().Context(), cfg, timeout)
+	if !result.OK {
+		return c.JSON(http.StatusBadRequest, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// --- Saved Queries ---
+
+func (h *Handlers) listSavedQueries(c echo.Context) error {
+	queries, err := h.Store.ListSavedQueries(c.QueryParam("profileId"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, queries)
+}
+
+func (h *Handlers) saveQuery(c echo.Context) error {
+	var q store.SavedQuery
+	if err := c.Bind(&q); err != nil {
+		return jsonErr(c, err)
+	}
+	q.ID = c.Param("id") // empty on create
+	if q.Name == "" {
+		return jsonErr(c, errors.New("saved query needs a name"))
+	}
+	if q.ID != "" {
+		existing, err := h.Store.GetSavedQuery(q.ID)
+		if err != nil {
+			return jsonErr(c, fmt.Errorf("saved query not found: %w", err))
+		}
+		q.CreatedAt = existing.CreatedAt
+	}
+	if err := h.Store.SaveQuery(&q); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, q)
+}
+
+func (h *Handlers) deleteSavedQuery(c echo.Context) error {
+	if err := h.Store.DeleteSavedQuery(c.Param("id")); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// --- Tabs / Active Connections ---
+
+func (h *Handlers) connect(c echo.Context) error {
+	tabID := c.Param("id")
+	var body struct {
+		ProfileID string `json:"profileId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	profile, err := h.Store.GetConnection(body.ProfileID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return jsonErr(c, fmt.Errorf("connection profile not found: %s", body.ProfileID))
+	}
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	cfg := h.decryptProfile(*profile).connConfig()
+	cfg.SSHHostKeys = h.Store
+
+	if err := h.ConnMgr.Connect(tabID, body.ProfileID, cfg); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) disconnect(c echo.Context) error {
+	tabID := c.Param("id")
+	if err := h.ConnMgr.Disconnect(tabID); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) connectionStates(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.ConnMgr.States())
+}
+
+func (h *Handlers) pingConnection(c echo.Context) error {
+	tabID := c.Param("id")
+	if err := h.ConnMgr.Ping(tabID); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// --- Schema ---
+
+func (h *Handlers) getConn(c echo.Context) (*database.Connection, error) {
+	return h.ConnMgr.Acquire(c.Param("id"))
+}
+
+// getWritableConn is getConn for endpoints that change the server, which
+// a read-only connection refuses.
+func (h *Handlers) getWritableConn(c echo.Context) (*database.Connection, error) {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return nil, err
+	}
+	if conn.Config.ReadOnly {
+		return nil, database.ErrReadOnly
+	}
+	return conn, nil
+}
+
+// listOrder reads the optional order query parameter of the list
+// endpoints: "nocase" for a case-insensitive sort, otherwise the lists'
+// default code point order.
+func listOrder(c echo.Context) database.NameOrder {
+	if c.QueryParam("order") == string(database.OrderNoCase) {
+		return database.OrderNoCase
+	}
+	return database.OrderBinary
+}
+
+func (h *Handlers) getDatabases(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	dbs, err := database.ListDatabases(conn.DB)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if order := listOrder(c); order != database.OrderBinary {
+		database.SortDatabases(dbs, order)
+	}
+	return c.JSON(http.StatusOK, dbs)
+}
+
+// touchRecent adds a database or table to the recently used list of the
+// tab's profile. The list is only a navigation aid, so failures are
+// ignored.
+func (h *Handlers) touchRecent(conn *database.Connection, db, table string) {
+	if conn.ProfileID != "" && db != "" {
+		h.Store.TouchRecent(conn.ProfileID, db, table)
+	}
+}
+
+func (h *Handlers) getTables(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	tables, err := database.ListTables(conn.DB, c.Param("db"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	h.touchRecent(conn, c.Param("db"), "")
+	if order := listOrder(c); order != database.OrderBinary {
+		database.SortTables(tables, order)
+	}
+	return c.JSON(http.StatusOK, tables)
+}
+
+func (h *Handlers) getTableDetail(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	detail, err := database.GetTableDetail(conn.DB, c.Param("db"), c.Param("table"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	h.touchRecent(conn, c.Param("db"), c.Param("table"))
+	return c.JSON(http.StatusOK, detail)
+}
+
+func (h *Handlers) getTableColumns(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	detail, err := database.GetTableDetail(conn.DB, c.Param("db"), c.Param("table"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	cols := make([]string, len(detail.Columns))
+	for i, col := range detail.Columns {
+		cols[i] = col.Name
+	}
+	return c.JSON(http.StatusOK, cols)
+}
+
+// getExactRowCount counts a table's rows, or those matching the optional
+// where query parameter, as with exports. It runs like a query, so the
+// tab's cancel and the query timeout stop it.
+func (h *Handlers) getExactRowCount(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	var n int64
+	var countErr error
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		n, countErr = database.CountRows(ctx, session, c.Param("db"), c.Param("table"), c.QueryParam("where"))
+		return nil
+	})
+	if err == nil && timeoutMsg != "" {
+		err = errors.New(timeoutMsg)
+	}
+	if err == nil {
+		err = countErr
+	}
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"rowCount": n})
+}
+
+func (h *Handlers) generateStatement(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	stmt, err := database.GenerateStatement(conn.DB, c.Param("db"), c.Param("table"), c.QueryParam("kind"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"sql": stmt})
+}
+
+type rowEditRequest struct {
+	PKColumns []string  `json:"pkColumns"`
+	PKValues  []*string `json:"pkValues"`
+	Column    string    `json:"column"`
+	Value     *string   `json:"value"` // null stores NULL
+}
+
+func (h *Handlers) updateRow(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body rowEditRequest
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.UpdateRow(conn.DB, c.Param("db"), c.Param("table"), body.PKColumns, body.PKValues, body.Column, body.Value)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) deleteRow(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body rowEditRequest
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.DeleteRow(conn.DB, c.Param("db"), c.Param("table"), body.PKColumns, body.PKValues)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) dropTable(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.DropTable(conn.DB, c.Param("db"), c.Param("table"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) renameTable(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		NewName string `json:"newName"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.RenameTable(conn.DB, c.Param("db"), c.Param("table"), body.NewName)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) truncateTable(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.TruncateTable(conn.DB, c.Param("db"), c.Param("table"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+type columnRequest struct {
+	Column   database.ColumnInfo     `json:"column"`
+	Position database.ColumnPosition `json:"position"`
+}
+
+func (h *Handlers) addColumn(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body columnRequest
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.AddColumn(conn.DB, c.Param("db"), c.Param("table"), body.Column, body.Position)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) modifyColumn(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body columnRequest
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.ModifyColumn(conn.DB, c.Param("db"), c.Param("table"), c.Param("column"), body.Column, body.Position)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) dropColumn(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.DropColumn(conn.DB, c.Param("db"), c.Param("table"), c.Param("column"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) renameColumn(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		NewName string `json:"newName"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.RenameColumn(conn.DB, c.Param("db"), c.Param("table"), c.Param("column"), body.NewName)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) createForeignKey(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var fk database.ForeignKeyInfo
+	if err := c.Bind(&fk); err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.CreateForeignKey(conn.DB, c.Param("db"), c.Param("table"), fk)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) dropForeignKey(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	n, err := database.DropForeignKey(conn.DB, c.Param("db"), c.Param("table"), c.Param("name"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"ok": true, "rowsAffected": n})
+}
+
+func (h *Handlers) analyzeTables(c echo.Context) error {
+	return h.maintainTables(c, database.AnalyzeTables, true)
+}
+
+func (h *Handlers) optimizeTables(c echo.Context) error {
+	return h.maintainTables(c, database.OptimizeTables, true)
+}
+
+func (h *Handlers) checkTables(c echo.Context) error {
+	return h.maintainTables(c, database.CheckTables, false)
+}
+
+// maintainTables runs a table maintenance op; writes marks the ops that
+// change the tables, which a read-only connection refuses.
+func (h *Handlers) maintainTables(c echo.Context, op func(*sql.DB, string, []string) ([]database.MaintenanceResult, error), writes bool) error {
+	getConn := h.getConn
+	if writes {
+		getConn = h.getWritableConn
+	}
+	conn, err := getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		Tables []string `json:"tables"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	results, err := op(conn.DB, c.Param("db"), body.Tables)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+func (h *Handlers) getViewDetail(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	detail, err := database.GetViewDefinition(conn.DB, c.Param("db"), c.Param("view"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, detail)
+}
+
+// getDatabaseDDL returns a snapshot of every CREATE statement in a
+// database, for saving and comparing with compareSchemas.
+func (h *Handlers) getDatabaseDDL(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	snap, err := database.GetDatabaseDDL(c.Request().Context(), conn.DB, c.Param("db"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, snap)
+}
+
+func (h *Handlers) compareSchemas(c echo.Context) error {
+	var body struct {
+		From database.SchemaSnapshot `json:"from"`
+		To   database.SchemaSnapshot `json:"to"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, database.CompareSchemas(&body.From, &body.To))
+}
+
+func (h *Handlers) getRoutines(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	routines, err := database.ListRoutines(conn.DB, c.Param("db"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if order := listOrder(c); order != database.OrderBinary {
+		database.SortRoutines(routines, order)
+	}
+	return c.JSON(http.StatusOK, routines)
+}
+
+func (h *Handlers) getTriggers(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	triggers, err := database.ListTriggers(conn.DB, c.Param("db"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if order := listOrder(c); order != database.OrderBinary {
+		database.SortTriggers(triggers, order)
+	}
+	return c.JSON(http.StatusOK, triggers)
+}
+
+func (h *Handlers) getRoutineDetail(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	detail, err := database.GetRoutineDefinition(conn.DB, c.Param("db"), c.Param("name"), c.QueryParam("type"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, detail)
+}
+
+func (h *Handlers) getTriggerDetail(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	detail, err := database.GetTriggerDefinition(conn.DB, c.Param("db"), c.Param("name"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, detail)
+}
+
+func (h *Handlers) createRoutine(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		SQL string `json:"sql"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.CreateRoutine(conn.DB, c.Param("db"), body.SQL); err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) dropRoutine(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.DropRoutine(conn.DB, c.Param("db"), c.Param("name"), c.QueryParam("type")); err != nil {
+		return jsonErr(c, err)
+	}
+	conn.InvalidateSchemaCache()
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) createTrigger(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		SQL string `json:"sql"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.CreateTrigger(conn.DB, c.Param("db"), body.SQL); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) dropTrigger(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.DropTrigger(conn.DB, c.Param("db"), c.Param("name")); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) getEvents(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	events, err := database.ListEvents(conn.DB, c.Param("db"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if order := listOrder(c); order != database.OrderBinary {
+		database.SortEvents(events, order)
+	}
+	return c.JSON(http.StatusOK, events)
+}
+
+func (h *Handlers) getEventDetail(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	ddl, err := database.GetEventDefinition(conn.DB, c.Param("db"), c.Param("name"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"name": c.Param("name"), "createSql": ddl})
+}
+
+func (h *Handlers) enableEvent(c echo.Context) error {
+	return h.setEventEnabled(c, true)
+}
+
+func (h *Handlers) disableEvent(c echo.Context) error {
+	return h.setEventEnabled(c, false)
+}
+
+func (h *Handlers) setEventEnabled(c echo.Context, enabled bool) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.EnableEvent(conn.DB, c.Param("db"), c.Param("name"), enabled); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) dropEvent(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.DropEvent(conn.DB, c.Param("db"), c.Param("name")); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) getSchemaCompletions(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	completions, err := conn.Completions(c.QueryParam("refresh") == "true")
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, completions.TableColumns())
+}
+
+// --- Queries ---
+
+// queryRequest is the body accepted by the query endpoints.
+type queryRequest struct {
+	SQL    string `json:"sql"`
+	Offset int    `json:"offset"` // row offset when paging through a SELECT
+	Typed  bool   `json:"typed"`  // return JSON-typed values instead of strings
+
+	SkipWarnings    bool `json:"skipWarnings"`    // don't run SHOW WARNINGS after each statement
+	ContinueOnError bool `json:"continueOnError"` // run the rest of a batch after a failure
+
+	// Summary wraps a batch's results as {results, summary}, with a
+	// database.BatchSummary of them.
+	Summary bool `json:"summary"`
+}
+
+func (h *Handlers) getCompletions(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	completions, err := conn.Completions(c.QueryParam("refresh") == "true")
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, completions)
+}
+
+func (h *Handlers) execOptions(conn *database.Connection, body queryRequest) database.ExecOptions {
+	return database.ExecOptions{
+		PageSize:      h.settingInt("page_size"),
+		PageOffset:    body.Offset,
+		Typed:         body.Typed,
+		Warnings:      !body.SkipWarnings,
+		ReadOnly:      conn.Config.ReadOnly,
+		MaxCellLength: h.settingInt("max_cell_length"),
+		MaxRows:       h.settingInt("max_result_rows"),
+
+		ContinueOnError: body.ContinueOnError,
+	}
+}
+
+// runQuery runs fn on a dedicated connection for the tab so cancelQuery's
+// KILL QUERY targets the right session, applying the configured query
+// timeout. When the timeout fires, the statement is killed server-side and
+// the returned message describes it; otherwise the message is empty.
+//
+// fn returns the statements that completed, so a BEGIN left open keeps the
+// tab pinned to this connection for its next query.
+func (h *Handlers) runQuery(tabID string, conn *database.Connection, fn func(ctx context.Context, session *sql.Conn) []string) (string, error) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	timeout := h.settingInt("query_timeout_seconds")
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	session, err := conn.Session(ctx)
+	if err != nil {
+		return "", err
+	}
+	var completed []string
+	defer func() { conn.Release(session, completed) }()
+	connID, err := database.GetConnectionID(session)
+	if err != nil {
+		return "", err
+	}
+
+	h.cancelMu.Lock()
+	h.cancels[tabID] = cancel
+	h.queryConns[tabID] = connID
+	h.cancelMu.Unlock()
+
+	defer func() {
+		h.cancelMu.Lock()
+		delete(h.cancels, tabID)
+		delete(h.queryConns, tabID)
 		h.cancelMu.Unlock()
 	}()
 
-	results := database.ExecuteMulti(ctx, conn.DB, body.SQL)
-	return c.JSON(http.StatusOK, results)
+	stopTimer := h.startElapsedTimer(tabID)
+	completed = fn(ctx, session)
+	stopTimer()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// The driver abandons the statement, but the server keeps running it.
+		database.KillQuery(conn.DB, connID)
+		return fmt.Sprintf("query timed out after %ds", timeout), nil
+	}
+	return "", nil
+}
+
+// noteDatabaseChange tells the UI when a query switched the tab's current
+// database, so the sidebar can follow it.
+func (h *Handlers) noteDatabaseChange(tabID string, conn *database.Connection, before string) {
+	if current := conn.CurrentDatabase(); current != before {
+		h.emitEvent(tabID, "database-changed", map[string]string{"database": current})
+	}
+}
+
+func (h *Handlers) useDatabase(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		Database string `json:"database"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if body.Database == "" {
+		return jsonErr(c, fmt.Errorf("database is required"))
+	}
+	before := conn.CurrentDatabase()
+	if err := conn.UseDatabase(context.Background(), body.Database); err != nil {
+		return jsonErr(c, err)
+	}
+	h.noteDatabaseChange(c.Param("id"), conn, before)
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// startElapsedTimer emits a "query-elapsed" event each second until the
+// returned stop function is called, so the UI can show a live timer while
+// a statement runs.
+func (h *Handlers) startElapsedTimer(tabID string) (stop func()) {
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				h.emitEvent(tabID, "query-elapsed", map[string]int64{"elapsedMs": time.Since(start).Milliseconds()})
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (h *Handlers) executeQuery(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	var body queryRequest
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	currentDB := conn.CurrentDatabase()
+	var results []database.QueryResult
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		results = database.ExecuteMulti(ctx, session, body.SQL, h.execOptions(conn, body))
+		return database.CompletedStatements(results)
+	})
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	h.noteDatabaseChange(tabID, conn, currentDB)
+	if n := len(results); timeoutMsg != "" && n > 0 {
+		results[n-1].Error = timeoutMsg
+	}
+	if body.Summary {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"results": results,
+			"summary": database.SummarizeBatch(results),
+		})
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+func (h *Handlers) executeParameterized(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	var body struct {
+		queryRequest
+		Params []interface{} `json:"params"`
+	}
+	// UseNumber keeps large integer parameters exact.
+	dec := json.NewDecoder(c.Request().Body)
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	for i, p := range body.Params {
+		if n, ok := p.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				body.Params[i] = v
+			} else {
+				body.Params[i] = n.String()
+			}
+		}
+	}
+
+	currentDB := conn.CurrentDatabase()
+	var result *database.QueryResult
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		result = database.ExecuteParameterized(ctx, session, body.SQL, body.Params, h.execOptions(conn, body.queryRequest))
+		if result.Error != "" {
+			return nil
+		}
+		return []string{body.SQL}
+	})
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	h.noteDatabaseChange(tabID, conn, currentDB)
+	if timeoutMsg != "" {
+		result.Error = timeoutMsg
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// executeStream runs a SELECT whose rows are delivered as "query-rows"
+// events on the tab's event stream, followed by a "query-done" event with
+// the totals. The response carries the totals too.
+func (h *Handlers) executeStream(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	var body struct {
+		queryRequest
+		ChunkSize int `json:"chunkSize"` // rows per event; 0 means 1000
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	currentDB := conn.CurrentDatabase()
+	var result *database.QueryResult
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		result = database.StreamQuery(ctx, session, body.SQL, body.ChunkSize, h.execOptions(conn, body.queryRequest), func(chunk database.RowChunk) error {
+			return h.sendEvent(ctx, tabID, "query-rows", chunk)
+		})
+		if result.Error != "" {
+			return nil
+		}
+		return []string{body.SQL}
+	})
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	h.noteDatabaseChange(tabID, conn, currentDB)
+	if timeoutMsg != "" {
+		result.Error = timeoutMsg
+	}
+	h.emitEvent(tabID, "query-done", result)
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) executeQueryTx(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	var body queryRequest
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	if conn.InTransaction() {
+		return jsonErr(c, fmt.Errorf("a transaction is already open on this tab; COMMIT or ROLLBACK it first"))
+	}
+
+	currentDB := conn.CurrentDatabase()
+	var result *database.TxResult
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		result = database.ExecuteMultiTx(ctx, session, body.SQL, h.execOptions(conn, body))
+		return nil
+	})
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	conn.NoteStatements(database.CompletedStatements(result.Results))
+	h.noteDatabaseChange(tabID, conn, currentDB)
+	if timeoutMsg != "" {
+		result.Error = timeoutMsg + "; " + result.Error
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) explainQuery(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	var body struct {
+		SQL  string `json:"sql"`
+		Mode string `json:"mode"` // "", "json", or "analyze"
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	var result *database.QueryResult
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		result = database.ExplainQueryMode(ctx, session, body.SQL, body.Mode, h.execOptions(conn, queryRequest{}))
+		return nil
+	})
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if timeoutMsg != "" {
+		result.Error = timeoutMsg
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) formatSQL(c echo.Context) error {
+	var body struct {
+		SQL string `json:"sql"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"sql": database.FormatSQL(body.SQL)})
+}
+
+func (h *Handlers) analyzeSQL(c echo.Context) error {
+	var body struct {
+		SQL string `json:"sql"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, database.AnalyzeStatement(body.SQL))
 }
 
-func (h *Handlers) explainQuery(c echo.Context) error {
+// formatResults renders result grid data as text in one of
+// database.ResultFormats, for copying to the clipboard.
+func (h *Handlers) formatResults(c echo.Context) error {
+	var body struct {
+		Format    string             `json:"format"`
+		Columns   []string           `json:"columns"`
+		Rows      [][]string         `json:"rows"`
+		NullCells database.GridNulls `json:"nullCells"` // optional; from the query result
+		database.ResultFormatOptions
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	text, err := database.FormatResults(body.Format, body.Columns, body.Rows, body.NullCells, body.ResultFormatOptions)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"text": text})
+}
+
+// getCellValue returns the full value of one result cell. With download
+// set, the raw bytes are sent as a file instead.
+func (h *Handlers) getCellValue(c echo.Context) error {
+	tabID := c.Param("id")
 	conn, err := h.getConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
 
 	var body struct {
-		SQL string `json:"sql"`
+		SQL      string `json:"sql"`
+		Row      int    `json:"row"`
+		Column   int    `json:"column"`
+		Encoding string `json:"encoding"` // "", "text", "base64", or "hex"
+		Download bool   `json:"download"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	var cell *database.CellValue
+	var cellErr error
+	timeoutMsg, err := h.runQuery(tabID, conn, func(ctx context.Context, session *sql.Conn) []string {
+		cell, cellErr = database.GetCellValue(ctx, session, body.SQL, body.Row, body.Column, body.Encoding, conn.Config.ReadOnly)
+		return nil
+	})
+	if err == nil && timeoutMsg != "" {
+		err = errors.New(timeoutMsg)
+	}
+	if err == nil {
+		err = cellErr
+	}
+	if err != nil {
+		return jsonErr(c, err)
+	}
 
-	result := database.ExplainQuery(ctx, conn.DB, body.SQL)
-	return c.JSON(http.StatusOK, result)
+	if body.Download {
+		c.Response().Header().Set("Content-Disposition", `attachment; filename="cell.bin"`)
+		return c.Blob(http.StatusOK, "application/octet-stream", cell.Data)
+	}
+	return c.JSON(http.StatusOK, cell)
 }
 
 func (h *Handlers) cancelQuery(c echo.Context) error {
@@ -527,14 +1880,12 @@ func (h *Handlers) cancelQuery(c echo.Context) error {
 	if cancel, ok := h.cancels[tabID]; ok {
 		cancel()
 	}
+	connID, running := h.queryConns[tabID]
 	h.cancelMu.Unlock()
 
 	conn := h.ConnMgr.Get(tabID)
-	if conn != nil {
-		connID, err := database.GetConnectionID(conn.DB)
-		if err == nil {
-			database.KillQuery(conn.DB, connID)
-		}
+	if conn != nil && running {
+		database.KillQuery(conn.DB, connID)
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
@@ -550,9 +1901,24 @@ func (h *Handlers) listUsers(c echo.Context) error {
 	if err != nil {
 		return jsonErr(c, err)
 	}
+	if order := listOrder(c); order != database.OrderBinary {
+		database.SortUsers(users, order)
+	}
 	return c.JSON(http.StatusOK, users)
 }
 
+func (h *Handlers) listPrivileges(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	privs, err := conn.AvailablePrivileges()
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, privs)
+}
+
 func (h *Handlers) getUserDetail(c echo.Context) error {
 	conn, err := h.getConn(c)
 	if err != nil {
@@ -566,7 +1932,7 @@ func (h *Handlers) getUserDetail(c echo.Context) error {
 }
 
 func (h *Handlers) createUser(c echo.Context) error {
-	conn, err := h.getConn(c)
+	conn, err := h.getWritableConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
@@ -576,19 +1942,21 @@ func (h *Handlers) createUser(c echo.Context) error {
 		Host     string `json:"host"`
 		Password string `json:"password"`
 		Plugin   string `json:"plugin"`
+
+		Resources database.UserResources `json:"resources"`
 	}
 	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
 	}
 
-	if err := database.CreateUser(conn.DB, body.User, body.Host, body.Password, body.Plugin); err != nil {
+	if err := database.CreateUser(conn.DB, body.User, body.Host, body.Password, body.Plugin, body.Resources); err != nil {
 		return jsonErr(c, err)
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
 func (h *Handlers) dropUser(c echo.Context) error {
-	conn, err := h.getConn(c)
+	conn, err := h.getWritableConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
@@ -599,7 +1967,7 @@ func (h *Handlers) dropUser(c echo.Context) error {
 }
 
 func (h *Handlers) changeUserPassword(c echo.Context) error {
-	conn, err := h.getConn(c)
+	conn, err := h.getWritableConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
@@ -615,8 +1983,63 @@ func (h *Handlers) changeUserPassword(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
+func (h *Handlers) alterUserResources(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var res database.UserResources
+	if err := c.Bind(&res); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.AlterUserResources(conn.DB, c.Param("user"), c.Param("host"), res); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) lockUser(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.LockUser(conn.DB, c.Param("user"), c.Param("host")); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) unlockUser(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.UnlockUser(conn.DB, c.Param("user"), c.Param("host")); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) setPasswordExpiry(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		Policy string `json:"policy"` // "now", "interval", "never", or "default"
+		Days   int    `json:"days"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.SetPasswordExpiry(conn.DB, c.Param("user"), c.Param("host"), body.Policy, body.Days); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
 func (h *Handlers) grantPrivileges(c echo.Context) error {
-	conn, err := h.getConn(c)
+	conn, err := h.getWritableConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
@@ -630,30 +2053,312 @@ func (h *Handlers) grantPrivileges(c echo.Context) error {
 	if err := database.GrantPrivileges(conn.DB, c.Param("user"), c.Param("host"), body.Privileges, body.On); err != nil {
 		return jsonErr(c, err)
 	}
-	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) revokePrivileges(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		Privileges string `json:"privileges"`
+		On         string `json:"on"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.RevokePrivileges(conn.DB, c.Param("user"), c.Param("host"), body.Privileges, body.On); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// --- Server ---
+
+func (h *Handlers) getServerInfo(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	info, err := conn.ServerInfo()
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+func (h *Handlers) listProcesses(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	procs, err := database.ListProcesses(conn.DB)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, procs)
+}
+
+func (h *Handlers) killConnection(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	pid, err := strconv.ParseInt(c.Param("pid"), 10, 64)
+	if err != nil {
+		return jsonErr(c, fmt.Errorf("invalid connection ID: %s", c.Param("pid")))
+	}
+	if err := database.KillProcessConnection(conn.DB, pid); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) killProcessQuery(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	pid, err := strconv.ParseInt(c.Param("pid"), 10, 64)
+	if err != nil {
+		return jsonErr(c, fmt.Errorf("invalid connection ID: %s", c.Param("pid")))
+	}
+	if err := database.KillProcessQuery(conn.DB, pid); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *Handlers) getServerVariables(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	vars, err := database.ServerVariables(conn.DB, c.QueryParam("like"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, vars)
+}
+
+func (h *Handlers) getServerStatus(c echo.Context) error {
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	vars, err := database.ServerStatus(conn.DB, c.QueryParam("like"))
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, vars)
+}
+
+func (h *Handlers) setServerVariable(c echo.Context) error {
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	var body struct {
+		Value  string `json:"value"`
+		Global bool   `json:"global"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	if err := database.SetVariable(conn.DB, c.Param("name"), body.Value, body.Global); err != nil {
+		return jsonErr(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+// --- Export ---
+
+// csvOptions reads the CSV dialect from the delimiter, quote, encoding,
+// header, and nullToken query or form parameters, defaulting to comma-separated with a
+// header row. An explicitly empty nullToken exports NULL as an empty field.
+func csvOptions(c echo.Context) database.CSVOptions {
+	opts := database.DefaultCSVOptions()
+	if v := c.FormValue("delimiter"); v != "" {
+		if v == `\t` {
+			v = "\t"
+		}
+		opts.Delimiter = v
+	}
+	if v := c.FormValue("quote"); v != "" {
+		opts.Quote = v
+	}
+	opts.Encoding = c.FormValue("encoding")
+	opts.TimeFormat = c.FormValue("timeFormat")
+	if v := c.FormValue("header"); v != "" {
+		opts.HasHeader = v != "false"
+	}
+	if v := c.FormValue("nullToken"); v != "" || c.Request().Form.Has("nullToken") {
+		opts.NullToken = v
+	}
+	return opts
+}
+
+// startDownload sets the attachment headers for an export. With the
+// compress query parameter the file is gzipped and named *.gz; the return
+// value says whether to compress.
+func startDownload(c echo.Context, filename, contentType string) bool {
+	compress := c.QueryParam("compress") == "true"
+	if compress {
+		filename += ".gz"
+		contentType = "application/gzip"
+	}
+	c.Response().Header().Set("Content-Type", contentType)
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return compress
+}
+
+// exportFilter reads the optional columns (comma-separated), where,
+// orderBy, and limit query parameters of a table export.
+func exportFilter(c echo.Context) database.ExportFilter {
+	f := database.ExportFilter{
+		Where:   c.QueryParam("where"),
+		OrderBy: c.QueryParam("orderBy"),
+	}
+	if v := c.QueryParam("columns"); v != "" {
+		for _, col := range strings.Split(v, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				f.Columns = append(f.Columns, col)
+			}
+		}
+	}
+	f.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	return f
+}
+
+// sqlExportOptions reads the rowsPerInsert query parameter.
+func sqlExportOptions(c echo.Context) (database.SQLExportOptions, error) {
+	var opts database.SQLExportOptions
+	if v := c.QueryParam("rowsPerInsert"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid rows per insert: %w", err)
+		}
+		opts.RowsPerInsert = n
+	}
+	return opts, nil
+}
+
+func (h *Handlers) exportTableCSV(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	dbName := c.QueryParam("db")
+	tableName := c.QueryParam("table")
+	csvOpts := csvOptions(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancelMu.Lock()
+	h.cancels[tabID+"_export"] = cancel
+	h.cancelMu.Unlock()
+	defer func() {
+		cancel()
+		h.cancelMu.Lock()
+		delete(h.cancels, tabID+"_export")
+		h.cancelMu.Unlock()
+	}()
+
+	compress := startDownload(c, tableName+".csv", "text/csv")
+
+	progress := func(current, total int64) bool {
+		h.emitEvent(tabID, "export-progress", map[string]int64{"current": current, "total": total})
+		return ctx.Err() == nil
+	}
+
+	return database.WriteCompressed(c.Response(), compress, func(w io.Writer) error {
+		return database.ExportTableCSV(ctx, conn.DB, dbName, tableName, exportFilter(c), w, csvOpts, progress)
+	})
+}
+
+func (h *Handlers) exportTableSQL(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	dbName := c.QueryParam("db")
+	tableName := c.QueryParam("table")
+
+	opts, err := sqlExportOptions(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancelMu.Lock()
+	h.cancels[tabID+"_export"] = cancel
+	h.cancelMu.Unlock()
+	defer func() {
+		cancel()
+		h.cancelMu.Lock()
+		delete(h.cancels, tabID+"_export")
+		h.cancelMu.Unlock()
+	}()
+
+	compress := startDownload(c, tableName+".sql", "application/sql")
+
+	progress := func(current, total int64) bool {
+		h.emitEvent(tabID, "export-progress", map[string]int64{"current": current, "total": total})
+		return ctx.Err() == nil
+	}
+
+	return database.WriteCompressed(c.Response(), compress, func(w io.Writer) error {
+		return database.ExportTableSQL(ctx, conn.DB, dbName, tableName, exportFilter(c), w, opts, progress)
+	})
 }
 
-func (h *Handlers) revokePrivileges(c echo.Context) error {
+func (h *Handlers) exportDatabaseSQL(c echo.Context) error {
+	tabID := c.Param("id")
 	conn, err := h.getConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
-	var body struct {
-		Privileges string `json:"privileges"`
-		On         string `json:"on"`
-	}
-	if err := c.Bind(&body); err != nil {
+
+	dbName := c.QueryParam("db")
+	sqlOpts, err := sqlExportOptions(c)
+	if err != nil {
 		return jsonErr(c, err)
 	}
-	if err := database.RevokePrivileges(conn.DB, c.Param("user"), c.Param("host"), body.Privileges, body.On); err != nil {
-		return jsonErr(c, err)
+	opts := database.DatabaseExportOptions{
+		SQLExportOptions: sqlOpts,
+		SchemaOnly:       c.QueryParam("schemaOnly") == "true",
+		IncludeRoutines:  c.QueryParam("routines") == "true",
+		IncludeTriggers:  c.QueryParam("triggers") == "true",
 	}
-	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
-}
 
-// --- Export ---
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancelMu.Lock()
+	h.cancels[tabID+"_export"] = cancel
+	h.cancelMu.Unlock()
+	defer func() {
+		cancel()
+		h.cancelMu.Lock()
+		delete(h.cancels, tabID+"_export")
+		h.cancelMu.Unlock()
+	}()
 
-func (h *Handlers) exportTableCSV(c echo.Context) error {
+	compress := startDownload(c, dbName+".sql", "application/sql")
+
+	progress := func(current, total int64) bool {
+		h.emitEvent(tabID, "export-progress", map[string]int64{"current": current, "total": total})
+		return ctx.Err() == nil
+	}
+
+	return database.WriteCompressed(c.Response(), compress, func(w io.Writer) error {
+		return database.ExportDatabaseSQL(ctx, conn.DB, dbName, w, opts, progress)
+	})
+}
+
+func (h *Handlers) exportTableXLSX(c echo.Context) error {
 	tabID := c.Param("id")
 	conn, err := h.getConn(c)
 	if err != nil {
@@ -674,26 +2379,39 @@ func (h *Handlers) exportTableCSV(c echo.Context) error {
 		h.cancelMu.Unlock()
 	}()
 
-	c.Response().Header().Set("Content-Type", "text/csv")
-	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, tableName))
+	c.Response().Header().Set("Content-Type", xlsxContentType)
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, tableName))
 
 	progress := func(current, total int64) bool {
 		h.emitEvent(tabID, "export-progress", map[string]int64{"current": current, "total": total})
 		return ctx.Err() == nil
 	}
 
-	return database.ExportTableCSV(ctx, conn.DB, dbName, tableName, c.Response(), progress)
+	return database.ExportTableXLSX(ctx, conn.DB, dbName, tableName, exportFilter(c), c.Response(), progress)
 }
 
-func (h *Handlers) exportTableSQL(c echo.Context) error {
+// exportQuery streams the rows of the query in the request body to a
+// download named query.ext, or after tableName when given. The query runs
+// on the tab's session so unqualified names resolve against its current
+// database, and it cancels with the tab's other exports.
+func (h *Handlers) exportQuery(c echo.Context, ext, contentType string, write func(ctx context.Context, db database.Querier, query string, readOnly bool, tableName string, w io.Writer, progress database.ProgressFunc) error) error {
 	tabID := c.Param("id")
 	conn, err := h.getConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
 
-	dbName := c.QueryParam("db")
-	tableName := c.QueryParam("table")
+	var body struct {
+		SQL       string `json:"sql"`
+		TableName string `json:"tableName"` // INSERT target for the SQL export
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+	filename := "query." + ext
+	if body.TableName != "" {
+		filename = body.TableName + "." + ext
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	h.cancelMu.Lock()
@@ -706,21 +2424,53 @@ func (h *Handlers) exportTableSQL(c echo.Context) error {
 		h.cancelMu.Unlock()
 	}()
 
-	c.Response().Header().Set("Content-Type", "application/sql")
-	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.sql"`, tableName))
+	session, err := conn.Session(ctx)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	defer conn.Release(session, nil)
+
+	compress := startDownload(c, filename, contentType)
 
 	progress := func(current, total int64) bool {
 		h.emitEvent(tabID, "export-progress", map[string]int64{"current": current, "total": total})
 		return ctx.Err() == nil
 	}
 
-	return database.ExportTableSQL(ctx, conn.DB, dbName, tableName, c.Response(), progress)
+	return database.WriteCompressed(c.Response(), compress, func(w io.Writer) error {
+		return write(ctx, session, body.SQL, conn.Config.ReadOnly, body.TableName, w, progress)
+	})
+}
+
+func (h *Handlers) exportQueryCSV(c echo.Context) error {
+	csvOpts := csvOptions(c)
+	return h.exportQuery(c, "csv", "text/csv", func(ctx context.Context, db database.Querier, query string, readOnly bool, _ string, w io.Writer, progress database.ProgressFunc) error {
+		return database.ExportQueryCSV(ctx, db, query, readOnly, w, csvOpts, progress)
+	})
+}
+
+func (h *Handlers) exportQuerySQL(c echo.Context) error {
+	opts, err := sqlExportOptions(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	return h.exportQuery(c, "sql", "application/sql", func(ctx context.Context, db database.Querier, query string, readOnly bool, tableName string, w io.Writer, progress database.ProgressFunc) error {
+		return database.ExportQuerySQL(ctx, db, query, readOnly, tableName, w, opts, progress)
+	})
+}
+
+func (h *Handlers) exportQueryJSON(c echo.Context) error {
+	return h.exportQuery(c, "json", "application/json", func(ctx context.Context, db database.Querier, query string, readOnly bool, _ string, w io.Writer, progress database.ProgressFunc) error {
+		return database.ExportQueryJSON(ctx, db, query, readOnly, w, progress)
+	})
 }
 
 func (h *Handlers) exportResultsCSV(c echo.Context) error {
+	csvOpts := csvOptions(c)
 	var body struct {
-		Columns []string   `json:"columns"`
-		Rows    [][]string `json:"rows"`
+		Columns   []string           `json:"columns"`
+		Rows      [][]string         `json:"rows"`
+		NullCells database.GridNulls `json:"nullCells"` // optional; from the query result
 	}
 	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
@@ -729,14 +2479,16 @@ func (h *Handlers) exportResultsCSV(c echo.Context) error {
 	c.Response().Header().Set("Content-Type", "text/csv")
 	c.Response().Header().Set("Content-Disposition", `attachment; filename="results.csv"`)
 
-	return database.ExportResultCSV(c.Response(), body.Columns, body.Rows)
+	return database.ExportResultCSV(c.Response(), body.Columns, body.Rows, body.NullCells, csvOpts)
 }
 
 func (h *Handlers) exportResultsSQL(c echo.Context) error {
 	var body struct {
-		TableName string     `json:"tableName"`
-		Columns   []string   `json:"columns"`
-		Rows      [][]string `json:"rows"`
+		TableName   string             `json:"tableName"`
+		Columns     []string           `json:"columns"`
+		ColumnTypes []string           `json:"columnTypes"` // optional database type names
+		Rows        [][]string         `json:"rows"`
+		NullCells   database.GridNulls `json:"nullCells"` // optional; from the query result
 	}
 	if err := c.Bind(&body); err != nil {
 		return jsonErr(c, err)
@@ -745,7 +2497,60 @@ func (h *Handlers) exportResultsSQL(c echo.Context) error {
 	c.Response().Header().Set("Content-Type", "application/sql")
 	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.sql"`, body.TableName))
 
-	return database.ExportResultSQL(c.Response(), body.TableName, body.Columns, body.Rows)
+	return database.ExportResultSQL(c.Response(), body.TableName, body.Columns, body.Rows, body.NullCells, body.ColumnTypes)
+}
+
+func (h *Handlers) exportResultsMarkdown(c echo.Context) error {
+	var body struct {
+		Columns     []string           `json:"columns"`
+		ColumnTypes []string           `json:"columnTypes"` // optional database type names
+		Rows        [][]string         `json:"rows"`
+		NullCells   database.GridNulls `json:"nullCells"` // optional; from the query result
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	c.Response().Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="results.md"`)
+
+	return database.ExportResultMarkdown(c.Response(), body.Columns, body.Rows, body.NullCells, body.ColumnTypes)
+}
+
+func (h *Handlers) exportResultsHTML(c echo.Context) error {
+	var body struct {
+		Columns     []string           `json:"columns"`
+		ColumnTypes []string           `json:"columnTypes"` // optional database type names
+		Rows        [][]string         `json:"rows"`
+		NullCells   database.GridNulls `json:"nullCells"` // optional; from the query result
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	c.Response().Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="results.html"`)
+
+	return database.ExportResultHTML(c.Response(), body.Columns, body.Rows, body.NullCells, body.ColumnTypes)
+}
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+func (h *Handlers) exportResultsXLSX(c echo.Context) error {
+	var body struct {
+		Columns     []string           `json:"columns"`
+		ColumnTypes []string           `json:"columnTypes"` // optional database type names
+		Rows        [][]string         `json:"rows"`
+		NullCells   database.GridNulls `json:"nullCells"` // optional; from the query result
+	}
+	if err := c.Bind(&body); err != nil {
+		return jsonErr(c, err)
+	}
+
+	c.Response().Header().Set("Content-Type", xlsxContentType)
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="results.xlsx"`)
+
+	return database.ExportResultXLSX(c.Response(), body.Columns, body.Rows, body.NullCells, body.ColumnTypes)
 }
 
 // --- Import ---
@@ -777,23 +2582,32 @@ func (h *Handlers) importCSVPreview(c echo.Context) error {
 	}
 	tmpFile.Close()
 
-	preview, err := database.PreviewCSV(tmpPath, 5)
+	opts := csvOptions(c)
+	preview, err := database.PreviewCSV(tmpPath, database.CSVInferenceRows, opts)
 	if err != nil {
 		os.Remove(tmpPath)
 		return jsonErr(c, err)
 	}
+	suggested := database.SuggestCSVColumns(preview, opts)
+	if len(preview.SampleRows) > 5 {
+		preview.SampleRows = preview.SampleRows[:5]
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"filePath":   tmpPath,
-		"headers":    preview.Headers,
-		"sampleRows": preview.SampleRows,
-		"totalRows":  preview.TotalRows,
+		"filePath":         tmpPath,
+		"headers":          preview.Headers,
+		"types":            preview.Types,
+		"sampleRows":       preview.SampleRows,
+		"totalRows":        preview.TotalRows,
+		"suggestedColumns": suggested,
+
+		"duplicateStrategies": database.DuplicateStrategies,
 	})
 }
 
 func (h *Handlers) importCSV(c echo.Context) error {
 	tabID := c.Param("id")
-	conn, err := h.getConn(c)
+	conn, err := h.getWritableConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
@@ -831,11 +2645,32 @@ func (h *Handlers) importCSV(c echo.Context) error {
 	tableName = c.FormValue("table")
 	mappingsJSON = c.FormValue("mappings")
 
+	// With createTable the table is created from the CSV columns, typed
+	// as in the preview's suggestedColumns unless columns overrides them.
+	createTable := c.FormValue("createTable") == "true"
 	var mappings []database.ColumnMapping
-	if err := json.Unmarshal([]byte(mappingsJSON), &mappings); err != nil {
+	var columns []database.ColumnInfo
+	if createTable {
+		if v := c.FormValue("columns"); v != "" {
+			if err := json.Unmarshal([]byte(v), &columns); err != nil {
+				return jsonErr(c, fmt.Errorf("invalid columns: %w", err))
+			}
+		}
+	} else if err := json.Unmarshal([]byte(mappingsJSON), &mappings); err != nil {
 		return jsonErr(c, fmt.Errorf("invalid mappings: %w", err))
 	}
 
+	opts := database.CSVImportOptions{
+		CSVOptions:      csvOptions(c),
+		OnDuplicate:     c.FormValue("onDuplicate"),
+		ContinueOnError: c.FormValue("continueOnError") == "true",
+	}
+	if v := c.FormValue("batchSize"); v != "" {
+		if opts.BatchSize, err = strconv.Atoi(v); err != nil {
+			return jsonErr(c, fmt.Errorf("invalid batch size: %w", err))
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	h.cancelMu.Lock()
 	h.cancels[tabID+"_import"] = cancel
@@ -852,20 +2687,143 @@ func (h *Handlers) importCSV(c echo.Context) error {
 		return ctx.Err() == nil
 	}
 
-	rows, err := database.ImportCSV(ctx, conn.DB, dbName, tableName, filePath, mappings, progress)
+	var summary *database.CSVImportSummary
+	if createTable {
+		summary, err = database.ImportCSVToNewTable(ctx, conn.DB, dbName, tableName, filePath, columns, opts, progress)
+		conn.InvalidateSchemaCache()
+	} else {
+		summary, err = database.ImportCSV(ctx, conn.DB, dbName, tableName, filePath, mappings, opts, progress)
+	}
+	if summary.Skipped > 0 {
+		h.emitEvent(tabID, "import-errors", summary)
+	}
+
+	resp := map[string]interface{}{
+		"rows":      summary.Imported,
+		"skipped":   summary.Skipped,
+		"errors":    summary.Errors,
+		"truncated": summary.Truncated,
+	}
 	if err != nil {
-		return c.JSON(http.StatusOK, map[string]interface{}{"rows": rows, "error": err.Error()})
+		resp["error"] = err.Error()
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"rows": rows})
+	return c.JSON(http.StatusOK, resp)
 }
 
-func (h *Handlers) importSQL(c echo.Context) error {
+func (h *Handlers) importJSONPreview(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return jsonErr(c, fmt.Errorf("no file uploaded: %w", err))
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	defer src.Close()
+
+	// Save to temp file for later import
+	tmpFile, err := os.CreateTemp(os.TempDir(), "mybench-json-*.json")
+	if err != nil {
+		return jsonErr(c, err)
+	}
+	tmpPath := tmpFile.Name()
+
+	if _, err := io.Copy(tmpFile, src); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return jsonErr(c, err)
+	}
+	tmpFile.Close()
+
+	preview, err := database.PreviewJSON(tmpPath, 5)
+	if err != nil {
+		os.Remove(tmpPath)
+		return jsonErr(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"filePath":   tmpPath,
+		"keys":       preview.Keys,
+		"sampleRows": preview.SampleRows,
+		"totalRows":  preview.TotalRows,
+		"format":     preview.Format,
+	})
+}
+
+func (h *Handlers) importJSON(c echo.Context) error {
 	tabID := c.Param("id")
-	conn, err := h.getConn(c)
+	conn, err := h.getWritableConn(c)
 	if err != nil {
 		return jsonErr(c, err)
 	}
 
+	// Accept an upload, or the temp path returned by the preview
+	var filePath string
+	file, fileErr := c.FormFile("file")
+	if fileErr == nil {
+		src, err := file.Open()
+		if err != nil {
+			return jsonErr(c, err)
+		}
+		defer src.Close()
+
+		tmpFile, err := os.CreateTemp(os.TempDir(), "mybench-json-*.json")
+		if err != nil {
+			return jsonErr(c, err)
+		}
+		if _, err := io.Copy(tmpFile, src); err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return jsonErr(c, err)
+		}
+		tmpFile.Close()
+		filePath = tmpFile.Name()
+		defer os.Remove(filePath)
+	} else if filePath = c.FormValue("filePath"); filePath == "" {
+		return jsonErr(c, fmt.Errorf("no file uploaded: %w", fileErr))
+	}
+
+	var mappings []database.JSONMapping
+	if err := json.Unmarshal([]byte(c.FormValue("mappings")), &mappings); err != nil {
+		return jsonErr(c, fmt.Errorf("invalid mappings: %w", err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancelMu.Lock()
+	h.cancels[tabID+"_import"] = cancel
+	h.cancelMu.Unlock()
+	defer func() {
+		cancel()
+		h.cancelMu.Lock()
+		delete(h.cancels, tabID+"_import")
+		h.cancelMu.Unlock()
+	}()
+
+	progress := func(current, total int64) bool {
+		h.emitEvent(tabID, "import-progress", map[string]int64{"current": current, "total": total})
+		return ctx.Err() == nil
+	}
+
+	imported, err := database.ImportJSON(ctx, conn.DB, c.FormValue("db"), c.FormValue("table"), filePath, mappings, progress)
+	resp := map[string]interface{}{"rows": imported}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// sqlImportOptions reads SQL import settings from form values.
+func sqlImportOptions(c echo.Context) database.SQLImportOptions {
+	return database.SQLImportOptions{
+		SingleTransaction: c.FormValue("singleTransaction") == "true",
+		Terminator:        c.FormValue("terminator"),
+		BatchSeparator:    c.FormValue("batchSeparator"),
+	}
+}
+
+func (h *Handlers) importSQLPreview(c echo.Context) error {
+	tabID := c.Param("id")
 	file, err := c.FormFile("file")
 	if err != nil {
 		return jsonErr(c, fmt.Errorf("no file uploaded: %w", err))
@@ -877,18 +2835,98 @@ func (h *Handlers) importSQL(c echo.Context) error {
 	}
 	defer src.Close()
 
-	// Save to temp file
+	// Save to temp file for later import
 	tmpFile, err := os.CreateTemp(os.TempDir(), "mybench-sql-*.sql")
 	if err != nil {
 		return jsonErr(c, err)
 	}
+	tmpPath := tmpFile.Name()
+
 	if _, err := io.Copy(tmpFile, src); err != nil {
 		tmpFile.Close()
-		os.Remove(tmpFile.Name())
+		os.Remove(tmpPath)
 		return jsonErr(c, err)
 	}
 	tmpFile.Close()
-	defer os.Remove(tmpFile.Name())
+
+	opts := sqlImportOptions(c)
+	preview, err := database.PreviewSQLFile(tmpPath, 5, opts)
+	if err != nil {
+		os.Remove(tmpPath)
+		return jsonErr(c, err)
+	}
+	resp := map[string]interface{}{
+		"filePath":   tmpPath,
+		"statements": preview.Statements,
+		"categories": preview.Categories,
+		"first":      preview.First,
+	}
+
+	if c.FormValue("dryRun") == "true" {
+		conn, err := h.getWritableConn(c)
+		if err != nil {
+			os.Remove(tmpPath)
+			return jsonErr(c, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancelMu.Lock()
+		h.cancels[tabID+"_import"] = cancel
+		h.cancelMu.Unlock()
+		defer func() {
+			cancel()
+			h.cancelMu.Lock()
+			delete(h.cancels, tabID+"_import")
+			h.cancelMu.Unlock()
+		}()
+
+		progress := func(current, total int64) bool {
+			h.emitEvent(tabID, "import-progress", map[string]int64{"current": current, "total": total})
+			return ctx.Err() == nil
+		}
+
+		result, err := database.DryRunSQLFile(ctx, conn.DB, tmpPath, opts, progress)
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		resp["dryRun"] = result
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handlers) importSQL(c echo.Context) error {
+	tabID := c.Param("id")
+	conn, err := h.getWritableConn(c)
+	if err != nil {
+		return jsonErr(c, err)
+	}
+
+	// Accept an upload, or the temp path returned by the preview
+	var filePath string
+	file, fileErr := c.FormFile("file")
+	if fileErr == nil {
+		src, err := file.Open()
+		if err != nil {
+			return jsonErr(c, err)
+		}
+		defer src.Close()
+
+		tmpFile, err := os.CreateTemp(os.TempDir(), "mybench-sql-*.sql")
+		if err != nil {
+			return jsonErr(c, err)
+		}
+		if _, err := io.Copy(tmpFile, src); err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return jsonErr(c, err)
+		}
+		tmpFile.Close()
+		filePath = tmpFile.Name()
+		defer os.Remove(filePath)
+	} else if filePath = c.FormValue("filePath"); filePath == "" {
+		return jsonErr(c, fmt.Errorf("no file uploaded: %w", fileErr))
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	h.cancelMu.Lock()
@@ -906,11 +2944,22 @@ func (h *Handlers) importSQL(c echo.Context) error {
 		return ctx.Err() == nil
 	}
 
-	executed, err := database.ImportSQLFile(ctx, conn.DB, tmpFile.Name(), progress)
+	opts := sqlImportOptions(c)
+	summary, err := database.ImportSQLFile(ctx, conn.DB, filePath, opts, progress)
+	// Dumps create and drop objects, and DDL that ran before a failure
+	// stays applied.
+	conn.InvalidateSchemaCache()
+	resp := map[string]interface{}{
+		"statements":      summary.Statements,
+		"failedStatement": summary.FailedStatement,
+		"rolledBack":      summary.RolledBack,
+		"committed":       summary.Committed,
+		"warnings":        summary.Warnings,
+	}
 	if err != nil {
-		return c.JSON(http.StatusOK, map[string]interface{}{"statements": executed, "error": err.Error()})
+		resp["error"] = err.Error()
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"statements": executed})
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (h *Handlers) cancelImportExport(c echo.Context) error {
@@ -950,7 +2999,8 @@ func (h *Handlers) events(c echo.Context) error {
 			}
 		}
 		h.sseMu.Unlock()
-		close(ch)
+		// ch is left open: a sender that looked it up before we
+		// unsubscribed would panic on a closed channel.
 	}()
 
 	for {
@@ -967,6 +3017,20 @@ func (h *Handlers) events(c echo.Context) error {
 
 // --- Helpers ---
 
+// jsonErr responds 400 with the error text and, for errors from the server
+// or the link to it, the code and number from database.ClassifyError. An
+// untrusted SSH host key comes with its details, for accepting it.
 func jsonErr(c echo.Context, err error) error {
-	return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	body := map[string]interface{}{"error": err.Error()}
+	if code, number := database.ClassifyError(err); code != "" {
+		body["code"] = code
+		if number != 0 {
+			body["number"] = number
+		}
+	}
+	var hostKeyErr *database.SSHHostKeyError
+	if errors.As(err, &hostKeyErr) {
+		body["hostKey"] = hostKeyErr
+	}
+	return c.JSON(http.StatusBadRequest, body)
 }
@@ -35,50 +35,144 @@ func StartServer(ctx context.Context, h *Handlers, port string) error {
 	api.POST("/vault/create", h.vaultCreate)
 	api.POST("/vault/unlock", h.vaultUnlock)
 
+	// Settings
+	api.GET("/settings", h.getSettings)
+	api.PUT("/settings", h.updateSettings)
+
 	// Connections
 	api.GET("/connections", h.listConnections)
 	api.POST("/connections", h.saveConnection)
+	api.POST("/connections/export", h.exportConnections)
+	api.POST("/connections/import", h.importConnections)
+	api.PUT("/connections/order", h.reorderConnections)
 	api.PUT("/connections/:id", h.updateConnection)
 	api.DELETE("/connections/:id", h.deleteConnection)
 	api.POST("/connections/:id/test", h.testConnection)
+	api.PUT("/connections/:id/group", h.assignConnectionGroup)
+	api.GET("/connection-groups", h.listGroups)
+	api.POST("/connection-groups", h.createGroup)
+	api.PUT("/connection-groups/:id", h.renameGroup)
+	api.DELETE("/connection-groups/:id", h.deleteGroup)
+	api.GET("/connections/:id/pins", h.listPins)
+	api.POST("/connections/:id/pins", h.addPin)
+	api.PUT("/connections/:id/pins/order", h.reorderPins)
+	api.DELETE("/connections/:id/pins/:pinId", h.deletePin)
+	api.GET("/connections/:id/recent", h.listRecent)
+	api.DELETE("/connections/:id/recent", h.clearRecent)
+
+	// Accepted SSH host keys
+	api.GET("/ssh/host-keys", h.listSSHHostKeys)
+	api.POST("/ssh/host-keys", h.acceptSSHHostKey)
+	api.DELETE("/ssh/host-keys", h.deleteSSHHostKey)
+
+	// Saved queries
+	api.GET("/saved-queries", h.listSavedQueries)
+	api.POST("/saved-queries", h.saveQuery)
+	api.PUT("/saved-queries/:id", h.saveQuery)
+	api.DELETE("/saved-queries/:id", h.deleteSavedQuery)
 
 	// Tabs / Active Connections
+	api.GET("/tabs", h.connectionStates)
 	api.POST("/tabs/:id/connect", h.connect)
 	api.POST("/tabs/:id/disconnect", h.disconnect)
 	api.GET("/tabs/:id/ping", h.pingConnection)
+	api.POST("/tabs/:id/use", h.useDatabase)
 
 	// Schema
 	api.GET("/tabs/:id/databases", h.getDatabases)
 	api.GET("/tabs/:id/databases/:db/tables", h.getTables)
 	api.GET("/tabs/:id/databases/:db/tables/:table", h.getTableDetail)
 	api.GET("/tabs/:id/databases/:db/tables/:table/columns", h.getTableColumns)
+	api.GET("/tabs/:id/databases/:db/tables/:table/count", h.getExactRowCount)
+	api.GET("/tabs/:id/databases/:db/tables/:table/statement", h.generateStatement)
+	api.PUT("/tabs/:id/databases/:db/tables/:table/rows", h.updateRow)
+	api.POST("/tabs/:id/databases/:db/tables/:table/rows/delete", h.deleteRow)
+	api.DELETE("/tabs/:id/databases/:db/tables/:table", h.dropTable)
+	api.POST("/tabs/:id/databases/:db/tables/:table/rename", h.renameTable)
+	api.POST("/tabs/:id/databases/:db/tables/:table/truncate", h.truncateTable)
+	api.POST("/tabs/:id/databases/:db/tables/:table/columns", h.addColumn)
+	api.PUT("/tabs/:id/databases/:db/tables/:table/columns/:column", h.modifyColumn)
+	api.DELETE("/tabs/:id/databases/:db/tables/:table/columns/:column", h.dropColumn)
+	api.POST("/tabs/:id/databases/:db/tables/:table/columns/:column/rename", h.renameColumn)
+	api.POST("/tabs/:id/databases/:db/tables/:table/foreign-keys", h.createForeignKey)
+	api.DELETE("/tabs/:id/databases/:db/tables/:table/foreign-keys/:name", h.dropForeignKey)
+	api.POST("/tabs/:id/databases/:db/analyze", h.analyzeTables)
+	api.POST("/tabs/:id/databases/:db/optimize", h.optimizeTables)
+	api.POST("/tabs/:id/databases/:db/check", h.checkTables)
+	api.GET("/tabs/:id/databases/:db/views/:view", h.getViewDetail)
+	api.GET("/tabs/:id/databases/:db/ddl", h.getDatabaseDDL)
+	api.POST("/schemas/compare", h.compareSchemas)
 	api.GET("/tabs/:id/databases/:db/routines", h.getRoutines)
+	api.GET("/tabs/:id/databases/:db/routines/:name", h.getRoutineDetail)
+	api.POST("/tabs/:id/databases/:db/routines", h.createRoutine)
+	api.DELETE("/tabs/:id/databases/:db/routines/:name", h.dropRoutine)
 	api.GET("/tabs/:id/databases/:db/triggers", h.getTriggers)
+	api.GET("/tabs/:id/databases/:db/triggers/:name", h.getTriggerDetail)
+	api.POST("/tabs/:id/databases/:db/triggers", h.createTrigger)
+	api.DELETE("/tabs/:id/databases/:db/triggers/:name", h.dropTrigger)
+	api.GET("/tabs/:id/databases/:db/events", h.getEvents)
+	api.GET("/tabs/:id/databases/:db/events/:name", h.getEventDetail)
+	api.POST("/tabs/:id/databases/:db/events/:name/enable", h.enableEvent)
+	api.POST("/tabs/:id/databases/:db/events/:name/disable", h.disableEvent)
+	api.DELETE("/tabs/:id/databases/:db/events/:name", h.dropEvent)
 	api.GET("/tabs/:id/completions", h.getSchemaCompletions)
+	api.GET("/tabs/:id/completions/full", h.getCompletions)
 
 	// Queries
+	api.POST("/format", h.formatSQL)
+	api.POST("/analyze", h.analyzeSQL)
+	api.POST("/results/format", h.formatResults)
 	api.POST("/tabs/:id/query", h.executeQuery)
+	api.POST("/tabs/:id/query/transaction", h.executeQueryTx)
+	api.POST("/tabs/:id/query/parameterized", h.executeParameterized)
+	api.POST("/tabs/:id/query/stream", h.executeStream)
 	api.POST("/tabs/:id/explain", h.explainQuery)
+	api.POST("/tabs/:id/cell", h.getCellValue)
 	api.POST("/tabs/:id/cancel", h.cancelQuery)
 
 	// Users
 	api.GET("/tabs/:id/users", h.listUsers)
+	api.GET("/tabs/:id/privileges", h.listPrivileges)
 	api.GET("/tabs/:id/users/:user/:host", h.getUserDetail)
 	api.POST("/tabs/:id/users", h.createUser)
 	api.DELETE("/tabs/:id/users/:user/:host", h.dropUser)
 	api.PUT("/tabs/:id/users/:user/:host/password", h.changeUserPassword)
+	api.PUT("/tabs/:id/users/:user/:host/resources", h.alterUserResources)
+	api.POST("/tabs/:id/users/:user/:host/lock", h.lockUser)
+	api.POST("/tabs/:id/users/:user/:host/unlock", h.unlockUser)
+	api.PUT("/tabs/:id/users/:user/:host/expiry", h.setPasswordExpiry)
 	api.POST("/tabs/:id/users/:user/:host/grant", h.grantPrivileges)
 	api.POST("/tabs/:id/users/:user/:host/revoke", h.revokePrivileges)
 
+	// Server
+	api.GET("/tabs/:id/server-info", h.getServerInfo)
+	api.GET("/tabs/:id/processes", h.listProcesses)
+	api.DELETE("/tabs/:id/processes/:pid", h.killConnection)
+	api.POST("/tabs/:id/processes/:pid/kill-query", h.killProcessQuery)
+	api.GET("/tabs/:id/variables", h.getServerVariables)
+	api.PUT("/tabs/:id/variables/:name", h.setServerVariable)
+	api.GET("/tabs/:id/status", h.getServerStatus)
+
 	// Export
 	api.GET("/tabs/:id/export/csv", h.exportTableCSV)
 	api.GET("/tabs/:id/export/sql", h.exportTableSQL)
+	api.GET("/tabs/:id/export/database", h.exportDatabaseSQL)
+	api.GET("/tabs/:id/export/xlsx", h.exportTableXLSX)
+	api.POST("/tabs/:id/export/query/csv", h.exportQueryCSV)
+	api.POST("/tabs/:id/export/query/sql", h.exportQuerySQL)
+	api.POST("/tabs/:id/export/query/json", h.exportQueryJSON)
 	api.POST("/tabs/:id/export/results/csv", h.exportResultsCSV)
 	api.POST("/tabs/:id/export/results/sql", h.exportResultsSQL)
+	api.POST("/tabs/:id/export/results/xlsx", h.exportResultsXLSX)
+	api.POST("/tabs/:id/export/results/markdown", h.exportResultsMarkdown)
+	api.POST("/tabs/:id/export/results/html", h.exportResultsHTML)
 
 	// Import
 	api.POST("/tabs/:id/import/csv/preview", h.importCSVPreview)
 	api.POST("/tabs/:id/import/csv", h.importCSV)
+	api.POST("/tabs/:id/import/json/preview", h.importJSONPreview)
+	api.POST("/tabs/:id/import/json", h.importJSON)
+	api.POST("/tabs/:id/import/sql/preview", h.importSQLPreview)
 	api.POST("/tabs/:id/import/sql", h.importSQL)
 	api.POST("/tabs/:id/import-export/cancel", h.cancelImportExport)
 
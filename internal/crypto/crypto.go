@@ -7,19 +7,55 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
 const (
-	saltLen    = 16
-	keyLen     = 32 // AES-256
-	argonTime  = 1
-	argonMem   = 64 * 1024
-	argonParts = 4
+	saltLen = 16
+	keyLen  = 32 // AES-256
 )
 
+// Params are the Argon2id cost parameters behind a hash and vault key.
+type Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+}
+
+// DefaultParams are used for new vaults. Raising them only affects vaults
+// created afterwards; existing hashes record the parameters they used.
+var DefaultParams = Params{Memory: 64 * 1024, Time: 1, Threads: 4}
+
+// legacyParams produced the bare base64 hashes stored before parameters
+// were encoded alongside them. Never change these.
+var legacyParams = Params{Memory: 64 * 1024, Time: 1, Threads: 4}
+
+// Bounds on the parameters accepted from stored hashes and imported files,
+// so a crafted value can't exhaust memory or stall key derivation before
+// the password is checked.
+const (
+	minMemory = 8           // KiB
+	maxMemory = 1024 * 1024 // KiB, 1 GiB
+	maxTime   = 32
+)
+
+// validate rejects parameters read from outside that are too weak to
+// compute or too costly to attempt.
+func (p Params) validate() error {
+	if p.Time < 1 || p.Time > maxTime || p.Threads < 1 || p.Memory < minMemory || p.Memory > maxMemory {
+		return fmt.Errorf("argon2 parameters out of range: m=%d,t=%d,p=%d", p.Memory, p.Time, p.Threads)
+	}
+	return nil
+}
+
+func (p Params) key(password string, salt []byte, length uint32) []byte {
+	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, length)
+}
+
 // EncodeSalt returns the base64 encoding of a salt.
 func EncodeSalt(salt []byte) string {
 	return base64.StdEncoding.EncodeToString(salt)
@@ -35,10 +71,16 @@ type Vault struct {
 	key []byte
 }
 
-// NewVault derives an encryption key from the master password and salt.
+// NewVault derives an encryption key from the master password and salt
+// using DefaultParams.
 func NewVault(password string, salt []byte) *Vault {
-	key := argon2.IDKey([]byte(password), salt, argonTime, argonMem, argonParts, keyLen)
-	return &Vault{key: key}
+	return NewVaultWithParams(password, salt, DefaultParams)
+}
+
+// NewVaultWithParams derives an encryption key with explicit parameters,
+// as recorded in the vault's password hash (see HashParams).
+func NewVaultWithParams(password string, salt []byte, p Params) *Vault {
+	return &Vault{key: p.key(password, salt, keyLen)}
 }
 
 // GenerateSalt returns a random salt for key derivation.
@@ -52,20 +94,73 @@ func GenerateSalt() ([]byte, error) {
 
 // HashPassword creates a verification hash of the master password.
 // This is stored so we can verify the password on subsequent launches
-// without needing to decrypt anything.
+// without needing to decrypt anything. The result is PHC-formatted
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash) and records DefaultParams.
 func HashPassword(password string, salt []byte) string {
-	hash := argon2.IDKey([]byte(password), salt, argonTime, argonMem, argonParts, keyLen)
-	return base64.StdEncoding.EncodeToString(hash)
+	p := DefaultParams
+	hash := p.key(password, salt, keyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
 }
 
-// VerifyPassword checks a password against a stored hash.
+// VerifyPassword checks a password against a stored hash, using the
+// parameters and salt encoded in it. Bare base64 hashes from older vaults
+// are checked with the original parameters and the given salt.
 func VerifyPassword(password string, salt []byte, storedHash string) bool {
-	hash := argon2.IDKey([]byte(password), salt, argonTime, argonMem, argonParts, keyLen)
-	decoded, err := base64.StdEncoding.DecodeString(storedHash)
-	if err != nil {
+	p, hashSalt, want, err := parseHash(storedHash)
+	if err != nil || len(want) == 0 {
 		return false
 	}
-	return subtle.ConstantTimeCompare(hash, decoded) == 1
+	if hashSalt != nil {
+		salt = hashSalt
+	}
+	hash := p.key(password, salt, uint32(len(want)))
+	return subtle.ConstantTimeCompare(hash, want) == 1
+}
+
+// HashParams returns the Argon2 parameters a stored hash was made with, so
+// the vault key can be derived the same way.
+func HashParams(storedHash string) (Params, error) {
+	p, _, _, err := parseHash(storedHash)
+	return p, err
+}
+
+// parseHash decodes a PHC-formatted argon2id hash, or a legacy bare base64
+// hash (for which salt is nil).
+func parseHash(encoded string) (p Params, salt, hash []byte, err error) {
+	if !strings.HasPrefix(encoded, "$") {
+		hash, err = base64.StdEncoding.DecodeString(encoded)
+		return legacyParams, nil, hash, err
+	}
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errors.New("unsupported password hash format")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid password hash version: %w", err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid password hash parameters: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return p, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid password hash salt: %w", err)
+	}
+	if hash, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid password hash: %w", err)
+	}
+	return p, salt, hash, nil
 }
 
 // Encrypt encrypts plaintext using AES-256-GCM.
@@ -128,3 +223,61 @@ func (v *Vault) Decrypt(encoded string) (string, error) {
 
 	return string(plaintext), nil
 }
+
+// Sealed is data encrypted under its own password rather than the master
+// key, with everything needed to derive that key again. It is used for
+// portable backups.
+type Sealed struct {
+	Algorithm string `json:"algorithm"` // always "argon2id"
+	Version   int    `json:"version"`
+	Memory    uint32 `json:"memory"`
+	Time      uint32 `json:"time"`
+	Threads   uint8  `json:"threads"`
+	Salt      string `json:"salt"`
+	Data      string `json:"data"` // base64 AES-256-GCM ciphertext
+}
+
+// Seal encrypts plaintext under password with a fresh salt.
+func Seal(password, plaintext string) (*Sealed, error) {
+	if password == "" {
+		return nil, errors.New("a password is required")
+	}
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	p := DefaultParams
+	data, err := NewVaultWithParams(password, salt, p).Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{
+		Algorithm: "argon2id",
+		Version:   argon2.Version,
+		Memory:    p.Memory,
+		Time:      p.Time,
+		Threads:   p.Threads,
+		Salt:      EncodeSalt(salt),
+		Data:      data,
+	}, nil
+}
+
+// Open decrypts data produced by Seal.
+func (s *Sealed) Open(password string) (string, error) {
+	if s.Algorithm != "argon2id" || s.Version != argon2.Version {
+		return "", fmt.Errorf("unsupported key derivation %s v%d", s.Algorithm, s.Version)
+	}
+	salt, err := DecodeSalt(s.Salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid salt: %w", err)
+	}
+	p := Params{Memory: s.Memory, Time: s.Time, Threads: s.Threads}
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	plaintext, err := NewVaultWithParams(password, salt, p).Decrypt(s.Data)
+	if err != nil {
+		return "", errors.New("decryption failed: wrong password or corrupted file")
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParamsOutOfRange(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Params
+	}{
+		{"zero time", Params{Memory: 64 * 1024, Time: 0, Threads: 4}},
+		{"huge time", Params{Memory: 1024 * 1024, Time: 4000000000, Threads: 4}},
+		{"zero threads", Params{Memory: 64 * 1024, Time: 1, Threads: 0}},
+		{"tiny memory", Params{Memory: 4, Time: 1, Threads: 1}},
+		{"huge memory", Params{Memory: 4 * 1024 * 1024, Time: 1, Threads: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.validate(); err == nil {
+				t.Errorf("validate(%+v) = nil, want an error", tt.p)
+			}
+		})
+	}
+	if err := DefaultParams.validate(); err != nil {
+		t.Errorf("DefaultParams rejected: %v", err)
+	}
+}
+
+// A huge t would keep argon2 busy for hours, so finishing quickly shows
+// the parameters were refused before any key was derived.
+func TestHugeTimeRejectedWithoutDerivingKey(t *testing.T) {
+	const hash = "$argon2id$v=19$m=1048576,t=4000000000,p=4$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+	sealed := &Sealed{Algorithm: "argon2id", Version: 19, Memory: 1024 * 1024, Time: 4000000000, Threads: 4,
+		Salt: "c2FsdHNhbHRzYWx0c2FsdA==", Data: "AAAA"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := HashParams(hash); err == nil {
+			t.Error("HashParams accepted t=4000000000")
+		}
+		if VerifyPassword("secret", nil, hash) {
+			t.Error("VerifyPassword accepted a hash with t=4000000000")
+		}
+		if _, err := sealed.Open("secret"); err == nil {
+			t.Error("Sealed.Open accepted t=4000000000")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a key was derived with t=4000000000")
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+// StatementAnalysis classifies one statement of a batch so the UI can ask
+// for confirmation before running anything destructive.
+type StatementAnalysis struct {
+	SQL      string `json:"sql"`
+	Category string `json:"category"` // "read", "write", "ddl", "account", "transaction", or "other"
+	Risky    bool   `json:"risky"`
+	Reason   string `json:"reason,omitempty"` // why the statement is risky
+}
+
+// AnalyzeStatement splits sql into statements and flags the destructive
+// ones: DROP, TRUNCATE, ALTER, and UPDATE or DELETE without a WHERE
+// clause. It only looks at keywords, so it can be fooled, but it never
+// runs anything.
+func AnalyzeStatement(sql string) []StatementAnalysis {
+	stmts := splitStatements(sql)
+	analyses := make([]StatementAnalysis, 0, len(stmts))
+	for _, stmt := range stmts {
+		analyses = append(analyses, analyzeStatement(stmt))
+	}
+	return analyses
+}
+
+func analyzeStatement(stmt string) StatementAnalysis {
+	a := StatementAnalysis{SQL: stmt, Category: "other"}
+	// A WITH is judged by its main statement, which may write.
+	words := mainStatement(topLevelWords(stmt))
+	if len(words) == 0 {
+		return a
+	}
+	object := ""
+	if len(words) > 1 {
+		object = words[1]
+	}
+
+	switch words[0] {
+	case "SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "TABLE", "VALUES", "USE":
+		a.Category = "read"
+	case "INSERT", "REPLACE", "LOAD", "CALL":
+		a.Category = "write"
+	case "UPDATE":
+		a.Category = "write"
+		if !containsWord(words, "WHERE") {
+			a.Risky, a.Reason = true, "UPDATE with no WHERE clause affects every row"
+		}
+	case "DELETE":
+		a.Category = "write"
+		if !containsWord(words, "WHERE") {
+			a.Risky, a.Reason = true, "DELETE with no WHERE clause removes every row"
+		}
+	case "TRUNCATE":
+		a.Category = "ddl"
+		a.Risky, a.Reason = true, "TRUNCATE removes every row"
+	case "DROP":
+		a.Category = "ddl"
+		if object == "USER" || object == "ROLE" {
+			a.Category = "account"
+		}
+		target := object
+		if object == "TEMPORARY" && len(words) > 2 {
+			target += " " + words[2]
+		}
+		a.Risky, a.Reason = true, "DROP "+target+" cannot be undone"
+	case "ALTER":
+		a.Category = "ddl"
+		if object == "USER" {
+			a.Category = "account"
+		}
+		a.Risky, a.Reason = true, "ALTER "+object+" changes an existing object"
+	case "CREATE", "RENAME":
+		a.Category = "ddl"
+		if object == "USER" || object == "ROLE" {
+			a.Category = "account"
+		}
+	case "GRANT", "REVOKE":
+		a.Category = "account"
+	case "BEGIN", "START", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE":
+		a.Category = "transaction"
+	}
+	return a
+}
@@ -0,0 +1,37 @@
+package database
+
+import "testing"
+
+func TestAnalyzeStatement(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		category string
+		risky    bool
+	}{
+		{"SELECT * FROM t", "read", false},
+		{"TABLE t", "read", false},
+		{"VALUES ROW(1, 2)", "read", false},
+		{"WITH c AS (SELECT 1) SELECT * FROM c", "read", false},
+		{"WITH RECURSIVE c (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM c WHERE n < 5) TABLE c", "read", false},
+		{"WITH c AS (SELECT 1) DELETE FROM t", "write", true},
+		{"WITH c AS (SELECT id FROM u WHERE a = 1) DELETE FROM t WHERE id IN (SELECT id FROM c)", "write", false},
+		{"WITH c AS (SELECT id FROM u WHERE a = 1) UPDATE t JOIN c USING (id) SET t.a = 1", "write", true},
+		{"WITH c AS (SELECT id FROM u) UPDATE t JOIN c USING (id) SET t.a = 1 WHERE t.b = 2", "write", false},
+		{"WITH c AS (SELECT 1 AS id) INSERT INTO t SELECT * FROM c", "write", false},
+		{"DELETE FROM t", "write", true},
+		{"DELETE FROM t WHERE id = 1", "write", false},
+		{"UPDATE t SET a = 1", "write", true},
+		{"UPDATE t SET a = (SELECT b FROM u WHERE u.id = t.id)", "write", true},
+		{"DROP TABLE t", "ddl", true},
+		{"TRUNCATE t", "ddl", true},
+		{"CREATE USER u", "account", false},
+		{"BEGIN", "transaction", false},
+	}
+	for _, tt := range tests {
+		a := analyzeStatement(tt.stmt)
+		if a.Category != tt.category || a.Risky != tt.risky {
+			t.Errorf("analyzeStatement(%q) = {Category:%s Risky:%v}, want {Category:%s Risky:%v}",
+				tt.stmt, a.Category, a.Risky, tt.category, tt.risky)
+		}
+	}
+}
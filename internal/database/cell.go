@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CellValue is the full content of a single result cell, which the grid
+// shows abbreviated (binary data) or cut short (long text).
+type CellValue struct {
+	Column   string `json:"column"`
+	Type     string `json:"type"`
+	IsNull   bool   `json:"isNull"`
+	Size     int    `json:"size"`     // length in bytes
+	Encoding string `json:"encoding"` // "text", "base64", or "hex"
+	Value    string `json:"value"`
+
+	// Data is the raw value, for saving to a file.
+	Data []byte `json:"-"`
+}
+
+// GetCellValue re-runs a SELECT and returns one cell of its result,
+// addressed by zero-based row and column index like the grid. Like paging,
+// it relies on the query returning rows in a stable order. encoding picks
+// how Value is rendered; "" means base64 for binary columns and text for
+// everything else. readOnly refuses what ExecOptions.ReadOnly would.
+func GetCellValue(ctx context.Context, db Querier, query string, row, column int, encoding string, readOnly bool) (*CellValue, error) {
+	query = strings.TrimSpace(query)
+	if !isSelectQuery(query) || containsWord(topLevelWords(query), "INTO") {
+		return nil, fmt.Errorf("cell values can only be fetched from SELECT results")
+	}
+	if readOnly && !readOnlyAllowed(query) {
+		return nil, ErrReadOnly
+	}
+	if row < 0 || column < 0 {
+		return nil, fmt.Errorf("invalid cell position")
+	}
+
+	// Skip straight to the row when the query leaves room for a LIMIT;
+	// otherwise read through to it.
+	skip := row
+	if paged, ok := paginate(query, row, 1); ok {
+		query, skip = paged, 0
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	if column >= len(colTypes) {
+		return nil, fmt.Errorf("the result has no column %d", column)
+	}
+
+	scanArgs := make([]interface{}, len(colTypes))
+	for i := range scanArgs {
+		scanArgs[i] = &sql.RawBytes{}
+	}
+	for i := 0; i <= skip; i++ {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("the result has no row %d", row)
+		}
+	}
+	if err := rows.Scan(scanArgs...); err != nil {
+		return nil, err
+	}
+
+	raw := *scanArgs[column].(*sql.RawBytes)
+	ct := colTypes[column]
+	cell := &CellValue{
+		Column: ct.Name(),
+		Type:   ct.DatabaseTypeName(),
+		IsNull: raw == nil,
+		Size:   len(raw),
+		Data:   append([]byte(nil), raw...),
+	}
+
+	if encoding == "" {
+		encoding = "text"
+		if kindOf(cell.Type) == KindBinary {
+			encoding = "base64"
+		}
+	}
+	switch encoding {
+	case "text":
+		cell.Value = string(cell.Data)
+	case "base64":
+		cell.Value = base64.StdEncoding.EncodeToString(cell.Data)
+	case "hex":
+		cell.Value = hex.EncodeToString(cell.Data)
+	default:
+		return nil, fmt.Errorf("unknown encoding: %s", encoding)
+	}
+	cell.Encoding = encoding
+	return cell, nil
+}
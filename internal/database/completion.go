@@ -2,15 +2,201 @@ package database
 
 import (
 	"database/sql"
+	"time"
 )
 
+// completionCacheTTL bounds how stale cached completions can get when the
+// schema is changed from outside this app.
+const completionCacheTTL = 5 * time.Minute
+
+// CompletionSchema groups editor autocomplete suggestions by category so
+// the editor can style them differently.
+type CompletionSchema struct {
+	// Tables maps table names, keyed as in GetCompletionSchema, to their
+	// columns with types and nullability.
+	Tables    map[string][]ColumnInfo `json:"tables"`
+	Routines  []CompletionRoutine     `json:"routines"`
+	Keywords  []string                `json:"keywords"`
+	Functions []string                `json:"functions"`
+}
+
+// CompletionRoutine is a stored procedure or function to suggest, e.g.
+// after CALL.
+type CompletionRoutine struct {
+	Database string `json:"database"`
+	Name     string `json:"name"`
+	Type     string `json:"type"` // "PROCEDURE" or "FUNCTION"
+}
+
+// GetCompletions returns tables and columns, stored routines, and the
+// built-in keywords and functions for editor autocomplete. When current
+// is set, bare table names refer to that database's tables only.
+func GetCompletions(db *sql.DB, current string) (*CompletionSchema, error) {
+	tables, err := completionColumns(db, current)
+	if err != nil {
+		return nil, err
+	}
+	routines, err := listCompletionRoutines(db)
+	if err != nil {
+		return nil, err
+	}
+	return &CompletionSchema{
+		Tables:    tables,
+		Routines:  routines,
+		Keywords:  sqlKeywords,
+		Functions: sqlFunctions,
+	}, nil
+}
+
+// TableColumns returns Tables reduced to column names, the shape
+// GetCompletionSchema returns.
+func (s *CompletionSchema) TableColumns() map[string][]string {
+	return columnNames(s.Tables)
+}
+
+// Completions returns GetCompletions for the connection, cached for
+// completionCacheTTL. refresh forces a reload.
+func (c *Connection) Completions(refresh bool) (*CompletionSchema, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if refresh || c.completions == nil || time.Since(c.completionsAt) > completionCacheTTL {
+		completions, err := GetCompletions(c.DB, c.CurrentDatabase())
+		if err != nil {
+			return nil, err
+		}
+		c.completions = completions
+		c.completionsAt = time.Now()
+	}
+	return c.completions, nil
+}
+
+// InvalidateSchemaCache drops cached completions so the next request
+// reloads them.
+func (c *Connection) InvalidateSchemaCache() {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.completions = nil
+}
+
+// NoteSchemaChanges invalidates the schema cache if any of stmts, which
+// ran on the connection, changed the schema.
+func (c *Connection) NoteSchemaChanges(stmts []string) {
+	for _, stmt := range stmts {
+		if changesSchema(stmt) {
+			c.InvalidateSchemaCache()
+			return
+		}
+	}
+}
+
+func changesSchema(stmt string) bool {
+	words := topLevelWords(stmt)
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "CREATE", "ALTER", "DROP", "RENAME":
+		return true
+	}
+	return false
+}
+
+func listCompletionRoutines(db *sql.DB) ([]CompletionRoutine, error) {
+	query := `
+		SELECT ROUTINE_SCHEMA, ROUTINE_NAME, ROUTINE_TYPE
+		FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA NOT IN ('information_schema', 'performance_schema', 'mysql', 'sys')
+		ORDER BY ROUTINE_SCHEMA, ROUTINE_NAME
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routines []CompletionRoutine
+	for rows.Next() {
+		var r CompletionRoutine
+		if err := rows.Scan(&r.Database, &r.Name, &r.Type); err != nil {
+			return nil, err
+		}
+		routines = append(routines, r)
+	}
+	return routines, rows.Err()
+}
+
+// sqlKeywords are the MySQL keywords worth suggesting while typing.
+var sqlKeywords = []string{
+	"ADD", "ALL", "ALTER", "AND", "AS", "ASC", "AUTO_INCREMENT", "BEGIN",
+	"BETWEEN", "BY", "CALL", "CASCADE", "CASE", "CHANGE", "CHARACTER SET",
+	"CHECK", "COLLATE", "COLUMN", "COMMENT", "COMMIT", "CONSTRAINT", "CREATE",
+	"CROSS JOIN", "DATABASE", "DEFAULT", "DELETE", "DELIMITER", "DESC",
+	"DESCRIBE", "DISTINCT", "DROP", "DUPLICATE", "ELSE", "END", "ENGINE",
+	"EVENT", "EXISTS", "EXPLAIN", "FALSE", "FOREIGN KEY", "FROM", "FULL",
+	"FUNCTION", "GRANT", "GROUP BY", "HAVING", "IF", "IGNORE", "IN", "INDEX",
+	"INNER JOIN", "INSERT", "INTERVAL", "INTO", "IS", "JOIN", "KEY", "KILL",
+	"LEFT JOIN", "LIKE", "LIMIT", "LOCK", "MODIFY", "NOT", "NULL", "OFFSET",
+	"ON", "ON DUPLICATE KEY UPDATE", "OR", "ORDER BY", "OUTER", "OVER",
+	"PARTITION BY", "PRIMARY KEY", "PROCEDURE", "REFERENCES", "REGEXP",
+	"RENAME", "REPLACE", "RETURNS", "REVOKE", "RIGHT JOIN", "ROLLBACK",
+	"SAVEPOINT", "SELECT", "SET", "SHOW", "START TRANSACTION", "TABLE",
+	"TEMPORARY", "THEN", "TO", "TRIGGER", "TRUE", "TRUNCATE", "UNION",
+	"UNION ALL", "UNIQUE", "UNLOCK", "UNSIGNED", "UPDATE", "USE", "USING",
+	"VALUES", "VIEW", "WHEN", "WHERE", "WINDOW", "WITH",
+}
+
+// sqlFunctions are common MySQL built-in functions.
+var sqlFunctions = []string{
+	"ABS", "ADDDATE", "AVG", "BIT_LENGTH", "CAST", "CEIL", "CHAR_LENGTH",
+	"COALESCE", "CONCAT", "CONCAT_WS", "CONVERT", "CONVERT_TZ", "COUNT",
+	"CURDATE", "CURRENT_TIMESTAMP", "CURTIME", "DATE", "DATE_ADD",
+	"DATE_FORMAT", "DATE_SUB", "DATEDIFF", "DAY", "DAYOFWEEK", "DENSE_RANK",
+	"EXTRACT", "FIELD", "FIND_IN_SET", "FIRST_VALUE", "FLOOR", "FORMAT",
+	"FROM_UNIXTIME", "GREATEST", "GROUP_CONCAT", "HEX", "HOUR", "IF",
+	"IFNULL", "INET_ATON", "INET_NTOA", "INSTR", "JSON_ARRAY",
+	"JSON_ARRAYAGG", "JSON_CONTAINS", "JSON_EXTRACT", "JSON_OBJECT",
+	"JSON_OBJECTAGG", "JSON_SET", "JSON_UNQUOTE", "LAG", "LAST_INSERT_ID",
+	"LEAD", "LEAST", "LEFT", "LENGTH", "LOCATE", "LOWER", "LPAD", "LTRIM",
+	"MAX", "MD5", "MIN", "MINUTE", "MOD", "MONTH", "NOW", "NULLIF",
+	"RAND", "RANK", "REGEXP_REPLACE", "REPLACE", "REVERSE", "RIGHT",
+	"ROUND", "ROW_NUMBER", "RPAD", "RTRIM", "SHA2", "SIGN", "STR_TO_DATE",
+	"SUBSTRING", "SUBSTRING_INDEX", "SUM", "TIMESTAMPDIFF", "TRIM",
+	"TRUNCATE", "UNHEX", "UNIX_TIMESTAMP", "UPPER", "UTC_TIMESTAMP",
+	"UUID", "WEEK", "YEAR",
+}
+
 // GetCompletionSchema returns a schema map for editor autocomplete.
 // Keys are table names (both "db.table" qualified and bare "table" forms).
 // Values are column name slices for that table.
 func GetCompletionSchema(db *sql.DB) (map[string][]string, error) {
+	tables, err := completionColumns(db, "")
+	if err != nil {
+		return nil, err
+	}
+	return columnNames(tables), nil
+}
+
+func columnNames(tables map[string][]ColumnInfo) map[string][]string {
+	schema := make(map[string][]string, len(tables))
+	for table, cols := range tables {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.Name
+		}
+		schema[table] = names
+	}
+	return schema
+}
+
+// completionColumns returns the columns of every table visible to the
+// user, keyed like GetCompletionSchema. Only Name, Position, DataType,
+// ColumnType, Nullable, and Key are filled in. A non-empty current limits
+// the bare keys to that database's tables.
+func completionColumns(db *sql.DB, current string) (map[string][]ColumnInfo, error) {
 	// Single query to get all databases, tables, and columns visible to this user.
 	query := `
-		SELECT TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME
+		SELECT TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME, ORDINAL_POSITION,
+		       DATA_TYPE, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY
 		FROM INFORMATION_SCHEMA.COLUMNS
 		WHERE TABLE_SCHEMA NOT IN ('information_schema', 'performance_schema', 'mysql', 'sys')
 		ORDER BY TABLE_SCHEMA, TABLE_NAME, ORDINAL_POSITION
@@ -22,20 +208,25 @@ func GetCompletionSchema(db *sql.DB) (map[string][]string, error) {
 	}
 	defer rows.Close()
 
-	schema := make(map[string][]string)
+	schema := make(map[string][]ColumnInfo)
 
 	for rows.Next() {
-		var dbName, tableName, colName string
-		if err := rows.Scan(&dbName, &tableName, &colName); err != nil {
+		var dbName, tableName, nullable string
+		var col ColumnInfo
+		if err := rows.Scan(&dbName, &tableName, &col.Name, &col.Position,
+			&col.DataType, &col.ColumnType, &nullable, &col.Key); err != nil {
 			return nil, err
 		}
+		col.Nullable = nullable == "YES"
 
 		// Qualified form: "database.table" -> columns
 		qualified := dbName + "." + tableName
-		schema[qualified] = append(schema[qualified], colName)
+		schema[qualified] = append(schema[qualified], col)
 
 		// Bare form: "table" -> columns (for convenience)
-		schema[tableName] = append(schema[tableName], colName)
+		if current == "" || dbName == current {
+			schema[tableName] = append(schema[tableName], col)
+		}
 	}
 
 	return schema, rows.Err()
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultTestTimeout is how long TestConnection waits when the caller
+// doesn't say.
+const DefaultTestTimeout = 5 * time.Second
+
+// Reasons a connection test can fail, from ConnTestResult.Kind.
+const (
+	ConnFailDNS      = "dns"      // the host name didn't resolve
+	ConnFailRefused  = "refused"  // nothing listening on the port or socket
+	ConnFailTimeout  = "timeout"  // no answer within the timeout
+	ConnFailTLS      = "tls"      // TLS negotiation or certificate check failed
+	ConnFailAuth     = "auth"     // the server rejected the account
+	ConnFailDatabase = "database" // the default database is missing or off limits
+	ConnFailSSH      = "ssh"      // the SSH tunnel couldn't be opened
+	ConnFailConfig   = "config"   // the profile's address is unusable as entered
+	ConnFailOther    = "other"
+)
+
+// ConnTestResult reports the outcome of TestConnection. On failure, Error
+// explains the problem in terms of the profile's settings and Detail holds
+// the underlying driver error, classified by Code and Number as in
+// ClassifyError.
+type ConnTestResult struct {
+	OK            bool   `json:"ok"`
+	Kind          string `json:"kind,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	Code          string `json:"code,omitempty"`
+	Number        uint16 `json:"number,omitempty"`
+	ServerVersion string `json:"serverVersion,omitempty"`
+	Duration      string `json:"duration"`
+
+	// HostKey describes the SSH host key when it wasn't trusted, for the
+	// user to check and accept.
+	HostKey *SSHHostKeyError `json:"hostKey,omitempty"`
+}
+
+// TestConnection opens and closes a connection for cfg, giving up after
+// timeout (DefaultTestTimeout when zero or less), and diagnoses any
+// failure. It doesn't touch any tab's connection.
+func TestConnection(ctx context.Context, cfg ConnConfig, timeout time.Duration) *ConnTestResult {
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+	cfg.ConnectTimeout = timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &ConnTestResult{}
+	defer func() { result.Duration = time.Since(start).String() }()
+
+	cfg, err := cfg.normalize()
+	if err != nil {
+		result.Kind, result.Error = ConnFailConfig, err.Error()
+		return result
+	}
+	conn, err := openConnection(ctx, "test", "", cfg)
+	if err == nil {
+		err = conn.DB.QueryRowContext(ctx, "SELECT VERSION()").Scan(&result.ServerVersion)
+		conn.close()
+	}
+	if err != nil {
+		result.Kind, result.Error = diagnoseConnError(err, cfg, timeout)
+		result.Detail = err.Error()
+		result.Code, result.Number = ClassifyError(err)
+		errors.As(err, &result.HostKey)
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// diagnoseConnError classifies an error from opening a connection and
+// explains it.
+func diagnoseConnError(err error, cfg ConnConfig, timeout time.Duration) (kind, message string) {
+	target := net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port))
+	if cfg.SocketPath != "" {
+		target = cfg.SocketPath
+	}
+
+	var sshErr *sshError
+	if errors.As(err, &sshErr) {
+		sshHost := cfg.SSHHost
+		var hostKeyErr *SSHHostKeyError
+		switch {
+		case errors.As(err, &hostKeyErr):
+			return ConnFailSSH, hostKeyErr.Error()
+		case isDNSError(err):
+			return ConnFailSSH, fmt.Sprintf("SSH host %q could not be resolved. Check the SSH host name.", sshHost)
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return ConnFailSSH, fmt.Sprintf("SSH host %s refused the connection. Check the SSH port.", sshHost)
+		case isTimeout(err):
+			return ConnFailSSH, fmt.Sprintf("SSH host %s did not answer within %s.", sshHost, timeout)
+		case strings.Contains(err.Error(), "unable to authenticate"):
+			return ConnFailSSH, "SSH authentication failed. Check the SSH user and key or password."
+		}
+		return ConnFailSSH, "SSH tunnel failed: " + err.Error()
+	}
+
+	var myErr *mysql.MySQLError
+	switch {
+	case isDNSError(err):
+		return ConnFailDNS, fmt.Sprintf("Host %q could not be resolved. Check the host name.", cfg.Host)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnFailRefused, fmt.Sprintf("Connection to %s was refused. Check the port and that MySQL is running.", target)
+	case cfg.SocketPath != "" && errors.Is(err, syscall.ENOENT):
+		return ConnFailRefused, fmt.Sprintf("Socket %s does not exist. Check the socket path and that MySQL is running.", target)
+	case isTimeout(err):
+		return ConnFailTimeout, fmt.Sprintf("%s did not answer within %s. Check the host, the port, and any firewall in between.", target, timeout)
+	case errors.Is(err, mysql.ErrNoTLS):
+		return ConnFailTLS, "The server does not support TLS. Set the SSL mode to disable or enable TLS on the server."
+	case isTLSError(err):
+		return ConnFailTLS, "TLS handshake failed: " + tlsReason(err)
+	case errors.As(err, &myErr):
+		switch myErr.Number {
+		case 1045:
+			return ConnFailAuth, fmt.Sprintf("Access denied for user %q. Check the user name and password.", cfg.Username)
+		case 1130:
+			return ConnFailAuth, fmt.Sprintf("User %q may not connect from this host.", cfg.Username)
+		case 1044, 1049:
+			return ConnFailDatabase, fmt.Sprintf("Cannot use database %q: %s", cfg.Database, myErr.Message)
+		}
+	}
+	return ConnFailOther, err.Error()
+}
+
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && !dnsErr.IsTimeout
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isTLSError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// tlsReason names the usual cause of a TLS failure.
+func tlsReason(err error) string {
+	var (
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &authorityErr):
+		return "the server certificate is not signed by a trusted CA. Set the CA certificate or use SSL mode require."
+	case errors.As(err, &hostnameErr):
+		return "the server certificate does not match the host name. Use SSL mode verify-ca or connect by the certificate's name."
+	case errors.As(err, &invalidErr):
+		return "the server certificate is invalid or expired."
+	}
+	return err.Error()
+}
@@ -0,0 +1,298 @@
+package database
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// CSVOptions describes the CSV dialect used by import and export.
+type CSVOptions struct {
+	Delimiter string `json:"delimiter"` // one character; "" means ","
+	Quote     string `json:"quote"`     // one character; "" means '"'
+	HasHeader bool   `json:"hasHeader"` // the first record holds column names
+	NullToken string `json:"nullToken"` // text that stands for NULL, e.g. `\N` or "NULL"; see isNull
+
+	// Encoding is the source charset for imports, e.g. "windows-1252" or
+	// "latin1". "" means UTF-8. A byte order mark is always honored and
+	// stripped.
+	Encoding string `json:"encoding"`
+
+	// TimeFormat is how table exports write DATETIME and TIMESTAMP values:
+	// "mysql" (2006-01-02 15:04:05, the default), "rfc3339", or a Go time
+	// layout. DATE values are always written as 2006-01-02.
+	TimeFormat string `json:"timeFormat"`
+}
+
+// DefaultCSVOptions returns the comma-separated dialect used when the
+// caller doesn't pick one. NULL is written as \N, as LOAD DATA and
+// mysqldump do, so it can't be mistaken for the text "NULL".
+func DefaultCSVOptions() CSVOptions {
+	return CSVOptions{Delimiter: ",", Quote: `"`, HasHeader: true, NullToken: `\N`}
+}
+
+// chars validates and returns the delimiter and quote characters.
+func (o CSVOptions) chars() (delim, quote rune, err error) {
+	delim, quote = ',', '"'
+	if o.Delimiter != "" {
+		if delim, err = singleRune("delimiter", o.Delimiter); err != nil {
+			return 0, 0, err
+		}
+	}
+	if o.Quote != "" {
+		if quote, err = singleRune("quote", o.Quote); err != nil {
+			return 0, 0, err
+		}
+	}
+	if delim == quote {
+		return 0, 0, fmt.Errorf("CSV delimiter and quote must differ")
+	}
+	return delim, quote, nil
+}
+
+func singleRune(name, s string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("CSV %s must be a single character, got %q", name, s)
+	}
+	return r, nil
+}
+
+// isNull reports whether an imported field stands for NULL: exactly
+// NullToken, or an empty field when there is no token. With a token set,
+// empty fields are empty strings.
+func (o CSVOptions) isNull(v string) bool {
+	return v == o.NullToken
+}
+
+// timeLayout resolves TimeFormat to a Go time layout.
+func (o CSVOptions) timeLayout() string {
+	switch strings.ToLower(o.TimeFormat) {
+	case "", "mysql":
+		return mysqlDateTimeLayout
+	case "rfc3339":
+		return time.RFC3339Nano
+	}
+	return o.TimeFormat
+}
+
+// fieldText renders a scanned driver value as CSV field text. NULL is the
+// caller's to handle. Text and numbers are written as the server sent
+// them, BIT values as numbers, and binary data as its raw bytes.
+func (o CSVOptions) fieldText(v interface{}, typeName string) string {
+	switch val := v.(type) {
+	case []byte:
+		if strings.EqualFold(typeName, "BIT") {
+			return strconv.FormatUint(bitValue(val), 10)
+		}
+		return string(val)
+	case time.Time:
+		if strings.EqualFold(typeName, "DATE") {
+			return val.Format("2006-01-02")
+		}
+		return val.Format(o.timeLayout())
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
+
+type csvRecordReader interface {
+	Read() ([]string, error)
+}
+
+type csvRecordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// newReader returns a record reader for the dialect. encoding/csv only
+// knows double quotes, so other quote characters use quotedReader.
+func (o CSVOptions) newReader(r io.Reader) (csvRecordReader, error) {
+	delim, quote, err := o.chars()
+	if err != nil {
+		return nil, err
+	}
+	if r, err = o.decode(r); err != nil {
+		return nil, err
+	}
+	if quote == '"' {
+		cr := csv.NewReader(r)
+		cr.Comma = delim
+		cr.LazyQuotes = true
+		return cr, nil
+	}
+	return &quotedReader{br: bufio.NewReader(r), delim: delim, quote: quote}, nil
+}
+
+// decode converts r from the source encoding to UTF-8, dropping any BOM.
+func (o CSVOptions) decode(r io.Reader) (io.Reader, error) {
+	var enc encoding.Encoding = unicode.UTF8
+	if o.Encoding != "" {
+		var err error
+		if enc, err = htmlindex.Get(o.Encoding); err != nil {
+			return nil, fmt.Errorf("unsupported encoding: %s", o.Encoding)
+		}
+	}
+	return transform.NewReader(r, unicode.BOMOverride(enc.NewDecoder())), nil
+}
+
+// newWriter returns a record writer for the dialect.
+func (o CSVOptions) newWriter(w io.Writer) (csvRecordWriter, error) {
+	delim, quote, err := o.chars()
+	if err != nil {
+		return nil, err
+	}
+	if quote == '"' {
+		cw := csv.NewWriter(w)
+		cw.Comma = delim
+		return cw, nil
+	}
+	return &quotedWriter{bw: bufio.NewWriter(w), delim: delim, quote: quote}, nil
+}
+
+// quotedReader reads RFC 4180 style records with a custom quote character:
+// quoted fields may span lines and a doubled quote is a literal quote.
+type quotedReader struct {
+	br    *bufio.Reader
+	delim rune
+	quote rune
+	line  int
+}
+
+func (r *quotedReader) Read() ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inQuote, quoted, started := false, false, false
+	startLine := r.line + 1
+
+	for {
+		c, _, err := r.br.ReadRune()
+		if err == io.EOF {
+			if inQuote {
+				return nil, &csv.ParseError{StartLine: startLine, Line: r.line + 1, Err: csv.ErrQuote}
+			}
+			if !started {
+				return nil, io.EOF
+			}
+			return append(fields, field.String()), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		started = true
+
+		if inQuote {
+			if c == r.quote {
+				if next, _, err := r.br.ReadRune(); err == nil {
+					if next == r.quote {
+						field.WriteRune(c)
+						continue
+					}
+					r.br.UnreadRune()
+				}
+				inQuote = false
+				continue
+			}
+			if c == '\n' {
+				r.line++
+			}
+			field.WriteRune(c)
+			continue
+		}
+
+		switch c {
+		case r.quote:
+			if field.Len() == 0 {
+				inQuote, quoted = true, true
+			} else {
+				field.WriteRune(c)
+			}
+		case r.delim:
+			fields = append(fields, field.String())
+			field.Reset()
+		case '\r':
+			if next, _, err := r.br.ReadRune(); err == nil {
+				r.br.UnreadRune()
+				if next == '\n' {
+					continue
+				}
+			}
+			field.WriteRune(c)
+		case '\n':
+			r.line++
+			// Skip blank lines like encoding/csv does.
+			if fields == nil && field.Len() == 0 && !quoted {
+				started = false
+				startLine = r.line + 1
+				continue
+			}
+			return append(fields, field.String()), nil
+		default:
+			field.WriteRune(c)
+		}
+	}
+}
+
+// quotedWriter is the csv.Writer counterpart of quotedReader.
+type quotedWriter struct {
+	bw    *bufio.Writer
+	delim rune
+	quote rune
+	err   error
+}
+
+func (w *quotedWriter) Write(record []string) error {
+	if w.err != nil {
+		return w.err
+	}
+	specials := string([]rune{w.delim, w.quote, '\r', '\n'})
+	q := string(w.quote)
+
+	for i, f := range record {
+		if i > 0 {
+			w.bw.WriteRune(w.delim)
+		}
+		if f == "" || (!strings.ContainsAny(f, specials) && f[0] != ' ' && f[0] != '\t') {
+			w.bw.WriteString(f)
+			continue
+		}
+		w.bw.WriteString(q)
+		w.bw.WriteString(strings.ReplaceAll(f, q, q+q))
+		w.bw.WriteString(q)
+	}
+	_, w.err = w.bw.WriteString("\n")
+	return w.err
+}
+
+func (w *quotedWriter) Flush() {
+	if err := w.bw.Flush(); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *quotedWriter) Error() error {
+	return w.err
+}
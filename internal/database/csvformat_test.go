@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestCSVIsNull(t *testing.T) {
+	tests := []struct {
+		token string
+		field string
+		want  bool
+	}{
+		{`\N`, `\N`, true},
+		{`\N`, `\n`, false},
+		{`\N`, "", false},
+		{`\N`, "NULL", false},
+		{"NULL", "NULL", true},
+		{"NULL", "null", false},
+		{"NULL", "", false},
+		{"", "", true},
+		{"", "NULL", false},
+	}
+	for _, tt := range tests {
+		opts := CSVOptions{NullToken: tt.token}
+		if got := opts.isNull(tt.field); got != tt.want {
+			t.Errorf("isNull(%q) with NullToken %q = %v, want %v", tt.field, tt.token, got, tt.want)
+		}
+	}
+	if opts := DefaultCSVOptions(); opts.isNull("NULL") || opts.isNull("") {
+		t.Errorf("default options read the text NULL or an empty field as NULL")
+	}
+}
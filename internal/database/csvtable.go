@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CSVInferenceRows is how many sample rows PreviewCSV should read when the
+// preview also feeds column type inference.
+const CSVInferenceRows = 100
+
+// Column types PreviewCSV infers from sample values.
+const (
+	CSVTypeInteger  = "integer"
+	CSVTypeFloat    = "float"
+	CSVTypeBoolean  = "boolean" // true or false, in any case
+	CSVTypeDate     = "date"
+	CSVTypeDateTime = "datetime"
+	CSVTypeString   = "string"
+)
+
+// csvDateTimeLayouts are the datetime forms MySQL accepts as literals.
+// time.Parse takes fractional seconds after the seconds field on its own.
+var csvDateTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// csvColumnStats accumulates what the sampled values of one CSV column
+// could be.
+type csvColumnStats struct {
+	seen       bool // any non-NULL value
+	integer    bool
+	float      bool
+	boolean    bool
+	date       bool
+	datetime   bool
+	bigint     bool // an integer outside the INT range
+	fractional bool // a datetime with fractional seconds
+	maxLen     int  // longest value in characters
+}
+
+func newCSVColumnStats() *csvColumnStats {
+	return &csvColumnStats{integer: true, float: true, boolean: true, date: true, datetime: true}
+}
+
+func (s *csvColumnStats) add(v string) {
+	s.seen = true
+	if n := utf8.RuneCountInString(v); n > s.maxLen {
+		s.maxLen = n
+	}
+
+	// Leading zeros usually mean a code (zip, phone), not a number.
+	digits := strings.TrimLeft(v, "+-")
+	n, err := strconv.ParseInt(v, 10, 64)
+	isInt := err == nil && (len(digits) == 1 || digits[0] != '0')
+	if isInt && (n < -1<<31 || n >= 1<<31) {
+		s.bigint = true
+	}
+	// Integers too big for BIGINT stay strings rather than lossy doubles.
+	isFloat := isInt
+	if !isInt && strings.ContainsAny(v, ".eE") && strings.ContainsAny(v, "0123456789") {
+		_, err := strconv.ParseFloat(v, 64)
+		isFloat = err == nil
+	}
+	_, err = time.Parse("2006-01-02", v)
+	isDate := err == nil
+	isDateTime := isDate
+	for _, layout := range csvDateTimeLayouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			isDateTime = true
+			s.fractional = s.fractional || strings.Contains(v, ".")
+			break
+		}
+	}
+
+	s.integer = s.integer && isInt
+	s.float = s.float && isFloat
+	s.boolean = s.boolean && (strings.EqualFold(v, "true") || strings.EqualFold(v, "false"))
+	s.date = s.date && isDate
+	s.datetime = s.datetime && isDateTime
+}
+
+// kind returns the narrowest type that fits every value seen, falling
+// back to a string when the values are mixed or there are none.
+func (s *csvColumnStats) kind() string {
+	switch {
+	case !s.seen:
+		return CSVTypeString
+	case s.integer:
+		return CSVTypeInteger
+	case s.float:
+		return CSVTypeFloat
+	case s.boolean:
+		return CSVTypeBoolean
+	case s.date:
+		return CSVTypeDate
+	case s.datetime:
+		return CSVTypeDateTime
+	}
+	return CSVTypeString
+}
+
+// columnType renders the MySQL column type for the sampled values.
+func (s *csvColumnStats) columnType() string {
+	switch s.kind() {
+	case CSVTypeInteger:
+		if s.bigint {
+			return "BIGINT"
+		}
+		return "INT"
+	case CSVTypeFloat:
+		return "DOUBLE"
+	case CSVTypeDate:
+		return "DATE"
+	case CSVTypeDateTime:
+		if s.fractional {
+			return "DATETIME(6)"
+		}
+		return "DATETIME"
+	}
+	// MySQL won't store true/false in a numeric column, so booleans stay
+	// text like strings. The sample may miss longer values, so leave
+	// headroom.
+	if s.maxLen > 255 {
+		return "TEXT"
+	}
+	return "VARCHAR(255)"
+}
+
+// inferCSVColumns collects type statistics for each of n columns from
+// sample rows. NULL fields (see CSVOptions.isNull) are ignored.
+func inferCSVColumns(rows [][]string, n int, opts CSVOptions) []*csvColumnStats {
+	stats := make([]*csvColumnStats, n)
+	for i := range stats {
+		stats[i] = newCSVColumnStats()
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			if i < n && !opts.isNull(v) {
+				stats[i].add(v)
+			}
+		}
+	}
+	return stats
+}
+
+// SuggestCSVColumns proposes a column definition for each CSV column of
+// preview, typed from its sample rows: INT, BIGINT, DOUBLE, DATE,
+// DATETIME, or VARCHAR/TEXT when the values are mixed. Columns are named
+// after the headers and all allow NULL.
+func SuggestCSVColumns(preview *CSVPreview, opts CSVOptions) []ColumnInfo {
+	stats := inferCSVColumns(preview.SampleRows, len(preview.Headers), opts)
+	names := csvColumnNames(preview.Headers)
+	cols := make([]ColumnInfo, len(names))
+	for i, name := range names {
+		cols[i] = ColumnInfo{
+			Name:       name,
+			Position:   i + 1,
+			Nullable:   true,
+			ColumnType: stats[i].columnType(),
+		}
+	}
+	return cols
+}
+
+// csvColumnNames turns CSV headers into distinct column names: trimmed,
+// at most 64 characters, with blanks and duplicates renamed.
+func csvColumnNames(headers []string) []string {
+	names := make([]string, len(headers))
+	used := make(map[string]bool, len(headers))
+	for i, h := range headers {
+		name := strings.TrimSpace(h)
+		if r := []rune(name); len(r) > 64 {
+			name = strings.TrimSpace(string(r[:64]))
+		}
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		base := name
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// ImportCSVToNewTable creates tableName with one column per CSV column and
+// imports the file into it. columns overrides the definitions
+// SuggestCSVColumns would infer and must match the CSV columns in order.
+// If the import fails the new table is left in place with the rows
+// imported so far.
+func ImportCSVToNewTable(ctx context.Context, db *sql.DB, dbName, tableName, filePath string, columns []ColumnInfo, opts CSVImportOptions, progress ProgressFunc) (*CSVImportSummary, error) {
+	if len(columns) == 0 {
+		preview, err := PreviewCSV(filePath, CSVInferenceRows, opts.CSVOptions)
+		if err != nil {
+			return &CSVImportSummary{}, err
+		}
+		columns = SuggestCSVColumns(preview, opts.CSVOptions)
+	}
+	if tableName == "" {
+		return &CSVImportSummary{}, fmt.Errorf("table name is required")
+	}
+
+	defs := make([]string, len(columns))
+	mappings := make([]ColumnMapping, len(columns))
+	for i, col := range columns {
+		def, err := columnDefinition(col)
+		if err != nil {
+			return &CSVImportSummary{}, err
+		}
+		defs[i] = def
+		mappings[i] = ColumnMapping{CSVIndex: i, ColumnName: col.Name}
+	}
+
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", qualifiedName(dbName, tableName), strings.Join(defs, ",\n  "))
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return &CSVImportSummary{}, err
+	}
+	return ImportCSV(ctx, db, dbName, tableName, filePath, mappings, opts, progress)
+}
@@ -0,0 +1,435 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DropTable drops a table. Server errors, such as a foreign key from
+// another table blocking the drop, are returned unchanged.
+func DropTable(db *sql.DB, database, table string) (int64, error) {
+	return execDDL(db, "DROP TABLE "+qualifiedName(database, table))
+}
+
+// RenameTable renames a table within its database.
+func RenameTable(db *sql.DB, database, oldName, newName string) (int64, error) {
+	if newName == "" {
+		return 0, fmt.Errorf("new table name must not be empty")
+	}
+	return execDDL(db, fmt.Sprintf("RENAME TABLE %s TO %s",
+		qualifiedName(database, oldName), qualifiedName(database, newName)))
+}
+
+// TruncateTable removes all rows from a table.
+func TruncateTable(db *sql.DB, database, table string) (int64, error) {
+	return execDDL(db, "TRUNCATE TABLE "+qualifiedName(database, table))
+}
+
+// ColumnPosition places a column added or modified by ALTER TABLE. The
+// zero value leaves it where the server puts it by default: last for a
+// new column, unchanged for a modified one.
+type ColumnPosition struct {
+	First bool   `json:"first"`
+	After string `json:"after"` // name of the column to follow
+}
+
+func (p ColumnPosition) clause() string {
+	switch {
+	case p.First:
+		return " FIRST"
+	case p.After != "":
+		return " AFTER " + quoteIdent(p.After)
+	}
+	return ""
+}
+
+// AddColumn adds col to a table. The definition is built from ColumnType
+// (or DataType), CharSet, Collation, Nullable, Default, Extra, and Comment.
+func AddColumn(db *sql.DB, database, table string, col ColumnInfo, pos ColumnPosition) (int64, error) {
+	def, err := columnDefinition(col)
+	if err != nil {
+		return 0, err
+	}
+	return execDDL(db, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s%s",
+		qualifiedName(database, table), def, pos.clause()))
+}
+
+// ModifyColumn replaces the definition of column oldName with col,
+// renaming it when col.Name differs.
+func ModifyColumn(db *sql.DB, database, table, oldName string, col ColumnInfo, pos ColumnPosition) (int64, error) {
+	def, err := columnDefinition(col)
+	if err != nil {
+		return 0, err
+	}
+	var stmt string
+	if oldName == "" || oldName == col.Name {
+		stmt = fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s%s",
+			qualifiedName(database, table), def, pos.clause())
+	} else {
+		stmt = fmt.Sprintf("ALTER TABLE %s CHANGE COLUMN %s %s%s",
+			qualifiedName(database, table), quoteIdent(oldName), def, pos.clause())
+	}
+	return execDDL(db, stmt)
+}
+
+// DropColumn removes a column from a table.
+func DropColumn(db *sql.DB, database, table, name string) (int64, error) {
+	return execDDL(db, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s",
+		qualifiedName(database, table), quoteIdent(name)))
+}
+
+// RenameColumn renames a column, keeping its definition. It uses RENAME
+// COLUMN, which needs MySQL 8.0 or MariaDB 10.5; on older servers use
+// ModifyColumn with the new name instead.
+func RenameColumn(db *sql.DB, database, table, oldName, newName string) (int64, error) {
+	if newName == "" {
+		return 0, fmt.Errorf("new column name must not be empty")
+	}
+	return execDDL(db, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+		qualifiedName(database, table), quoteIdent(oldName), quoteIdent(newName)))
+}
+
+// columnDefinition renders col as a column definition for ALTER TABLE.
+func columnDefinition(col ColumnInfo) (string, error) {
+	if col.Name == "" {
+		return "", fmt.Errorf("column name must not be empty")
+	}
+	typ := col.ColumnType
+	if typ == "" {
+		typ = col.DataType
+	}
+	if typ == "" {
+		return "", fmt.Errorf("column %s has no type", col.Name)
+	}
+	if err := checkDefinitionFragment("column type", typ); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(quoteIdent(col.Name) + " " + typ)
+	if col.CharSet != nil && *col.CharSet != "" && isPluginName(*col.CharSet) {
+		b.WriteString(" CHARACTER SET " + *col.CharSet)
+	}
+	if col.Collation != nil && *col.Collation != "" && isPluginName(*col.Collation) {
+		b.WriteString(" COLLATE " + *col.Collation)
+	}
+	if col.Nullable {
+		b.WriteString(" NULL")
+	} else {
+		b.WriteString(" NOT NULL")
+	}
+
+	// INFORMATION_SCHEMA reports expression defaults with DEFAULT_GENERATED
+	// in EXTRA; everything else there is part of the definition.
+	extra := strings.TrimSpace(col.Extra)
+	exprDefault := false
+	if i := strings.Index(strings.ToUpper(extra), "DEFAULT_GENERATED"); i >= 0 {
+		exprDefault = true
+		extra = strings.TrimSpace(extra[:i] + extra[i+len("DEFAULT_GENERATED"):])
+	}
+	if strings.Contains(strings.ToUpper(extra), "GENERATED") {
+		return "", fmt.Errorf("column %s: generated columns are not supported", col.Name)
+	}
+
+	if col.Default != nil {
+		b.WriteString(" DEFAULT " + defaultLiteral(*col.Default, col.DataType, exprDefault))
+	}
+	if extra != "" {
+		if err := checkDefinitionFragment("column extra", extra); err != nil {
+			return "", err
+		}
+		b.WriteString(" " + extra)
+	}
+	if col.Comment != "" {
+		b.WriteString(" COMMENT " + quoteSQLString(col.Comment))
+	}
+	return b.String(), nil
+}
+
+// CreateForeignKey adds fk to a table, referencing a table in the same
+// database. Column and RefColumn may list several comma-separated columns
+// for a composite key. If existing rows violate the constraint the
+// server's error is returned.
+func CreateForeignKey(db *sql.DB, database, table string, fk ForeignKeyInfo) (int64, error) {
+	cols, refCols := splitColumnList(fk.Column), splitColumnList(fk.RefColumn)
+	if len(cols) == 0 || len(cols) != len(refCols) {
+		return 0, fmt.Errorf("foreign key needs matching column and referenced column lists")
+	}
+	if fk.RefTable == "" {
+		return 0, fmt.Errorf("foreign key needs a referenced table")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ALTER TABLE %s ADD ", qualifiedName(database, table))
+	if fk.Name != "" {
+		b.WriteString("CONSTRAINT " + quoteIdent(fk.Name) + " ")
+	}
+	fmt.Fprintf(&b, "FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteIdentList(cols), qualifiedName(database, fk.RefTable), quoteIdentList(refCols))
+	for _, r := range []struct{ clause, rule string }{
+		{"ON DELETE", fk.DeleteRule},
+		{"ON UPDATE", fk.UpdateRule},
+	} {
+		if r.rule == "" {
+			continue
+		}
+		rule := strings.ToUpper(strings.TrimSpace(r.rule))
+		switch rule {
+		case "RESTRICT", "CASCADE", "SET NULL", "NO ACTION", "SET DEFAULT":
+		default:
+			return 0, fmt.Errorf("invalid foreign key rule: %s", r.rule)
+		}
+		b.WriteString(" " + r.clause + " " + rule)
+	}
+	return execDDL(db, b.String())
+}
+
+// DropForeignKey removes the named foreign key constraint from a table.
+// The index backing it is left in place.
+func DropForeignKey(db *sql.DB, database, table, name string) (int64, error) {
+	return execDDL(db, fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s",
+		qualifiedName(database, table), quoteIdent(name)))
+}
+
+func splitColumnList(s string) []string {
+	var cols []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+func quoteIdentList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quoteIdent(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// CreateRoutine creates a procedure or function in database from the text
+// of its CREATE statement. The text may be wrapped in mysql client
+// DELIMITER directives, as it is when copied from a script; without them,
+// the whole text is taken as one statement so semicolons in the body are
+// left alone. An unqualified routine name is created in database.
+func CreateRoutine(db *sql.DB, database, text string) error {
+	stmt, err := definitionStatement(text)
+	if err != nil {
+		return err
+	}
+	switch createsObject(stmt) {
+	case "PROCEDURE", "FUNCTION":
+	default:
+		return fmt.Errorf("expected a CREATE PROCEDURE or CREATE FUNCTION statement")
+	}
+	if stmt, err = qualifyName(stmt, createsObject(stmt), database); err != nil {
+		return err
+	}
+	_, err = execDDL(db, stmt)
+	return err
+}
+
+// DropRoutine drops a procedure or function.
+func DropRoutine(db *sql.DB, database, name, routineType string) error {
+	routineType = strings.ToUpper(routineType)
+	if routineType != "PROCEDURE" && routineType != "FUNCTION" {
+		return fmt.Errorf("invalid routine type: %s", routineType)
+	}
+	_, err := execDDL(db, fmt.Sprintf("DROP %s %s", routineType, qualifiedName(database, name)))
+	return err
+}
+
+// CreateTrigger creates a trigger in database from the text of its CREATE
+// TRIGGER statement, with DELIMITER directives handled as in CreateRoutine.
+// Unqualified trigger and table names resolve to database.
+func CreateTrigger(db *sql.DB, database, text string) error {
+	stmt, err := definitionStatement(text)
+	if err != nil {
+		return err
+	}
+	if createsObject(stmt) != "TRIGGER" {
+		return fmt.Errorf("expected a CREATE TRIGGER statement")
+	}
+	if stmt, err = qualifyName(stmt, "TRIGGER", database); err != nil {
+		return err
+	}
+	if stmt, err = qualifyName(stmt, "ON", database); err != nil {
+		return err
+	}
+	_, err = execDDL(db, stmt)
+	return err
+}
+
+// DropTrigger drops a trigger from the schema that holds it.
+func DropTrigger(db *sql.DB, database, name string) error {
+	_, err := execDDL(db, "DROP TRIGGER "+qualifiedName(database, name))
+	return err
+}
+
+// EnableEvent turns a scheduled event on or off.
+func EnableEvent(db *sql.DB, database, name string, enabled bool) error {
+	state := "DISABLE"
+	if enabled {
+		state = "ENABLE"
+	}
+	_, err := execDDL(db, fmt.Sprintf("ALTER EVENT %s %s", qualifiedName(database, name), state))
+	return err
+}
+
+// DropEvent drops a scheduled event.
+func DropEvent(db *sql.DB, database, name string) error {
+	_, err := execDDL(db, "DROP EVENT "+qualifiedName(database, name))
+	return err
+}
+
+// definitionStatement extracts the single statement in text, following
+// DELIMITER directives when there are any.
+func definitionStatement(text string) (string, error) {
+	lines := strings.Split(text, "\n")
+	directives := false
+	for _, line := range lines {
+		if _, ok := parseDelimiter(line); ok {
+			directives = true
+			break
+		}
+	}
+	if !directives {
+		stmt := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), ";"))
+		if stmt == "" {
+			return "", fmt.Errorf("statement must not be empty")
+		}
+		return stmt, nil
+	}
+
+	splitter := newStmtSplitter()
+	var stmts []string
+	for _, line := range lines {
+		stmts = append(stmts, splitter.feedLine(strings.TrimSuffix(line, "\r"))...)
+	}
+	if rest := splitter.flush(); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	if len(stmts) != 1 {
+		return "", fmt.Errorf("expected a single statement, found %d", len(stmts))
+	}
+	return stmts[0], nil
+}
+
+// createsObject returns the kind of object a CREATE statement creates:
+// PROCEDURE, FUNCTION, TRIGGER, EVENT, or "" for anything else.
+func createsObject(stmt string) string {
+	words := topLevelWords(stmt)
+	if len(words) == 0 || words[0] != "CREATE" {
+		return ""
+	}
+	for _, w := range words[1:] {
+		switch w {
+		case "PROCEDURE", "FUNCTION", "TRIGGER", "EVENT":
+			return w
+		case "TABLE", "VIEW", "INDEX", "DATABASE", "SCHEMA", "USER", "ROLE":
+			return ""
+		}
+	}
+	return ""
+}
+
+// qualifyName prefixes the object name that follows keyword in stmt with
+// database, unless it already names one.
+func qualifyName(stmt, keyword, database string) (string, error) {
+	at := topLevelWordAt(stmt, keyword)
+	if at < 0 {
+		return "", fmt.Errorf("expected %s in statement", keyword)
+	}
+	i := skipSpace(stmt, at+len(keyword))
+	for _, w := range []string{"IF", "NOT", "EXISTS"} {
+		if j := i + len(w); j <= len(stmt) && strings.EqualFold(stmt[i:j], w) && (j == len(stmt) || !isWordChar(stmt[j])) {
+			i = skipSpace(stmt, j)
+		}
+	}
+
+	end := i
+	if end < len(stmt) && stmt[end] == '`' {
+		end = skipQuoted(stmt, end) + 1
+	} else {
+		for end < len(stmt) && isWordChar(stmt[end]) {
+			end++
+		}
+	}
+	if end == i {
+		return "", fmt.Errorf("expected a name after %s", keyword)
+	}
+	if j := skipSpace(stmt, end); j < len(stmt) && stmt[j] == '.' {
+		return stmt, nil
+	}
+	return stmt[:i] + quoteIdent(database) + "." + stmt[i:], nil
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// checkDefinitionFragment is checkGrantFragment for pieces of a column
+// definition, which may also hold string literals such as ENUM values.
+func checkDefinitionFragment(what, s string) error {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '`' || c == '\'' || c == '"':
+			end := skipQuoted(s, i)
+			if end == i || s[end] != c {
+				return fmt.Errorf("unterminated quote in %s: %s", what, s)
+			}
+			i = end
+		case c == ';' || c == '#' || c == '\\' || c == 0,
+			c == '-' && strings.HasPrefix(s[i:], "--"),
+			c == '/' && strings.HasPrefix(s[i:], "/*"):
+			return fmt.Errorf("invalid %s: %s", what, s)
+		}
+	}
+	return nil
+}
+
+// defaultLiteral renders a COLUMN_DEFAULT value as it must appear after
+// DEFAULT.
+func defaultLiteral(v, dataType string, expr bool) string {
+	upper := strings.ToUpper(v)
+	switch {
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), strings.HasPrefix(upper, "NOW("):
+		return v
+	case expr:
+		return "(" + v + ")"
+	case strings.HasPrefix(v, "b'") && strings.EqualFold(dataType, "BIT"):
+		return v
+	case kindOf(dataType) == KindNumber:
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return v
+		}
+	}
+	return quoteSQLString(v)
+}
+
+// execDDL runs a schema statement and returns the affected row count the
+// server reports.
+func execDDL(db *sql.DB, stmt string) (int64, error) {
+	res, err := db.Exec(stmt)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// qualifiedName renders database.table with both parts quoted. An empty
+// database leaves the table unqualified.
+func qualifiedName(database, table string) string {
+	if database == "" {
+		return quoteIdent(table)
+	}
+	return quoteIdent(database) + "." + quoteIdent(table)
+}
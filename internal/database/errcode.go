@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Stable error codes from ClassifyError, for the UI to key guidance on
+// rather than parsing driver text.
+const (
+	ErrCodeAccessDenied       = "access_denied"        // 1045: bad user name or password
+	ErrCodeHostNotAllowed     = "host_not_allowed"     // 1130: the account can't connect from here
+	ErrCodeNoPrivilege        = "no_privilege"         // 1044, 1142, 1143, 1227, ...
+	ErrCodeUnknownDatabase    = "unknown_database"     // 1049
+	ErrCodeUnknownTable       = "unknown_table"        // 1146, 1051
+	ErrCodeUnknownColumn      = "unknown_column"       // 1054
+	ErrCodeSyntax             = "syntax"               // 1064, 1149
+	ErrCodeDuplicateKey       = "duplicate_key"        // 1062, 1586
+	ErrCodeForeignKey         = "foreign_key"          // 1216, 1217, 1451, 1452
+	ErrCodeTooManyConnections = "too_many_connections" // 1040, 1203
+	ErrCodeLockTimeout        = "lock_timeout"         // 1205
+	ErrCodeDeadlock           = "deadlock"             // 1213
+	ErrCodeInterrupted        = "interrupted"          // 1317, 3024: killed or over max_execution_time
+	ErrCodeServerReadOnly     = "server_read_only"     // 1290, 1792, 1836
+	ErrCodeLostConnection     = "lost_connection"      // 2006, 2013, or the driver losing the link
+	ErrCodeTimeout            = "timeout"              // the client gave up waiting
+	ErrCodeCancelled          = "cancelled"
+	ErrCodeSSHHostKeyUnknown  = "ssh_host_key_unknown" // see SSHHostKeyError
+	ErrCodeSSHHostKeyChanged  = "ssh_host_key_changed"
+	ErrCodeServer             = "server" // any other MySQL server error
+)
+
+// serverErrorCodes maps MySQL error numbers to stable codes. 2006 and 2013
+// are client-library numbers that some proxies send on.
+var serverErrorCodes = map[uint16]string{
+	1045: ErrCodeAccessDenied,
+	1698: ErrCodeAccessDenied,
+	1130: ErrCodeHostNotAllowed,
+	1044: ErrCodeNoPrivilege,
+	1142: ErrCodeNoPrivilege,
+	1143: ErrCodeNoPrivilege,
+	1227: ErrCodeNoPrivilege,
+	1370: ErrCodeNoPrivilege,
+	1049: ErrCodeUnknownDatabase,
+	1051: ErrCodeUnknownTable,
+	1146: ErrCodeUnknownTable,
+	1054: ErrCodeUnknownColumn,
+	1064: ErrCodeSyntax,
+	1149: ErrCodeSyntax,
+	1062: ErrCodeDuplicateKey,
+	1586: ErrCodeDuplicateKey,
+	1216: ErrCodeForeignKey,
+	1217: ErrCodeForeignKey,
+	1451: ErrCodeForeignKey,
+	1452: ErrCodeForeignKey,
+	1040: ErrCodeTooManyConnections,
+	1203: ErrCodeTooManyConnections,
+	1205: ErrCodeLockTimeout,
+	1213: ErrCodeDeadlock,
+	1317: ErrCodeInterrupted,
+	3024: ErrCodeInterrupted,
+	1290: ErrCodeServerReadOnly,
+	1792: ErrCodeServerReadOnly,
+	1836: ErrCodeServerReadOnly,
+	2006: ErrCodeLostConnection,
+	2013: ErrCodeLostConnection,
+}
+
+// ClassifyError returns the stable code for err and, when the server sent
+// it, the MySQL error number. Errors that don't come from the server or
+// the link to it, such as validation failures, get an empty code.
+func ClassifyError(err error) (code string, number uint16) {
+	var myErr *mysql.MySQLError
+	var hostKeyErr *SSHHostKeyError
+	switch {
+	case err == nil:
+		return "", 0
+	case errors.As(err, &hostKeyErr):
+		if hostKeyErr.Changed {
+			return ErrCodeSSHHostKeyChanged, 0
+		}
+		return ErrCodeSSHHostKeyUnknown, 0
+	case errors.As(err, &myErr):
+		if code, ok := serverErrorCodes[myErr.Number]; ok {
+			return code, myErr.Number
+		}
+		return ErrCodeServer, myErr.Number
+	case errors.Is(err, context.Canceled):
+		return ErrCodeCancelled, 0
+	case isTimeout(err):
+		return ErrCodeTimeout, 0
+	case errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrCodeLostConnection, 0
+	}
+	return "", 0
+}
+
+// setError records err on a result with its ClassifyError code.
+func (r *QueryResult) setError(err error) {
+	r.Error = err.Error()
+	r.ErrorCode, r.ErrorNumber = ClassifyError(err)
+}
@@ -3,9 +3,11 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // QueryResult holds the result of a single query execution.
@@ -14,41 +16,277 @@ type QueryResult struct {
 	Rows         [][]string `json:"rows"`
 	RowCount     int        `json:"rowCount"`
 	AffectedRows int64      `json:"affectedRows"`
+	LastInsertID int64      `json:"lastInsertId,omitempty"`
 	Duration     string     `json:"duration"`
 	IsSelect     bool       `json:"isSelect"`
 	Error        string     `json:"error"`
+
+	// ErrorCode and ErrorNumber classify Error (see ClassifyError) when it
+	// came from the server or the link to it.
+	ErrorCode   string `json:"errorCode,omitempty"`
+	ErrorNumber uint16 `json:"errorNumber,omitempty"`
+
+	// Warnings holds SHOW WARNINGS output when ExecOptions.Warnings is set.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Populated instead of Rows when ExecOptions.Typed is set.
+	ColumnMeta []ColumnMeta    `json:"columnMeta,omitempty"`
+	Values     [][]interface{} `json:"values,omitempty"`
+
+	// TruncatedCells lists the [row, column] positions of text cells cut
+	// short under ExecOptions.MaxCellLength; GetCellValue fetches them whole.
+	TruncatedCells [][2]int `json:"truncatedCells,omitempty"`
+
+	// NullCells lists the [row, column] positions of SQL NULLs in Rows.
+	// Rows show NULL as the text "NULL", so this is what tells it apart
+	// from a string that says NULL.
+	NullCells [][2]int `json:"nullCells"`
+
+	// Truncated is set when the result had more rows than
+	// ExecOptions.MaxRows; Message says so for display.
+	Truncated bool   `json:"truncated"`
+	Message   string `json:"message,omitempty"`
+
+	// Set when a LIMIT was added for paging; HasMore reports whether
+	// another page follows.
+	Paginated bool `json:"paginated"`
+	Offset    int  `json:"offset"`
+	Limit     int  `json:"limit"`
+	HasMore   bool `json:"hasMore"`
+
+	// MoreResults holds the result sets after the first when a CALL
+	// returns several (see ExecuteCall). Error is set if any of them
+	// failed.
+	MoreResults []QueryResult `json:"moreResults,omitempty"`
+
+	// Set by ExecuteMulti and ExecuteMultiTx: the statement's position in
+	// the batch, from 0, and its text.
+	StatementIndex int    `json:"statementIndex"`
+	Statement      string `json:"statement,omitempty"`
+}
+
+// ExecOptions tunes how statements are executed. The zero value runs
+// statements exactly as written.
+type ExecOptions struct {
+	// PageSize, when positive, adds LIMIT PageSize OFFSET PageOffset to
+	// SELECTs that don't already limit themselves.
+	PageSize   int
+	PageOffset int
+
+	// Typed returns SELECT results as JSON-typed Values with ColumnMeta
+	// instead of stringified Rows.
+	Typed bool
+
+	// Warnings runs SHOW WARNINGS after each successful statement. It only
+	// makes sense when the Querier is a single connection or transaction.
+	Warnings bool
+
+	// ReadOnly rejects anything but SELECT, SHOW, DESCRIBE, EXPLAIN, and
+	// USE before it reaches the server; see readOnlyAllowed.
+	ReadOnly bool
+
+	// MaxCellLength, when positive, cuts text values longer than this many
+	// bytes and marks them in QueryResult.TruncatedCells.
+	MaxCellLength int
+
+	// MaxRows, when positive, stops reading a SELECT after this many rows
+	// and sets QueryResult.Truncated, so a huge result can't exhaust
+	// memory. Unlike paging it never changes the statement.
+	MaxRows int
+
+	// ContinueOnError makes ExecuteMulti run the rest of a batch after a
+	// statement fails instead of stopping there. ExecuteMultiTx ignores
+	// it, since a failure rolls the whole batch back.
+	ContinueOnError bool
+}
+
+// errReadOnly is reported for statements refused under ExecOptions.ReadOnly.
+const errReadOnly = "connection is read-only: only SELECT, SHOW, DESCRIBE, EXPLAIN, and USE statements are allowed"
+
+// ErrReadOnly is errReadOnly as an error, for callers refusing writes on
+// a read-only connection themselves.
+var ErrReadOnly = errors.New(errReadOnly)
+
+// readOnlyAllowed reports whether ExecOptions.ReadOnly lets query through.
+// Besides statements that write, it refuses EXPLAIN ANALYZE, which runs
+// the statement it explains, SELECT ... INTO, which writes a file or
+// variables, and locking reads (FOR UPDATE, FOR SHARE, LOCK IN SHARE MODE).
+func readOnlyAllowed(query string) bool {
+	if _, ok := useTarget(query); ok {
+		return true
+	}
+	if !isSelectQuery(query) {
+		return false
+	}
+	words := topLevelWords(query)
+	switch words[0] {
+	case "SHOW":
+		return true
+	case "EXPLAIN", "DESCRIBE", "DESC":
+		return len(words) < 2 || words[1] != "ANALYZE"
+	}
+	return !containsWord(words, "INTO") && !containsWord(words, "FOR") && !containsWord(words, "LOCK")
+}
+
+// Querier is the subset of *sql.DB, *sql.Conn, and *sql.Tx used to run
+// statements, so a batch can be pinned to a single connection.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // ExecuteQuery runs a SQL query on the given connection and returns results.
-func ExecuteQuery(ctx context.Context, db *sql.DB, query string) *QueryResult {
+func ExecuteQuery(ctx context.Context, db Querier, query string, opts ExecOptions) *QueryResult {
+	return executeQuery(ctx, db, query, opts)
+}
+
+// ExecuteParameterized runs a single statement with params bound to its ?
+// placeholders, so callers never splice values into SQL text. The result
+// has the same shape as ExecuteQuery's.
+func ExecuteParameterized(ctx context.Context, db Querier, query string, params []interface{}, opts ExecOptions) *QueryResult {
+	stmts := splitStatements(query)
+	if len(stmts) > 1 {
+		return &QueryResult{Error: "a parameterized query must be a single statement"}
+	}
+	if len(stmts) == 1 {
+		query = stmts[0]
+	}
+	return executeQuery(ctx, db, query, opts, params...)
+}
+
+func executeQuery(ctx context.Context, db Querier, query string, opts ExecOptions, args ...interface{}) *QueryResult {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return &QueryResult{Error: "empty query"}
 	}
+	if opts.ReadOnly && !readOnlyAllowed(query) {
+		return &QueryResult{Error: errReadOnly}
+	}
 
 	start := time.Now()
-	isSelect := isSelectQuery(query)
+	var result *QueryResult
+	switch {
+	case isSelectQuery(query):
+		if paged, ok := paginate(query, opts.PageOffset, opts.PageSize); ok {
+			result = executePage(ctx, db, paged, opts, start, args...)
+		} else {
+			result = executeSelect(ctx, db, query, start, opts, args...)
+		}
+	case isCallQuery(query):
+		// ExecuteCall collects the warnings itself. A failure in a later
+		// result set fails the statement, so batches stop and roll back.
+		results := ExecuteCall(ctx, db, query, opts, args...)
+		result = &results[0]
+		result.MoreResults = results[1:]
+		if last := results[len(results)-1]; result.Error == "" {
+			result.Error, result.ErrorCode, result.ErrorNumber = last.Error, last.ErrorCode, last.ErrorNumber
+		}
+		return result
+	default:
+		result = executeExec(ctx, db, query, start, args...)
+	}
 
-	if isSelect {
-		return executeSelect(ctx, db, query, start)
+	if opts.Warnings && result.Error == "" {
+		result.Warnings = showWarnings(ctx, db)
 	}
-	return executeExec(ctx, db, query, start)
+	return result
+}
+
+// ExecuteCall runs a CALL and returns one result per result set the
+// procedure produced, stopping at the first error, or a single result
+// without rows when it produced none. If the CALL passes user variables
+// such as @total as arguments, a last result holds their values, so OUT
+// and INOUT parameters can be read back; that needs db to be a single
+// connection, since user variables belong to the session.
+func ExecuteCall(ctx context.Context, db Querier, query string, opts ExecOptions, args ...interface{}) []QueryResult {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		result := QueryResult{Duration: time.Since(start).String()}
+		result.setError(err)
+		return []QueryResult{result}
+	}
+
+	var results []QueryResult
+	for {
+		// The status after the last result set has no columns.
+		if cols, _ := rows.Columns(); len(cols) > 0 {
+			result := scanResultSet(rows, start, opts)
+			results = append(results, *result)
+			if result.Error != "" {
+				break
+			}
+			start = time.Now()
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	// A procedure failing midway ends the result sets early.
+	err = rows.Err()
+	rows.Close()
+	if n := len(results); err != nil && (n == 0 || results[n-1].Error == "") {
+		result := QueryResult{Duration: time.Since(start).String()}
+		result.setError(err)
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return []QueryResult{{Duration: time.Since(start).String()}}
+	}
+
+	if results[len(results)-1].Error != "" {
+		return results
+	}
+	// Reading the variables would clear the CALL's warnings.
+	if opts.Warnings {
+		results[0].Warnings = showWarnings(ctx, db)
+	}
+	if vars := callUserVariables(query); len(vars) > 0 {
+		out := executeSelect(ctx, db, "SELECT "+strings.Join(vars, ", "), time.Now(), opts)
+		out.Message = "OUT parameter values"
+		results = append(results, *out)
+	}
+	return results
+}
+
+// showWarnings returns the warnings left by the last statement on db,
+// formatted like the mysql client prints them.
+func showWarnings(ctx context.Context, db Querier) []string {
+	rows, err := db.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var warnings []string
+	for rows.Next() {
+		var level, message string
+		var code int
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return warnings
+		}
+		warnings = append(warnings, fmt.Sprintf("%s (Code %d): %s", level, code, message))
+	}
+	return warnings
 }
 
 // ExecuteMulti splits SQL by semicolons and executes each statement.
-// Returns results for each statement.
-func ExecuteMulti(ctx context.Context, db *sql.DB, queries string) []QueryResult {
+// Returns results for each statement, up to and including the first that
+// fails unless opts.ContinueOnError is set.
+func ExecuteMulti(ctx context.Context, db Querier, queries string, opts ExecOptions) []QueryResult {
 	stmts := splitStatements(queries)
 	results := make([]QueryResult, 0, len(stmts))
 
-	for _, stmt := range stmts {
+	for i, stmt := range stmts {
 		if ctx.Err() != nil {
-			results = append(results, QueryResult{Error: "cancelled"})
+			results = append(results, QueryResult{Error: "cancelled", ErrorCode: ErrCodeCancelled, StatementIndex: i, Statement: stmt})
 			break
 		}
-		result := ExecuteQuery(ctx, db, stmt)
+		result := ExecuteQuery(ctx, db, stmt, opts)
+		result.StatementIndex, result.Statement = i, stmt
 		results = append(results, *result)
-		if result.Error != "" {
+		if result.Error != "" && !opts.ContinueOnError {
 			break
 		}
 	}
@@ -56,21 +294,188 @@ func ExecuteMulti(ctx context.Context, db *sql.DB, queries string) []QueryResult
 	return results
 }
 
+// BatchSummary totals the results of a batch.
+type BatchSummary struct {
+	Statements   int   `json:"statements"` // statements run, including any that failed
+	Failed       int   `json:"failed"`
+	FailedIndex  int   `json:"failedIndex"` // StatementIndex of the first failure, or -1
+	AffectedRows int64 `json:"affectedRows"`
+}
+
+// SummarizeBatch totals results from ExecuteMulti or ExecuteMultiTx.
+func SummarizeBatch(results []QueryResult) BatchSummary {
+	sum := BatchSummary{Statements: len(results), FailedIndex: -1}
+	for _, r := range results {
+		sum.AffectedRows += r.AffectedRows
+		if r.Error != "" {
+			if sum.Failed == 0 {
+				sum.FailedIndex = r.StatementIndex
+			}
+			sum.Failed++
+		}
+	}
+	return sum
+}
+
+// CompletedStatements returns, in order, the statements of a batch that
+// ran without error, given the results ExecuteMulti or ExecuteMultiTx
+// produced for it.
+func CompletedStatements(results []QueryResult) []string {
+	var done []string
+	for _, r := range results {
+		if r.Error == "" {
+			done = append(done, r.Statement)
+		}
+	}
+	return done
+}
+
+// TxResult reports the outcome of a batch run inside a single transaction.
+type TxResult struct {
+	Results   []QueryResult `json:"results"`
+	Committed bool          `json:"committed"`
+	Error     string        `json:"error"`
+	ErrorCode string        `json:"errorCode,omitempty"` // for a failed begin, commit, or rollback
+	Summary   BatchSummary  `json:"summary"`
+}
+
+// txBeginner is satisfied by *sql.DB and *sql.Conn.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ExecuteMultiTx runs every statement on one transaction and commits only
+// if all of them succeed; otherwise it rolls back. MySQL commits implicitly
+// around DDL and a few other statements, so Error notes when a rollback
+// could not undo everything that ran.
+func ExecuteMultiTx(ctx context.Context, db txBeginner, queries string, opts ExecOptions) *TxResult {
+	stmts := splitStatements(queries)
+	out := &TxResult{Results: make([]QueryResult, 0, len(stmts))}
+	defer func() { out.Summary = SummarizeBatch(out.Results) }()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		out.Error = fmt.Sprintf("failed to begin transaction: %v", err)
+		out.ErrorCode, _ = ClassifyError(err)
+		return out
+	}
+
+	lastImplicit := -1
+	for i, stmt := range stmts {
+		if ctx.Err() != nil {
+			out.Results = append(out.Results, QueryResult{Error: "cancelled", ErrorCode: ErrCodeCancelled, StatementIndex: i, Statement: stmt})
+			break
+		}
+		result := ExecuteQuery(ctx, tx, stmt, opts)
+		result.StatementIndex, result.Statement = i, stmt
+		out.Results = append(out.Results, *result)
+		if result.Error != "" {
+			break
+		}
+		if causesImplicitCommit(stmt) {
+			lastImplicit = i
+		}
+	}
+
+	failed := len(out.Results) > 0 && out.Results[len(out.Results)-1].Error != ""
+	if !failed {
+		if err := tx.Commit(); err != nil {
+			out.Error = fmt.Sprintf("commit failed: %v", err)
+			out.ErrorCode, _ = ClassifyError(err)
+			return out
+		}
+		out.Committed = true
+		return out
+	}
+
+	if err := tx.Rollback(); err != nil {
+		out.Error = fmt.Sprintf("rollback failed: %v", err)
+		out.ErrorCode, _ = ClassifyError(err)
+		return out
+	}
+	out.Error = "transaction rolled back"
+	if lastImplicit >= 0 {
+		out.Error += fmt.Sprintf("; statement %d caused an implicit commit (DDL cannot be rolled back in MySQL), "+
+			"so statements 1-%d were not undone", lastImplicit+1, lastImplicit+1)
+	}
+	return out
+}
+
+// EXPLAIN output formats accepted by ExplainQueryMode.
+const (
+	ExplainTable   = ""        // classic tabular EXPLAIN
+	ExplainJSON    = "json"    // EXPLAIN FORMAT=JSON, one cell holding the plan tree
+	ExplainAnalyze = "analyze" // EXPLAIN ANALYZE, runs the query (MySQL 8.0.18+)
+)
+
 // ExplainQuery runs EXPLAIN on the given query.
-func ExplainQuery(ctx context.Context, db *sql.DB, query string) *QueryResult {
+func ExplainQuery(ctx context.Context, db Querier, query string) *QueryResult {
+	return ExplainQueryMode(ctx, db, query, ExplainTable, ExecOptions{})
+}
+
+// ExplainQueryMode runs EXPLAIN in the requested output mode, reading the
+// plan under opts like any other SELECT. Modes the server is too old for
+// are rejected before anything is sent.
+func ExplainQueryMode(ctx context.Context, db Querier, query, mode string, opts ExecOptions) *QueryResult {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return &QueryResult{Error: "empty query"}
 	}
-	explainSQL := "EXPLAIN " + query
+
+	var prefix string
+	switch mode {
+	case ExplainTable:
+		prefix = "EXPLAIN "
+	case ExplainJSON:
+		prefix = "EXPLAIN FORMAT=JSON "
+	case ExplainAnalyze:
+		version, err := serverVersion(ctx, db)
+		if err != nil {
+			result := &QueryResult{}
+			result.setError(err)
+			return result
+		}
+		if isMariaDB(version) || !versionAtLeast(version, 8, 0, 18) {
+			return &QueryResult{Error: fmt.Sprintf("EXPLAIN ANALYZE requires MySQL 8.0.18 or later (server is %s)", version)}
+		}
+		prefix = "EXPLAIN ANALYZE "
+	default:
+		return &QueryResult{Error: fmt.Sprintf("unknown explain mode: %s", mode)}
+	}
+	if opts.ReadOnly && !readOnlyAllowed(prefix+query) {
+		return &QueryResult{Error: errReadOnly}
+	}
+
 	start := time.Now()
-	return executeSelect(ctx, db, explainSQL, start)
+	return executeSelect(ctx, db, prefix+query, start, opts)
+}
+
+// serverVersion returns the server's VERSION() string, e.g. "8.0.36" or
+// "10.11.6-MariaDB".
+func serverVersion(ctx context.Context, db Querier) (string, error) {
+	var version string
+	err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version)
+	return version, err
+}
+
+// versionAtLeast reports whether a VERSION() string is at least
+// major.minor.patch. Unparseable parts count as zero.
+func versionAtLeast(version string, major, minor, patch int) bool {
+	var v [3]int
+	fmt.Sscanf(version, "%d.%d.%d", &v[0], &v[1], &v[2])
+	for i, want := range [3]int{major, minor, patch} {
+		if v[i] != want {
+			return v[i] > want
+		}
+	}
+	return true
 }
 
 // GetConnectionID returns the MySQL connection ID for KILL QUERY support.
-func GetConnectionID(db *sql.DB) (int64, error) {
+// Pass the *sql.Conn a statement will run on to get an ID that targets it.
+func GetConnectionID(db Querier) (int64, error) {
 	var id int64
-	err := db.QueryRow("SELECT CONNECTION_ID()").Scan(&id)
+	err := db.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&id)
 	return id, err
 }
 
@@ -80,181 +485,307 @@ func KillQuery(db *sql.DB, connID int64) error {
 	return err
 }
 
-func executeSelect(ctx context.Context, db *sql.DB, query string, start time.Time) *QueryResult {
-	rows, err := db.QueryContext(ctx, query)
+// KillConnection terminates a server session by connection ID, rolling
+// back whatever transaction it holds.
+func KillConnection(db *sql.DB, connID int64) error {
+	_, err := db.Exec(fmt.Sprintf("KILL CONNECTION %d", connID))
+	return err
+}
+
+func executeSelect(ctx context.Context, db Querier, query string, start time.Time, opts ExecOptions, args ...interface{}) *QueryResult {
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return &QueryResult{
-			Error:    err.Error(),
-			Duration: time.Since(start).String(),
-			IsSelect: true,
-		}
+		result := &QueryResult{Duration: time.Since(start).String(), IsSelect: true}
+		result.setError(err)
+		return result
 	}
 	defer rows.Close()
+	return scanResultSet(rows, start, opts)
+}
 
+// scanResultSet reads the current result set of rows, leaving rows open
+// so the caller can move on to the next one.
+func scanResultSet(rows *sql.Rows, start time.Time, opts ExecOptions) *QueryResult {
 	cols, err := rows.Columns()
 	if err != nil {
-		return &QueryResult{
-			Error:    err.Error(),
-			Duration: time.Since(start).String(),
-			IsSelect: true,
-		}
+		result := &QueryResult{Duration: time.Since(start).String(), IsSelect: true}
+		result.setError(err)
+		return result
 	}
 
 	// Detect binary columns via column types.
 	colTypes, _ := rows.ColumnTypes()
-	isBinary := make([]bool, len(cols))
+	if opts.Typed {
+		return scanTyped(rows, cols, colTypes, start, opts.MaxCellLength, opts.MaxRows)
+	}
+	scanner := newGridScanner(colTypes, len(cols), opts.MaxCellLength)
+	result := &QueryResult{Columns: cols, IsSelect: true, NullCells: [][2]int{}}
+	for rows.Next() {
+		if opts.MaxRows > 0 && len(result.Rows) >= opts.MaxRows {
+			result.truncate(opts.MaxRows)
+			break
+		}
+		row, cut, nulls, err := scanner.scan(rows)
+		if err != nil {
+			result.setError(err)
+			break
+		}
+		for _, i := range cut {
+			result.TruncatedCells = append(result.TruncatedCells, [2]int{len(result.Rows), i})
+		}
+		for _, i := range nulls {
+			result.NullCells = append(result.NullCells, [2]int{len(result.Rows), i})
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil && result.Error == "" {
+		result.setError(err)
+	}
+
+	result.RowCount = len(result.Rows)
+	result.Duration = time.Since(start).String()
+	return result
+}
+
+// truncate marks a result cut off at the row cap. The caller stops reading;
+// closing the rows then discards the rest of the result without keeping it.
+func (r *QueryResult) truncate(maxRows int) {
+	r.Truncated = true
+	r.Message = fmt.Sprintf("Only the first %d rows were fetched; the result has more. Add a LIMIT or raise the row cap to see the rest.", maxRows)
+}
+
+// gridScanner scans rows into grid text: NULL as "NULL", binary data as a
+// placeholder, and text longer than maxCell bytes cut short.
+type gridScanner struct {
+	isBinary []bool
+	args     []interface{}
+	maxCell  int
+}
+
+func newGridScanner(colTypes []*sql.ColumnType, n, maxCell int) *gridScanner {
+	g := &gridScanner{isBinary: make([]bool, n), args: make([]interface{}, n), maxCell: maxCell}
+	// Detect binary columns via column types.
 	for i, ct := range colTypes {
-		if ct != nil {
+		if ct != nil && i < n {
 			typeName := strings.ToUpper(ct.DatabaseTypeName())
-			isBinary[i] = strings.Contains(typeName, "BLOB") ||
+			g.isBinary[i] = strings.Contains(typeName, "BLOB") ||
 				strings.Contains(typeName, "BINARY") ||
 				typeName == "GEOMETRY"
 		}
 	}
-
-	var resultRows [][]string
-	scanArgs := make([]interface{}, len(cols))
-	for i := range scanArgs {
-		if isBinary[i] {
-			scanArgs[i] = &sql.RawBytes{}
+	for i := range g.args {
+		if g.isBinary[i] {
+			g.args[i] = &sql.RawBytes{}
 		} else {
-			scanArgs[i] = &sql.NullString{}
+			g.args[i] = &sql.NullString{}
 		}
 	}
+	return g
+}
 
-	for rows.Next() {
-		if err := rows.Scan(scanArgs...); err != nil {
-			return &QueryResult{
-				Columns:  cols,
-				Rows:     resultRows,
-				RowCount: len(resultRows),
-				Error:    err.Error(),
-				Duration: time.Since(start).String(),
-				IsSelect: true,
+// scan reads the current row. cut lists the columns whose text was
+// truncated and nulls the columns that are NULL.
+func (g *gridScanner) scan(rows *sql.Rows) (row []string, cut, nulls []int, err error) {
+	if err := rows.Scan(g.args...); err != nil {
+		return nil, nil, nil, err
+	}
+	row = make([]string, len(g.args))
+	for i := range g.args {
+		if g.isBinary[i] {
+			raw := g.args[i].(*sql.RawBytes)
+			if *raw == nil {
+				row[i] = "NULL"
+				nulls = append(nulls, i)
+			} else {
+				row[i] = binaryPlaceholder(*raw)
 			}
+			continue
 		}
+		ns := g.args[i].(*sql.NullString)
+		if !ns.Valid {
+			row[i] = "NULL"
+			nulls = append(nulls, i)
+			continue
+		}
+		var truncated bool
+		if row[i], truncated = truncateCell(ns.String, g.maxCell); truncated {
+			cut = append(cut, i)
+		}
+	}
+	return row, cut, nulls, nil
+}
 
-		row := make([]string, len(cols))
-		for i := range cols {
-			if isBinary[i] {
-				raw := scanArgs[i].(*sql.RawBytes)
-				if *raw == nil {
-					row[i] = "NULL"
-				} else if len(*raw) == 0 {
-					row[i] = "(empty)"
-				} else {
-					row[i] = fmt.Sprintf("(binary %d bytes)", len(*raw))
-				}
-			} else {
-				ns := scanArgs[i].(*sql.NullString)
-				if ns.Valid {
-					row[i] = ns.String
-				} else {
-					row[i] = "NULL"
+// truncateCell shortens s to at most max bytes, cut at a character
+// boundary, and appends an ellipsis. max <= 0 leaves s alone.
+func truncateCell(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…", true
+}
+
+// scanTyped reads rows as JSON-typed values. Text and JSON values longer
+// than maxCell bytes are cut, and reading stops after maxRows rows, as in
+// the untyped grid.
+func scanTyped(rows *sql.Rows, cols []string, colTypes []*sql.ColumnType, start time.Time, maxCell, maxRows int) *QueryResult {
+	result := &QueryResult{
+		Columns:    cols,
+		ColumnMeta: columnMeta(cols, colTypes),
+		Values:     [][]interface{}{},
+		IsSelect:   true,
+	}
+
+	scanVals := make([]interface{}, len(cols))
+	scanPtrs := make([]interface{}, len(cols))
+	for i := range scanVals {
+		scanPtrs[i] = &scanVals[i]
+	}
+
+	for rows.Next() {
+		if maxRows > 0 && len(result.Values) >= maxRows {
+			result.truncate(maxRows)
+			break
+		}
+		if err := rows.Scan(scanPtrs...); err != nil {
+			result.setError(err)
+			break
+		}
+		row := make([]interface{}, len(cols))
+		for i, v := range scanVals {
+			meta := result.ColumnMeta[i]
+			if b, ok := v.([]byte); ok && (meta.Kind == KindString || meta.Kind == KindJSON) {
+				if text, cut := truncateCell(string(b), maxCell); cut {
+					row[i] = text
+					result.TruncatedCells = append(result.TruncatedCells, [2]int{len(result.Values), i})
+					continue
 				}
 			}
+			row[i] = typedValue(v, meta)
+		}
+		result.Values = append(result.Values, row)
+	}
+	if err := rows.Err(); err != nil && result.Error == "" {
+		result.setError(err)
+	}
+
+	result.RowCount = len(result.Values)
+	result.Duration = time.Since(start).String()
+	return result
+}
+
+// executePage runs a paginated SELECT. It fetches one row past the page
+// to learn whether another page exists, then trims it.
+func executePage(ctx context.Context, db Querier, query string, opts ExecOptions, start time.Time, args ...interface{}) *QueryResult {
+	limit := opts.PageSize
+	result := executeSelect(ctx, db, query, start, opts, args...)
+	result.Paginated = true
+	result.Offset = opts.PageOffset
+	result.Limit = limit
+	if result.RowCount > limit {
+		if result.Values != nil {
+			result.Values = result.Values[:limit]
+		} else {
+			result.Rows = result.Rows[:limit]
 		}
-		resultRows = append(resultRows, row)
+		result.RowCount = limit
+		result.HasMore = true
+
+		result.TruncatedCells = cellsBefore(result.TruncatedCells, limit)
+		result.NullCells = cellsBefore(result.NullCells, limit)
 	}
+	return result
+}
 
-	if err := rows.Err(); err != nil {
-		return &QueryResult{
-			Columns:  cols,
-			Rows:     resultRows,
-			RowCount: len(resultRows),
-			Error:    err.Error(),
-			Duration: time.Since(start).String(),
-			IsSelect: true,
+// cellsBefore filters [row, column] positions down to the first n rows.
+func cellsBefore(cells [][2]int, n int) [][2]int {
+	kept := cells[:0]
+	for _, cell := range cells {
+		if cell[0] < n {
+			kept = append(kept, cell)
 		}
 	}
+	return kept
+}
 
-	return &QueryResult{
-		Columns:  cols,
-		Rows:     resultRows,
-		RowCount: len(resultRows),
-		Duration: time.Since(start).String(),
-		IsSelect: true,
+// paginate appends a LIMIT to a plain SELECT that has none. Statements that
+// already limit themselves, write somewhere (INTO), or take locks are left
+// alone, since a trailing LIMIT would change their meaning or be invalid.
+func paginate(query string, offset, limit int) (string, bool) {
+	if limit <= 0 {
+		return query, false
+	}
+	words := topLevelWords(query)
+	if len(words) == 0 || words[0] != "SELECT" {
+		return query, false
+	}
+	for _, w := range []string{"LIMIT", "INTO", "FOR", "LOCK", "PROCEDURE"} {
+		if containsWord(words, w) {
+			return query, false
+		}
+	}
+	if offset < 0 {
+		offset = 0
 	}
+	// A newline keeps a trailing "-- comment" from swallowing the LIMIT.
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	return fmt.Sprintf("%s\nLIMIT %d OFFSET %d", query, limit+1, offset), true
 }
 
-func executeExec(ctx context.Context, db *sql.DB, query string, start time.Time) *QueryResult {
-	result, err := db.ExecContext(ctx, query)
+func executeExec(ctx context.Context, db Querier, query string, start time.Time, args ...interface{}) *QueryResult {
+	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return &QueryResult{
-			Error:    err.Error(),
-			Duration: time.Since(start).String(),
-		}
+		failed := &QueryResult{Duration: time.Since(start).String()}
+		failed.setError(err)
+		return failed
 	}
 
 	affected, _ := result.RowsAffected()
+	// Zero means the statement generated no AUTO_INCREMENT value.
+	lastID, _ := result.LastInsertId()
 
 	return &QueryResult{
 		AffectedRows: affected,
+		LastInsertID: lastID,
 		Duration:     time.Since(start).String(),
 	}
 }
 
+// isCallQuery reports whether query calls a stored procedure.
+func isCallQuery(query string) bool {
+	words := topLevelWords(query)
+	return len(words) > 0 && words[0] == "CALL"
+}
+
+// isSelectQuery reports whether query only reads rows: SELECT, SHOW,
+// DESCRIBE, EXPLAIN, TABLE, VALUES, or a WITH whose main statement is one
+// of those rather than an UPDATE or DELETE. CALL returns rows too, but
+// the procedure may write, so executeQuery routes it on its own.
 func isSelectQuery(query string) bool {
-	upper := strings.ToUpper(strings.TrimSpace(query))
-	return strings.HasPrefix(upper, "SELECT") ||
-		strings.HasPrefix(upper, "SHOW") ||
-		strings.HasPrefix(upper, "DESCRIBE") ||
-		strings.HasPrefix(upper, "DESC") ||
-		strings.HasPrefix(upper, "EXPLAIN")
+	words := mainStatement(topLevelWords(query))
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "TABLE", "VALUES":
+		return true
+	}
+	return false
 }
 
+// splitStatements splits a batch into statements, honoring DELIMITER
+// directives.
 func splitStatements(sql string) []string {
 	var stmts []string
-	var current strings.Builder
-	inString := false
-	stringChar := byte(0)
-	escaped := false
-
-	for i := 0; i < len(sql); i++ {
-		c := sql[i]
-
-		if escaped {
-			current.WriteByte(c)
-			escaped = false
-			continue
-		}
-
-		if c == '\\' && inString {
-			current.WriteByte(c)
-			escaped = true
-			continue
-		}
-
-		if (c == '\'' || c == '"') && !inString {
-			inString = true
-			stringChar = c
-			current.WriteByte(c)
-			continue
-		}
-
-		if inString && c == stringChar {
-			inString = false
-			current.WriteByte(c)
-			continue
-		}
-
-		if c == ';' && !inString {
-			stmt := strings.TrimSpace(current.String())
-			if stmt != "" {
-				stmts = append(stmts, stmt)
-			}
-			current.Reset()
-			continue
-		}
-
-		current.WriteByte(c)
+	s := newStmtSplitter()
+	for _, line := range strings.Split(sql, "\n") {
+		stmts = append(stmts, s.feedLine(line)...)
 	}
-
-	// Last statement without trailing semicolon.
-	stmt := strings.TrimSpace(current.String())
-	if stmt != "" {
+	if stmt := s.flush(); stmt != "" {
 		stmts = append(stmts, stmt)
 	}
-
 	return stmts
 }
@@ -0,0 +1,42 @@
+package database
+
+import "testing"
+
+func TestStatementRouting(t *testing.T) {
+	tests := []struct {
+		query string
+		rows  bool // isSelectQuery: read as a result set
+		call  bool // isCallQuery
+	}{
+		{"SELECT * FROM t", true, false},
+		{"  select 1", true, false},
+		{"/* hint */ SELECT 1", true, false},
+		{"SHOW TABLES", true, false},
+		{"DESCRIBE t", true, false},
+		{"DESC t", true, false},
+		{"EXPLAIN SELECT 1", true, false},
+		{"TABLE t", true, false},
+		{"TABLE t ORDER BY a LIMIT 2", true, false},
+		{"VALUES ROW(1, 2), ROW(3, 4)", true, false},
+		{"WITH c AS (SELECT 1) SELECT * FROM c", true, false},
+		{"WITH RECURSIVE c (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM c WHERE n < 5) SELECT * FROM c", true, false},
+		{"WITH a AS (SELECT 1), b AS (SELECT 2) TABLE a", true, false},
+		{"WITH c AS (SELECT id FROM t) UPDATE t JOIN c USING (id) SET t.a = 1", false, false},
+		{"WITH c AS (SELECT id FROM t) DELETE t FROM t JOIN c USING (id)", false, false},
+		{"WITH c AS (SELECT 1 AS id) INSERT INTO t SELECT * FROM c", false, false},
+		{"INSERT INTO t SELECT * FROM u", false, false},
+		{"UPDATE t SET a = (SELECT 1)", false, false},
+		{"CALL p()", false, true},
+		{"call db.p(@out)", false, true},
+		{"-- run it\nCALL p", false, true},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if got := isSelectQuery(tt.query); got != tt.rows {
+			t.Errorf("isSelectQuery(%q) = %v, want %v", tt.query, got, tt.rows)
+		}
+		if got := isCallQuery(tt.query); got != tt.call {
+			t.Errorf("isCallQuery(%q) = %v, want %v", tt.query, got, tt.call)
+		}
+	}
+}
@@ -1,45 +1,66 @@
 package database
 
 import (
+	"compress/gzip"
 	"context"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
 	"io"
 	"strings"
 )
 
 // ExportResultCSV writes query result data (columns + rows) to a CSV writer.
-func ExportResultCSV(w io.Writer, columns []string, rows [][]string) error {
-	cw := csv.NewWriter(w)
+// NULL cells are written as opts.NullToken.
+func ExportResultCSV(w io.Writer, columns []string, rows [][]string, nulls GridNulls, opts CSVOptions) error {
+	cw, err := opts.newWriter(w)
+	if err != nil {
+		return err
+	}
 	defer cw.Flush()
 
-	if err := cw.Write(columns); err != nil {
-		return err
+	if opts.HasHeader {
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
 	}
-	for _, row := range rows {
-		if err := cw.Write(row); err != nil {
+	isNull := nulls.lookup()
+	for r, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			if isNull(r, i, v) {
+				v = opts.NullToken
+			}
+			record[i] = v
+		}
+		if err := cw.Write(record); err != nil {
 			return err
 		}
 	}
+	cw.Flush()
 	return cw.Error()
 }
 
 // ExportResultSQL writes query result data as SQL INSERT statements.
-// tableName is used in the INSERT INTO clause.
-func ExportResultSQL(w io.Writer, tableName string, columns []string, rows [][]string) error {
-	for _, row := range rows {
+// tableName is used in the INSERT INTO clause. columnTypes, when given,
+// holds each column's database type name so numbers are left unquoted.
+func ExportResultSQL(w io.Writer, tableName string, columns []string, rows [][]string, nulls GridNulls, columnTypes []string) error {
+	isNull := nulls.lookup()
+	for r, row := range rows {
 		vals := make([]string, len(row))
 		for i, v := range row {
-			if v == "NULL" {
+			if isNull(r, i, v) {
 				vals[i] = "NULL"
-			} else {
-				vals[i] = "'" + strings.ReplaceAll(v, "'", "\\'") + "'"
+				continue
+			}
+			typeName := ""
+			if i < len(columnTypes) {
+				typeName = columnTypes[i]
 			}
+			vals[i] = sqlTextLiteral(v, typeName)
 		}
-		line := fmt.Sprintf("INSERT INTO `%s` (`%s`) VALUES (%s);\n",
-			tableName,
-			strings.Join(columns, "`, `"),
+		line := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);\n",
+			quoteIdent(tableName),
+			quoteIdentList(columns),
 			strings.Join(vals, ", "),
 		)
 		if _, err := io.WriteString(w, line); err != nil {
@@ -49,38 +70,112 @@ func ExportResultSQL(w io.Writer, tableName string, columns []string, rows [][]s
 	return nil
 }
 
+// WriteCompressed runs write against w, gzip-compressing the output when
+// compress is set. The gzip stream is closed before returning so the file
+// isn't truncated.
+func WriteCompressed(w io.Writer, compress bool, write func(io.Writer) error) error {
+	if !compress {
+		return write(w)
+	}
+	gz := gzip.NewWriter(w)
+	if err := write(gz); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
 // ProgressFunc is called with (current, total) to report progress.
 // Return false to cancel the operation.
 type ProgressFunc func(current, total int64) bool
 
-// ExportTableCSV streams an entire table to CSV.
-func ExportTableCSV(ctx context.Context, db *sql.DB, dbName, tableName string, w io.Writer, progress ProgressFunc) error {
-	// Get row count for progress reporting.
-	var totalRows int64
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", dbName, tableName)
-	if err := db.QueryRowContext(ctx, countQuery).Scan(&totalRows); err != nil {
-		totalRows = -1 // unknown, continue anyway
+// ExportFilter narrows a table export to some rows and columns. The zero
+// value exports the whole table. Where and OrderBy are raw SQL fragments.
+type ExportFilter struct {
+	Columns []string `json:"columns"`
+	Where   string   `json:"where"`
+	OrderBy string   `json:"orderBy"`
+	Limit   int      `json:"limit"` // 0 means no limit
+}
+
+// selectQuery builds the SELECT for exporting the filtered table.
+func (f ExportFilter) selectQuery(dbName, tableName string) string {
+	cols := "*"
+	if len(f.Columns) > 0 {
+		cols = quoteIdentList(f.Columns)
 	}
 
-	query := fmt.Sprintf("SELECT * FROM `%s`.`%s`", dbName, tableName)
-	rows, err := db.QueryContext(ctx, query)
+	query := fmt.Sprintf("SELECT %s FROM %s", cols, qualifiedName(dbName, tableName)) + f.whereClause()
+	if f.OrderBy != "" {
+		query += "\nORDER BY " + f.OrderBy
+	}
+	if f.Limit > 0 {
+		query += fmt.Sprintf("\nLIMIT %d", f.Limit)
+	}
+	return query
+}
+
+// A newline before each clause keeps a trailing "-- comment" in the
+// user's fragment from swallowing the rest of the query.
+func (f ExportFilter) whereClause() string {
+	if strings.TrimSpace(f.Where) == "" {
+		return ""
+	}
+	return "\nWHERE " + f.Where
+}
+
+// countRows returns how many rows the filtered export will write, or -1
+// if the count fails.
+func (f ExportFilter) countRows(ctx context.Context, db *sql.DB, dbName, tableName string) int64 {
+	total, err := CountRows(ctx, db, dbName, tableName, f.Where)
+	if err != nil {
+		return -1 // unknown, continue anyway
+	}
+	if f.Limit > 0 && total > int64(f.Limit) {
+		total = int64(f.Limit)
+	}
+	return total
+}
+
+// ExportTableCSV streams a table, or the rows and columns filter selects,
+// to CSV.
+func ExportTableCSV(ctx context.Context, db *sql.DB, dbName, tableName string, filter ExportFilter, w io.Writer, opts CSVOptions, progress ProgressFunc) error {
+	// Get row count for progress reporting.
+	totalRows := filter.countRows(ctx, db, dbName, tableName)
+
+	rows, err := db.QueryContext(ctx, filter.selectQuery(dbName, tableName))
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
+	return writeRowsCSV(ctx, rows, w, opts, progress, totalRows)
+}
 
-	cols, err := rows.Columns()
+// writeRowsCSV streams rows to CSV, reporting progress against totalRows
+// (-1 when unknown).
+func writeRowsCSV(ctx context.Context, rows *sql.Rows, w io.Writer, opts CSVOptions, progress ProgressFunc, totalRows int64) error {
+	cw, err := opts.newWriter(w)
 	if err != nil {
 		return err
 	}
-
-	cw := csv.NewWriter(w)
 	defer cw.Flush()
 
-	if err := cw.Write(cols); err != nil {
+	cols, err := rows.Columns()
+	if err != nil {
 		return err
 	}
 
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	if opts.HasHeader {
+		if err := cw.Write(cols); err != nil {
+			return err
+		}
+	}
+
 	scanVals := make([]interface{}, len(cols))
 	scanPtrs := make([]interface{}, len(cols))
 	for i := range scanVals {
@@ -99,9 +194,9 @@ func ExportTableCSV(ctx context.Context, db *sql.DB, dbName, tableName string, w
 		record := make([]string, len(cols))
 		for i, v := range scanVals {
 			if v == nil {
-				record[i] = "NULL"
+				record[i] = opts.NullToken
 			} else {
-				record[i] = fmt.Sprintf("%s", v)
+				record[i] = opts.fieldText(v, colTypes[i].DatabaseTypeName())
 			}
 		}
 		if err := cw.Write(record); err != nil {
@@ -124,25 +219,45 @@ func ExportTableCSV(ctx context.Context, db *sql.DB, dbName, tableName string, w
 	return rows.Err()
 }
 
-// ExportTableSQL streams an entire table as SQL INSERT statements.
-func ExportTableSQL(ctx context.Context, db *sql.DB, dbName, tableName string, w io.Writer, progress ProgressFunc) error {
-	var totalRows int64
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", dbName, tableName)
-	if err := db.QueryRowContext(ctx, countQuery).Scan(&totalRows); err != nil {
-		totalRows = -1
-	}
+// maxInsertBytes caps the size of one extended INSERT so dumps stay under
+// the server's default max_allowed_packet on re-import.
+const maxInsertBytes = 1 << 20
 
-	query := fmt.Sprintf("SELECT * FROM `%s`.`%s`", dbName, tableName)
-	rows, err := db.QueryContext(ctx, query)
+// SQLExportOptions controls the statements ExportTableSQL writes.
+type SQLExportOptions struct {
+	// RowsPerInsert batches rows into extended INSERTs like mysqldump's
+	// --extended-insert. 0 or 1 writes one INSERT per row, which diffs well.
+	RowsPerInsert int `json:"rowsPerInsert"`
+}
+
+// ExportTableSQL streams a table, or the rows and columns filter selects,
+// as SQL INSERT statements.
+func ExportTableSQL(ctx context.Context, db *sql.DB, dbName, tableName string, filter ExportFilter, w io.Writer, opts SQLExportOptions, progress ProgressFunc) error {
+	totalRows := filter.countRows(ctx, db, dbName, tableName)
+	return writeTableInserts(ctx, db, dbName, tableName, filter, w, opts, progress, totalRows)
+}
+
+// writeTableInserts streams a table's rows as INSERT statements.
+func writeTableInserts(ctx context.Context, db *sql.DB, dbName, tableName string, filter ExportFilter, w io.Writer, opts SQLExportOptions, progress ProgressFunc, totalRows int64) error {
+	rows, err := db.QueryContext(ctx, filter.selectQuery(dbName, tableName))
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
+	return writeInserts(ctx, rows, tableName, w, opts, progress, totalRows)
+}
 
+// writeInserts streams rows as INSERT statements into tableName, reporting
+// progress against totalRows (-1 when unknown).
+func writeInserts(ctx context.Context, rows *sql.Rows, tableName string, w io.Writer, opts SQLExportOptions, progress ProgressFunc, totalRows int64) error {
 	cols, err := rows.Columns()
 	if err != nil {
 		return err
 	}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
 
 	scanVals := make([]interface{}, len(cols))
 	scanPtrs := make([]interface{}, len(cols))
@@ -150,7 +265,14 @@ func ExportTableSQL(ctx context.Context, db *sql.DB, dbName, tableName string, w
 		scanPtrs[i] = &scanVals[i]
 	}
 
+	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteIdent(tableName), quoteIdentList(cols))
+	if opts.RowsPerInsert > 1 {
+		insertPrefix += "\n"
+	}
+
+	// inStmt counts the rows written to the INSERT still open, if any.
 	var written int64
+	var inStmt, stmtBytes int
 	for rows.Next() {
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -161,22 +283,25 @@ func ExportTableSQL(ctx context.Context, db *sql.DB, dbName, tableName string, w
 
 		vals := make([]string, len(cols))
 		for i, v := range scanVals {
-			if v == nil {
-				vals[i] = "NULL"
-			} else {
-				s := fmt.Sprintf("%s", v)
-				vals[i] = "'" + strings.ReplaceAll(s, "'", "\\'") + "'"
-			}
+			vals[i] = sqlLiteral(v, colTypes[i].DatabaseTypeName())
 		}
+		tuple := "(" + strings.Join(vals, ", ") + ")"
 
-		line := fmt.Sprintf("INSERT INTO `%s` (`%s`) VALUES (%s);\n",
-			tableName,
-			strings.Join(cols, "`, `"),
-			strings.Join(vals, ", "),
-		)
-		if _, err := io.WriteString(w, line); err != nil {
+		var chunk string
+		switch {
+		case inStmt == 0:
+			chunk = insertPrefix + tuple
+		case inStmt >= opts.RowsPerInsert || stmtBytes+len(tuple) > maxInsertBytes:
+			chunk = ";\n" + insertPrefix + tuple
+			inStmt, stmtBytes = 0, 0
+		default:
+			chunk = ",\n" + tuple
+		}
+		if _, err := io.WriteString(w, chunk); err != nil {
 			return err
 		}
+		inStmt++
+		stmtBytes += len(chunk)
 
 		written++
 		if progress != nil && written%500 == 0 {
@@ -186,9 +311,172 @@ func ExportTableSQL(ctx context.Context, db *sql.DB, dbName, tableName string, w
 		}
 	}
 
+	if inStmt > 0 {
+		if _, err := io.WriteString(w, ";\n"); err != nil {
+			return err
+		}
+	}
+
 	if progress != nil {
 		progress(written, totalRows)
 	}
 
 	return rows.Err()
 }
+
+// DatabaseExportOptions controls what ExportDatabaseSQL writes.
+type DatabaseExportOptions struct {
+	SQLExportOptions
+
+	SchemaOnly      bool `json:"schemaOnly"` // skip table data
+	IncludeRoutines bool `json:"includeRoutines"`
+	IncludeTriggers bool `json:"includeTriggers"`
+}
+
+// ExportDatabaseSQL streams a mysqldump-style dump of a whole database:
+// each table's DDL and data, then views, routines, and triggers. Objects
+// are guarded with DROP ... IF EXISTS, and the dump splits cleanly with
+// ImportSQLFile. progress reports tables done out of tables total.
+func ExportDatabaseSQL(ctx context.Context, db *sql.DB, dbName string, w io.Writer, opts DatabaseExportOptions, progress ProgressFunc) error {
+	tables, err := ListTables(db, dbName)
+	if err != nil {
+		return err
+	}
+	var routines []RoutineInfo
+	if opts.IncludeRoutines {
+		if routines, err = ListRoutines(db, dbName); err != nil {
+			return err
+		}
+	}
+	var triggers []TriggerInfo
+	if opts.IncludeTriggers {
+		if triggers, err = ListTriggers(db, dbName); err != nil {
+			return err
+		}
+	}
+
+	// A line break in the name would end the comment early.
+	header := fmt.Sprintf("-- Dump of database %s\n\n"+
+		"SET NAMES utf8mb4;\n"+
+		"SET @OLD_FOREIGN_KEY_CHECKS = @@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS = 0;\n\n", strings.NewReplacer("\n", " ", "\r", " ").Replace(quoteIdent(dbName)))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	total := int64(len(tables))
+	var done int64
+	report := func() error {
+		done++
+		if progress != nil && !progress(done, total) {
+			return fmt.Errorf("cancelled")
+		}
+		return nil
+	}
+
+	// ListTables returns base tables before views, so views are created
+	// after the tables they select from.
+	for _, t := range tables {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if t.Type == "VIEW" {
+			ddl, err := showCreate(ctx, db, fmt.Sprintf("SHOW CREATE VIEW %s", qualifiedName(dbName, t.Name)), 1)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "DROP VIEW IF EXISTS %s;\n%s;\n\n", quoteIdent(t.Name), ddl); err != nil {
+				return err
+			}
+			if err := report(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ddl, err := getCreateTable(db, dbName, t.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n%s;\n\n", quoteIdent(t.Name), ddl); err != nil {
+			return err
+		}
+		if !opts.SchemaOnly {
+			if err := writeTableInserts(ctx, db, dbName, t.Name, ExportFilter{}, w, opts.SQLExportOptions, nil, -1); err != nil {
+				return fmt.Errorf("exporting %s: %w", t.Name, err)
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := report(); err != nil {
+			return err
+		}
+	}
+
+	if len(routines) > 0 || len(triggers) > 0 {
+		if _, err := io.WriteString(w, "DELIMITER ;;\n\n"); err != nil {
+			return err
+		}
+	}
+	for _, r := range routines {
+		ddl, err := showCreate(ctx, db, fmt.Sprintf("SHOW CREATE %s %s", r.Type, qualifiedName(dbName, r.Name)), 2)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "DROP %s IF EXISTS %s;;\n%s;;\n\n", r.Type, quoteIdent(r.Name), ddl); err != nil {
+			return err
+		}
+	}
+	// Triggers come last so they don't fire while the data loads.
+	for _, t := range triggers {
+		ddl, err := showCreate(ctx, db, fmt.Sprintf("SHOW CREATE TRIGGER %s", qualifiedName(dbName, t.Name)), 2)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "DROP TRIGGER IF EXISTS %s;;\n%s;;\n\n", quoteIdent(t.Name), ddl); err != nil {
+			return err
+		}
+	}
+	if len(routines) > 0 || len(triggers) > 0 {
+		if _, err := io.WriteString(w, "DELIMITER ;\n\n"); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "SET FOREIGN_KEY_CHECKS = @OLD_FOREIGN_KEY_CHECKS;\n")
+	return err
+}
+
+// showCreate runs a SHOW CREATE statement and returns the DDL found in
+// column col of its single row.
+func showCreate(ctx context.Context, db *sql.DB, query string, col int) (string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s returned no rows", query)
+	}
+	vals := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", err
+	}
+	if col >= len(vals) || !vals[col].Valid {
+		return "", fmt.Errorf("%s returned no definition; the account may lack privileges to see it", query)
+	}
+	return vals[col].String, nil
+}
@@ -0,0 +1,415 @@
+package database
+
+import (
+	"bytes"
+	"strings"
+)
+
+// FormatSQL pretty-prints SQL for the editor: reserved words are
+// upper-cased, major clauses start on their own line, select lists and
+// WHERE conditions are broken one per line, and subqueries are indented.
+// String literals, quoted identifiers, and comments are kept as written.
+// Scripts using DELIMITER directives are returned unchanged, since their
+// routine bodies can't be re-split safely.
+func FormatSQL(sql string) string {
+	for _, line := range strings.Split(sql, "\n") {
+		if _, ok := parseDelimiter(line); ok {
+			return sql
+		}
+	}
+
+	toks := tokenizeSQL(sql)
+	f := &sqlFormatter{toks: toks, atLine: true}
+	f.reset()
+	for i := range toks {
+		f.format(i)
+	}
+	return strings.TrimSpace(string(f.out)) + "\n"
+}
+
+// Token kinds produced by tokenizeSQL.
+const (
+	tokWord = iota
+	tokQuoted
+	tokLineComment
+	tokBlockComment
+	tokOpen
+	tokClose
+	tokComma
+	tokSemicolon
+	tokDot
+	tokOp
+)
+
+type sqlToken struct {
+	kind        int
+	text        string
+	spaceBefore bool // whitespace preceded the token in the source
+}
+
+// upper returns the token's text upper-cased, for keyword comparisons.
+func (t sqlToken) upper() string {
+	if t.kind != tokWord {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+var multiCharOps = []string{"<=>", "->>", "<=", ">=", "<>", "!=", ":=", "->", "||", "&&", "<<", ">>"}
+
+// tokenizeSQL splits SQL into tokens, dropping whitespace.
+func tokenizeSQL(sql string) []sqlToken {
+	var toks []sqlToken
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		start := i
+		kind := tokOp
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(sql, i)
+			kind = tokQuoted
+		case c == '#' || (c == '-' && strings.HasPrefix(sql[i:], "-- ")):
+			for i+1 < len(sql) && sql[i+1] != '\n' {
+				i++
+			}
+			kind = tokLineComment
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql) - 1
+			} else {
+				i += end + 3
+			}
+			kind = tokBlockComment
+		case isWordChar(c) || c == '@':
+			for i+1 < len(sql) && (isWordChar(sql[i+1]) || sql[i+1] == '@') {
+				i++
+				// Keep the sign of an exponent: 1e-5.
+				if e := sql[i]; (e == 'e' || e == 'E') && c >= '0' && c <= '9' &&
+					i+2 < len(sql) && (sql[i+1] == '-' || sql[i+1] == '+') && sql[i+2] >= '0' && sql[i+2] <= '9' {
+					i++
+				}
+			}
+			kind = tokWord
+		case c == '(':
+			kind = tokOpen
+		case c == ')':
+			kind = tokClose
+		case c == ',':
+			kind = tokComma
+		case c == ';':
+			kind = tokSemicolon
+		case c == '.':
+			kind = tokDot
+		default:
+			for _, op := range multiCharOps {
+				if strings.HasPrefix(sql[i:], op) {
+					i += len(op) - 1
+					break
+				}
+			}
+		}
+		toks = append(toks, sqlToken{kind: kind, text: sql[start : i+1], spaceBefore: space})
+		space = false
+	}
+	return toks
+}
+
+// formatReserved are the MySQL reserved words FormatSQL upper-cases. Only
+// reserved words are touched: they can't be unquoted table names, whose
+// case may matter to the server.
+var formatReserved = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		ADD ALL ALTER AND AS ASC BETWEEN BY CALL CASCADE CASE CHANGE CHARACTER
+		CHECK COLLATE COLUMN CONSTRAINT CREATE CROSS DATABASE DEFAULT DELETE
+		DESC DESCRIBE DISTINCT DIV DROP ELSE ELSEIF EXISTS EXPLAIN FALSE FOR
+		FOREIGN FROM FULLTEXT GRANT GROUP HAVING IF IGNORE IN INDEX INNER
+		INSERT INTERVAL INTO IS JOIN KEY KEYS KILL LEFT LIKE LIMIT LOCK MOD
+		NATURAL NOT NULL ON OR ORDER OUTER OVER PARTITION PRIMARY PROCEDURE
+		REFERENCES REGEXP RENAME REPLACE REVOKE RIGHT SCHEMA SELECT SET SHOW
+		STRAIGHT_JOIN TABLE THEN TO TRIGGER TRUE UNION UNIQUE UNLOCK UPDATE
+		USE USING VALUES WHEN WHERE WINDOW WITH XOR`) {
+		formatReserved[w] = true
+	}
+}
+
+// clauseWords start a new line when they begin a clause.
+var clauseWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "HAVING": true, "LIMIT": true,
+	"SET": true, "VALUES": true, "UNION": true, "WINDOW": true,
+}
+
+// joinWords may begin a JOIN clause, e.g. LEFT OUTER JOIN.
+var joinWords = map[string]bool{
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "CROSS": true,
+	"NATURAL": true, "STRAIGHT_JOIN": true, "OUTER": true,
+}
+
+// fmtFrame is a parenthesized level of the statement. Only the statement
+// itself and subqueries get clause breaks; other parentheses (function
+// calls, column lists, grouped conditions) are kept on one line.
+type fmtFrame struct {
+	indent      int
+	breaks      bool
+	closeIndent int    // indent of the line holding the opening parenthesis
+	clause      string // current clause keyword, e.g. "WHERE"
+	started     bool   // a token has been written in this frame
+	between     bool   // a BETWEEN is waiting for its AND
+	listBreak   bool   // the select list is broken one item per line
+	pendingList bool   // break before the first select item
+}
+
+type sqlFormatter struct {
+	toks      []sqlToken
+	out       []byte
+	frames    []fmtFrame
+	lineStart int  // offset in out where the current line begins
+	atLine    bool // nothing written on the current line yet
+	indent    int  // indent of the current line
+	prev      *sqlToken
+	unary     bool // prev is a unary operator
+}
+
+func (f *sqlFormatter) reset() {
+	f.frames = []fmtFrame{{breaks: true}}
+	f.prev = nil
+}
+
+func (f *sqlFormatter) top() *fmtFrame {
+	return &f.frames[len(f.frames)-1]
+}
+
+// newline starts a line at the given indent level, reusing the current
+// line if nothing has been written on it yet.
+func (f *sqlFormatter) newline(indent int) {
+	if f.atLine {
+		f.out = f.out[:f.lineStart]
+	} else {
+		f.out = bytes.TrimRight(f.out, " ")
+		f.out = append(f.out, '\n')
+		f.lineStart = len(f.out)
+	}
+	f.out = append(f.out, strings.Repeat("  ", indent)...)
+	f.atLine = true
+	f.indent = indent
+}
+
+func (f *sqlFormatter) write(t sqlToken, text string) {
+	if !f.atLine && f.needsSpace(t) {
+		f.out = append(f.out, ' ')
+	}
+	f.out = append(f.out, text...)
+	f.atLine = false
+	f.unary = false
+	tok := t
+	f.prev = &tok
+}
+
+func (f *sqlFormatter) needsSpace(t sqlToken) bool {
+	p := f.prev
+	switch {
+	case p == nil, f.unary:
+		return false
+	case p.kind == tokOpen || p.kind == tokDot:
+		return false
+	case t.kind == tokClose || t.kind == tokComma || t.kind == tokSemicolon || t.kind == tokDot:
+		return false
+	case t.kind == tokOpen && (p.kind == tokWord || p.kind == tokQuoted):
+		// Built-in functions must not be separated from their argument
+		// list, so keep whatever spacing the source had.
+		return t.spaceBefore
+	case t.kind == tokQuoted && p.kind == tokWord, p.text == "@", t.text == "@":
+		// Introducers and literal prefixes (_utf8mb4'x', X'ff') and
+		// 'user'@'host' must stay joined.
+		return t.spaceBefore
+	}
+	return true
+}
+
+// nextWord returns the upper-cased next word after token i, skipping
+// comments, or "".
+func (f *sqlFormatter) nextWord(i int) string {
+	for j := i + 1; j < len(f.toks); j++ {
+		switch f.toks[j].kind {
+		case tokLineComment, tokBlockComment:
+			continue
+		}
+		return f.toks[j].upper()
+	}
+	return ""
+}
+
+// multiItemList reports whether the select list starting after token i has
+// more than one item.
+func (f *sqlFormatter) multiItemList(i int) bool {
+	depth := 0
+	for j := i + 1; j < len(f.toks); j++ {
+		t := f.toks[j]
+		switch t.kind {
+		case tokOpen:
+			depth++
+		case tokClose:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case tokSemicolon:
+			return false
+		case tokComma:
+			if depth == 0 {
+				return true
+			}
+		case tokWord:
+			if depth == 0 {
+				switch t.upper() {
+				case "FROM", "INTO", "UNION", "WHERE":
+					return false
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isSelectModifier(w string) bool {
+	switch w {
+	case "ALL", "DISTINCT", "DISTINCTROW", "HIGH_PRIORITY", "STRAIGHT_JOIN":
+		return true
+	}
+	return strings.HasPrefix(w, "SQL_")
+}
+
+func (f *sqlFormatter) format(i int) {
+	t := f.toks[i]
+	fr := f.top()
+
+	switch t.kind {
+	case tokLineComment:
+		f.write(t, t.text)
+		f.newline(f.indent)
+		return
+	case tokBlockComment, tokQuoted, tokDot:
+		f.beginItem(fr)
+		f.write(t, t.text)
+		return
+	case tokSemicolon:
+		// A blank line between statements.
+		f.write(t, t.text)
+		f.out = append(f.out, '\n')
+		f.newline(0)
+		f.reset()
+		return
+	case tokComma:
+		f.write(t, t.text)
+		if fr.breaks && fr.listBreak {
+			f.newline(fr.indent + 1)
+		}
+		return
+	case tokOpen:
+		f.beginItem(fr)
+		f.write(t, t.text)
+		sub := f.nextWord(i) == "SELECT" || f.nextWord(i) == "WITH"
+		next := fmtFrame{indent: fr.indent, closeIndent: f.indent, breaks: sub}
+		if sub {
+			next.indent = f.indent + 1
+		}
+		f.frames = append(f.frames, next)
+		if sub {
+			f.newline(next.indent)
+		}
+		return
+	case tokClose:
+		if len(f.frames) > 1 {
+			closed := *fr
+			f.frames = f.frames[:len(f.frames)-1]
+			if closed.breaks {
+				f.newline(closed.closeIndent)
+			}
+		}
+		f.write(t, t.text)
+		return
+	case tokOp:
+		f.beginItem(fr)
+		unary := (t.text == "-" || t.text == "+" || t.text == "~") &&
+			(f.prev == nil || f.prev.kind == tokOp || f.prev.kind == tokOpen ||
+				f.prev.kind == tokComma || formatReserved[f.prev.upper()])
+		f.write(t, t.text)
+		f.unary = unary
+		return
+	}
+
+	// Words.
+	w := t.upper()
+	text := t.text
+	if formatReserved[w] && (f.prev == nil || f.prev.kind != tokDot) {
+		text = w
+	}
+	if !fr.breaks || (f.prev != nil && f.prev.kind == tokDot) {
+		f.write(t, text)
+		return
+	}
+
+	prevWord, prevOp := "", false
+	if f.prev != nil {
+		prevWord, prevOp = f.prev.upper(), f.prev.kind == tokOp
+	}
+	switch {
+	case clauseWords[w] && prevWord != "CHARACTER" && !(w == "VALUES" && prevOp) && !(w == "FROM" && fr.clause == ""),
+		(w == "GROUP" || w == "ORDER") && f.nextWord(i) == "BY",
+		joinWords[w] && !joinWords[prevWord] && (w == "JOIN" || w == "STRAIGHT_JOIN" || strings.HasSuffix(f.joinAhead(i), "JOIN")):
+		if fr.started {
+			f.newline(fr.indent)
+		}
+		fr.clause = w
+		if joinWords[w] {
+			fr.clause = "JOIN"
+		}
+		fr.between = false
+		fr.listBreak = w == "SELECT" && f.multiItemList(i)
+		fr.pendingList = fr.listBreak
+		fr.started = true
+		f.write(t, text)
+		return
+	case w == "BETWEEN":
+		fr.between = true
+	case (w == "AND" || w == "OR" || w == "XOR") && (fr.clause == "WHERE" || fr.clause == "HAVING" || fr.clause == "JOIN"):
+		if w == "AND" && fr.between {
+			fr.between = false
+			break
+		}
+		f.newline(fr.indent + 1)
+		f.write(t, text)
+		return
+	case fr.pendingList && isSelectModifier(w):
+		f.write(t, text)
+		return
+	}
+	f.beginItem(fr)
+	f.write(t, text)
+}
+
+// beginItem marks the frame started and, right after SELECT, moves a
+// broken select list's first item to its own line.
+func (f *sqlFormatter) beginItem(fr *fmtFrame) {
+	fr.started = true
+	if fr.pendingList {
+		fr.pendingList = false
+		f.newline(fr.indent + 1)
+	}
+}
+
+// joinAhead returns the words of a join keyword run starting at token i,
+// e.g. "LEFT OUTER JOIN", so LEFT(...) the function isn't mistaken for one.
+func (f *sqlFormatter) joinAhead(i int) string {
+	var words []string
+	for j := i; j < len(f.toks) && f.toks[j].kind == tokWord && joinWords[f.toks[j].upper()]; j++ {
+		words = append(words, f.toks[j].upper())
+	}
+	return strings.Join(words, " ")
+}
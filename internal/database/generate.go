@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// GenerateStatement returns a ready-to-edit statement for a table. kind is
+// "select", "insert", or "update". INSERT and UPDATE fill in placeholder
+// values by column type; UPDATE matches rows on the primary key, or on
+// every column with LIMIT 1 when the table has none.
+func GenerateStatement(db *sql.DB, database, table, kind string) (string, error) {
+	cols, err := listColumns(db, database, table)
+	if err != nil {
+		return "", err
+	}
+	if len(cols) == 0 {
+		return "", fmt.Errorf("table %s.%s not found", database, table)
+	}
+	name := qualifiedName(database, table)
+
+	switch strings.ToLower(kind) {
+	case "select":
+		names := make([]string, len(cols))
+		for i, col := range cols {
+			names[i] = quoteIdent(col.Name)
+		}
+		return fmt.Sprintf("SELECT\n  %s\nFROM %s\nLIMIT 100;", strings.Join(names, ",\n  "), name), nil
+
+	case "insert":
+		var names, values []string
+		for _, col := range cols {
+			if isAutoIncrement(col) || isGenerated(col) {
+				continue
+			}
+			names = append(names, quoteIdent(col.Name))
+			values = append(values, placeholderValue(col))
+		}
+		return fmt.Sprintf("INSERT INTO %s\n  (%s)\nVALUES\n  (%s);", name, strings.Join(names, ", "), strings.Join(values, ", ")), nil
+
+	case "update":
+		var sets, where []string
+		hasKey := false
+		for _, col := range cols {
+			if col.Key == "PRI" {
+				hasKey = true
+			}
+		}
+		for _, col := range cols {
+			cond := quoteIdent(col.Name) + " = " + placeholderValue(col)
+			if !hasKey || col.Key == "PRI" {
+				where = append(where, cond)
+			}
+			if col.Key != "PRI" && !isGenerated(col) {
+				sets = append(sets, cond)
+			}
+		}
+		stmt := fmt.Sprintf("UPDATE %s\nSET\n  %s\nWHERE %s", name, strings.Join(sets, ",\n  "), strings.Join(where, "\n  AND "))
+		if !hasKey {
+			stmt += "\nLIMIT 1"
+		}
+		return stmt + ";", nil
+	}
+	return "", fmt.Errorf("unknown statement kind: %s", kind)
+}
+
+func isAutoIncrement(col ColumnInfo) bool {
+	return strings.Contains(strings.ToLower(col.Extra), "auto_increment")
+}
+
+// isGenerated reports a VIRTUAL or STORED generated column, which can't be
+// assigned. DEFAULT_GENERATED only marks an expression default.
+func isGenerated(col ColumnInfo) bool {
+	extra := strings.ToUpper(col.Extra)
+	return strings.Contains(strings.ReplaceAll(extra, "DEFAULT_GENERATED", ""), "GENERATED")
+}
+
+// placeholderValue is an editable stand-in value for col in a generated
+// statement.
+func placeholderValue(col ColumnInfo) string {
+	switch kindOf(col.DataType) {
+	case KindNumber:
+		return "0"
+	case KindDatetime:
+		if strings.EqualFold(col.DataType, "DATE") {
+			return "CURDATE()"
+		}
+		return "NOW()"
+	case KindJSON:
+		return "'{}'"
+	}
+	return "''"
+}
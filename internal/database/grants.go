@@ -0,0 +1,151 @@
+package database
+
+import "strings"
+
+// Grant is one parsed line of SHOW GRANTS output.
+type Grant struct {
+	Raw string `json:"raw"`
+
+	// Privileges lists the granted privileges as written, including any
+	// column list, e.g. "SELECT" or "UPDATE (`a`, `b`)". Empty for role
+	// grants.
+	Privileges []string `json:"privileges"`
+
+	// ObjectType is "PROCEDURE" or "FUNCTION" for routine grants, otherwise
+	// empty.
+	ObjectType string `json:"objectType"`
+	On         string `json:"on"`       // the target as written, e.g. *.* or `db`.`t`
+	Database   string `json:"database"` // unquoted; "*" means all databases
+	Table      string `json:"table"`    // unquoted; "*" means all tables
+
+	// GrantOption is set for WITH GRANT OPTION, or WITH ADMIN OPTION on a
+	// role grant.
+	GrantOption bool `json:"grantOption"`
+
+	// IsRole marks a grant of roles rather than privileges; Roles holds the
+	// granted role accounts as written.
+	IsRole bool     `json:"isRole"`
+	Roles  []string `json:"roles,omitempty"`
+}
+
+// ParseGrant parses a line of SHOW GRANTS output. Lines it doesn't
+// recognize come back with only Raw set.
+func ParseGrant(raw string) Grant {
+	g := Grant{Raw: raw}
+	s := strings.TrimSpace(raw)
+	if len(s) < 6 || !strings.EqualFold(s[:6], "GRANT ") {
+		return g
+	}
+	s = s[6:]
+
+	to := topLevelWordAt(s, "TO")
+	if to < 0 {
+		return g
+	}
+	on := topLevelWordAt(s[:to], "ON")
+	rest := s[to+2:]
+
+	words := topLevelWords(rest)
+	for i := 0; i+2 < len(words); i++ {
+		if words[i] == "WITH" && (words[i+1] == "GRANT" || words[i+1] == "ADMIN") && words[i+2] == "OPTION" {
+			g.GrantOption = true
+		}
+	}
+
+	if on < 0 {
+		g.IsRole = true
+		g.Roles = splitTopLevel(s[:to], ',')
+		return g
+	}
+
+	g.Privileges = splitTopLevel(s[:on], ',')
+	target := strings.TrimSpace(s[on+2 : to])
+	for _, kind := range []string{"PROCEDURE", "FUNCTION", "TABLE"} {
+		if len(target) > len(kind) && strings.EqualFold(target[:len(kind)], kind) && target[len(kind)] == ' ' {
+			if kind != "TABLE" {
+				g.ObjectType = kind
+			}
+			target = strings.TrimSpace(target[len(kind):])
+			break
+		}
+	}
+	g.On = target
+
+	// PROXY grants target an account rather than a database object.
+	if len(g.Privileges) == 1 && strings.EqualFold(g.Privileges[0], "PROXY") {
+		return g
+	}
+	parts := splitTopLevel(target, '.')
+	switch len(parts) {
+	case 1:
+		g.Table = unquoteIdent(parts[0])
+	case 2:
+		g.Database = unquoteIdent(parts[0])
+		g.Table = unquoteIdent(parts[1])
+	}
+	return g
+}
+
+// topLevelWordAt returns the offset of the first occurrence of the keyword
+// word in s outside quotes and parentheses, or -1.
+func topLevelWordAt(s, word string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(s, i)
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case isWordStart(c):
+			j := i
+			for j < len(s) && isWordChar(s[j]) {
+				j++
+			}
+			if depth == 0 && strings.EqualFold(s[i:j], word) {
+				return i
+			}
+			i = j - 1
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s at sep outside quotes and parentheses, trimming
+// each part.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(s, i)
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// unquoteIdent strips backtick or quote characters from an identifier.
+func unquoteIdent(s string) string {
+	if len(s) >= 2 {
+		if q := s[0]; (q == '`' || q == '\'' || q == '"') && s[len(s)-1] == q {
+			return strings.ReplaceAll(s[1:len(s)-1], string(q)+string(q), string(q))
+		}
+	}
+	return s
+}
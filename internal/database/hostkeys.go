@@ -0,0 +1,189 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHHostKeyStore holds the SSH host keys accepted in the app, which are
+// trusted alongside those in ~/.ssh/known_hosts.
+type SSHHostKeyStore interface {
+	// SSHHostKeys returns the accepted keys of host, in authorized_keys
+	// format. host is written as in known_hosts: the name alone for port
+	// 22, otherwise "[name]:port".
+	SSHHostKeys(host string) ([]string, error)
+}
+
+// SSHHostKeyError reports an SSH host key that isn't trusted yet, or that
+// differs from the one on record. Key is what to save to accept it.
+type SSHHostKeyError struct {
+	Host        string `json:"host"` // as in known_hosts
+	KeyType     string `json:"keyType"`
+	Fingerprint string `json:"fingerprint"` // SHA256:...
+	Key         string `json:"key"`         // authorized_keys format
+	Changed     bool   `json:"changed"`
+}
+
+func (e *SSHHostKeyError) Error() string {
+	if e.Changed {
+		return fmt.Sprintf("SSH host key for %s has changed: the server sent %s key %s, which does not match the key on record. "+
+			"Someone may be intercepting the connection; if the server's key was replaced on purpose, remove the old key and connect again.",
+			e.Host, e.KeyType, e.Fingerprint)
+	}
+	return fmt.Sprintf("SSH host %s is not known yet: it sent %s key %s. Check the fingerprint and accept the key to connect.",
+		e.Host, e.KeyType, e.Fingerprint)
+}
+
+// SSHKeyType checks a public key in authorized_keys format, as in
+// SSHHostKeyError.Key, and returns its type.
+func SSHKeyType(key string) (string, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("invalid SSH public key: %w", err)
+	}
+	return pub.Type(), nil
+}
+
+// hostKeyVerifier checks the key an SSH host presents against
+// ~/.ssh/known_hosts and the keys accepted in the app.
+type hostKeyVerifier struct {
+	host       string              // as in known_hosts
+	knownHosts ssh.HostKeyCallback // nil without a known_hosts file
+	accepted   []ssh.PublicKey
+	knownTypes []string // key types on record for host, from either source
+}
+
+// newHostKeyVerifier loads what is known about the SSH host at addr.
+func newHostKeyVerifier(cfg ConnConfig, addr string) (*hostKeyVerifier, error) {
+	v := &hostKeyVerifier{host: knownhosts.Normalize(addr)}
+
+	path := knownHostsPath()
+	if path != "" {
+		cb, err := knownhosts.New(path)
+		switch {
+		case err == nil:
+			v.knownHosts = cb
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	if cfg.SSHHostKeys != nil {
+		keys, err := cfg.SSHHostKeys.SSHHostKeys(v.host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load accepted SSH host keys: %w", err)
+		}
+		for _, k := range keys {
+			if pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k)); err == nil {
+				v.accepted = append(v.accepted, pub)
+				v.knownTypes = append(v.knownTypes, pub.Type())
+			}
+		}
+	}
+
+	// known_hosts has no lookup, but checking a key that can't match
+	// reports the keys it holds for the host.
+	if v.knownHosts != nil {
+		var keyErr *knownhosts.KeyError
+		if err := v.knownHosts(addr, &net.TCPAddr{}, probeKey{}); errors.As(err, &keyErr) {
+			for _, k := range keyErr.Want {
+				v.knownTypes = append(v.knownTypes, k.Key.Type())
+			}
+		}
+	}
+	return v, nil
+}
+
+// knownHostsPath returns the user's known_hosts file, or "" when there is
+// no home directory.
+func knownHostsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// algorithms returns the host key algorithms to offer: those for the key
+// types on record first, so a server with several keys presents one that
+// can be checked, then the usual defaults. It is nil, leaving the
+// defaults alone, when nothing is known about the host.
+func (v *hostKeyVerifier) algorithms() []string {
+	if len(v.knownTypes) == 0 {
+		return nil
+	}
+	var algos []string
+	seen := make(map[string]bool)
+	add := func(a string) {
+		if !seen[a] {
+			seen[a] = true
+			algos = append(algos, a)
+		}
+	}
+	for _, t := range v.knownTypes {
+		for _, a := range hostKeyAlgorithms(t) {
+			add(a)
+		}
+	}
+	for _, a := range ssh.SupportedAlgorithms().HostKeys {
+		add(a)
+	}
+	return algos
+}
+
+// hostKeyAlgorithms lists the signature algorithms a key type can be
+// presented with. RSA keys sign with SHA-2; SHA-1 ssh-rsa stays off, as
+// it is by default.
+func hostKeyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256}
+	}
+	return []string{keyType}
+}
+
+// check is the ssh.HostKeyCallback. A key that is neither in known_hosts
+// nor accepted fails with an SSHHostKeyError, marked Changed when other
+// keys are on record for the host.
+func (v *hostKeyVerifier) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	for _, k := range v.accepted {
+		if bytes.Equal(k.Marshal(), key.Marshal()) {
+			return nil
+		}
+	}
+	if v.knownHosts != nil {
+		var revoked *knownhosts.RevokedError
+		var keyErr *knownhosts.KeyError
+		err := v.knownHosts(hostname, remote, key)
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &revoked):
+			return fmt.Errorf("SSH host key for %s (%s) is marked revoked in %s", v.host, ssh.FingerprintSHA256(key), knownHostsPath())
+		case !errors.As(err, &keyErr):
+			return err
+		}
+	}
+	return &SSHHostKeyError{
+		Host:        v.host,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Key:         strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Changed:     len(v.knownTypes) > 0,
+	}
+}
+
+// probeKey is a public key no host has, for listing the known_hosts keys
+// of a host.
+type probeKey struct{}
+
+func (probeKey) Type() string                        { return "probe" }
+func (probeKey) Marshal() []byte                     { return []byte("probe") }
+func (probeKey) Verify([]byte, *ssh.Signature) error { return errors.New("probe key") }
@@ -5,29 +5,38 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 // CSVPreview holds the header and first few rows of a CSV file for column mapping.
 type CSVPreview struct {
 	Headers    []string   `json:"headers"`
+	Types      []string   `json:"types"` // inferred type per column, one of the CSVType constants
 	SampleRows [][]string `json:"sampleRows"`
 	TotalRows  int        `json:"totalRows"`
 }
 
 // PreviewCSV reads a CSV file and returns its headers and first N sample rows.
-func PreviewCSV(filePath string, sampleSize int) (*CSVPreview, error) {
+// Without a header row the columns are named "Column 1", "Column 2", ...
+// Each column's type is inferred from the sample rows; NULL fields are
+// ignored, and anything ambiguous is a string.
+func PreviewCSV(filePath string, sampleSize int, opts CSVOptions) (*CSVPreview, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	r := csv.NewReader(f)
-	r.LazyQuotes = true
+	r, err := opts.newReader(f)
+	if err != nil {
+		return nil, err
+	}
 
 	headers, err := r.Read()
 	if err != nil {
@@ -36,6 +45,14 @@ func PreviewCSV(filePath string, sampleSize int) (*CSVPreview, error) {
 
 	var samples [][]string
 	total := 0
+	if !opts.HasHeader {
+		samples = append(samples, headers)
+		total = 1
+		headers = make([]string, len(headers))
+		for i := range headers {
+			headers[i] = fmt.Sprintf("Column %d", i+1)
+		}
+	}
 	for {
 		record, err := r.Read()
 		if err == io.EOF {
@@ -50,8 +67,14 @@ func PreviewCSV(filePath string, sampleSize int) (*CSVPreview, error) {
 		}
 	}
 
+	types := make([]string, len(headers))
+	for i, st := range inferCSVColumns(samples, len(headers), opts) {
+		types[i] = st.kind()
+	}
+
 	return &CSVPreview{
 		Headers:    headers,
+		Types:      types,
 		SampleRows: samples,
 		TotalRows:  total,
 	}, nil
@@ -63,54 +86,159 @@ type ColumnMapping struct {
 	ColumnName string `json:"columnName"`
 }
 
+// DefaultCSVBatchSize is the number of rows ImportCSV inserts per statement
+// when CSVImportOptions.BatchSize is unset.
+const DefaultCSVBatchSize = 500
+
+// maxPlaceholders is the most ? parameters MySQL accepts in one prepared
+// statement.
+const maxPlaceholders = 65535
+
+// Strategies for CSV rows that collide with an existing primary or unique
+// key, set in CSVImportOptions.OnDuplicate.
+const (
+	DuplicateFail   = "fail"   // abort the import (the default)
+	DuplicateIgnore = "ignore" // keep the existing row (INSERT IGNORE)
+	DuplicateUpdate = "update" // overwrite the mapped columns (ON DUPLICATE KEY UPDATE)
+)
+
+// DuplicateStrategies lists the accepted OnDuplicate values, default first.
+var DuplicateStrategies = []string{DuplicateFail, DuplicateIgnore, DuplicateUpdate}
+
+// CSVImportOptions controls how ImportCSV reads and writes rows.
+type CSVImportOptions struct {
+	CSVOptions
+
+	BatchSize   int    `json:"batchSize"`   // rows per INSERT; 0 means DefaultCSVBatchSize
+	OnDuplicate string `json:"onDuplicate"` // one of DuplicateStrategies; "" means DuplicateFail
+
+	// ContinueOnError skips rows that fail to parse or insert and records
+	// them in the summary instead of aborting the import.
+	ContinueOnError bool `json:"continueOnError"`
+}
+
+// maxImportErrors caps the row errors kept in a CSVImportSummary.
+const maxImportErrors = 1000
+
+// CSVRowError describes a CSV row skipped by a continue-on-error import.
+// Row counts data rows from 1, not including the header.
+type CSVRowError struct {
+	Row   int64  `json:"row"`
+	Error string `json:"error"`
+}
+
+// CSVImportSummary reports the outcome of ImportCSV.
+type CSVImportSummary struct {
+	Imported  int64         `json:"imported"`
+	Skipped   int64         `json:"skipped"`
+	Errors    []CSVRowError `json:"errors"`    // at most maxImportErrors entries
+	Truncated bool          `json:"truncated"` // more rows failed than Errors holds
+}
+
+func (s *CSVImportSummary) skip(row int64, err error) {
+	s.Skipped++
+	if len(s.Errors) < maxImportErrors {
+		s.Errors = append(s.Errors, CSVRowError{Row: row, Error: err.Error()})
+	} else {
+		s.Truncated = true
+	}
+}
+
 // ImportCSV imports a CSV file into a table using the given column mappings.
-func ImportCSV(ctx context.Context, db *sql.DB, dbName, tableName, filePath string, mappings []ColumnMapping, progress ProgressFunc) (int64, error) {
+// Rows are sent as multi-row INSERTs, one transaction per batch. With
+// ContinueOnError a failed batch is retried row by row to find and skip
+// the bad rows.
+func ImportCSV(ctx context.Context, db *sql.DB, dbName, tableName, filePath string, mappings []ColumnMapping, opts CSVImportOptions, progress ProgressFunc) (*CSVImportSummary, error) {
+	summary := &CSVImportSummary{}
+	if len(mappings) == 0 {
+		return summary, fmt.Errorf("no columns mapped")
+	}
+
 	f, err := os.Open(filePath)
 	if err != nil {
-		return 0, err
+		return summary, err
 	}
 	defer f.Close()
 
-	r := csv.NewReader(f)
-	r.LazyQuotes = true
+	r, err := opts.newReader(f)
+	if err != nil {
+		return summary, err
+	}
 
-	// Skip header row.
-	if _, err := r.Read(); err != nil {
-		return 0, fmt.Errorf("failed to read CSV headers: %w", err)
+	if opts.HasHeader {
+		if _, err := r.Read(); err != nil {
+			return summary, fmt.Errorf("failed to read CSV headers: %w", err)
+		}
 	}
 
-	// Build INSERT template.
-	colNames := make([]string, len(mappings))
-	placeholders := make([]string, len(mappings))
+	batchSize := importBatchSize(opts.BatchSize, len(mappings))
+	columns := make([]string, len(mappings))
 	for i, m := range mappings {
-		colNames[i] = "`" + m.ColumnName + "`"
-		placeholders[i] = "?"
+		columns[i] = m.ColumnName
+	}
+	insertPrefix, rowPlaceholder, suffix, err := insertStatement(dbName, tableName, columns, opts.OnDuplicate)
+	if err != nil {
+		return summary, err
+	}
+
+	var rowNum int64 // data rows read so far
+	batch := make([][]interface{}, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		first := rowNum - int64(len(batch)) + 1
+		err := insertBatch(ctx, db, insertPrefix, rowPlaceholder, suffix, batch)
+		switch {
+		case err == nil:
+			summary.Imported += int64(len(batch))
+		case !opts.ContinueOnError || ctx.Err() != nil:
+			return fmt.Errorf("insert error in rows %d-%d: %w", first, rowNum, err)
+		default:
+			for i, row := range batch {
+				if err := insertBatch(ctx, db, insertPrefix, rowPlaceholder, suffix, [][]interface{}{row}); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					summary.skip(first+int64(i), err)
+				} else {
+					summary.Imported++
+				}
+			}
+		}
+		batch = batch[:0]
+
+		if progress != nil && !progress(summary.Imported, -1) {
+			return fmt.Errorf("cancelled")
+		}
+		return nil
 	}
-	insertSQL := fmt.Sprintf("INSERT INTO `%s`.`%s` (%s) VALUES (%s)",
-		dbName, tableName,
-		strings.Join(colNames, ", "),
-		strings.Join(placeholders, ", "),
-	)
 
-	var imported int64
 	for {
 		if ctx.Err() != nil {
-			return imported, ctx.Err()
+			return summary, ctx.Err()
 		}
 
 		record, err := r.Read()
 		if err == io.EOF {
 			break
 		}
+		rowNum++
 		if err != nil {
-			return imported, fmt.Errorf("CSV read error at row %d: %w", imported+1, err)
+			var parseErr *csv.ParseError
+			if opts.ContinueOnError && errors.As(err, &parseErr) {
+				summary.skip(rowNum, err)
+				continue
+			}
+			return summary, fmt.Errorf("CSV read error at row %d: %w", rowNum, err)
 		}
 
 		vals := make([]interface{}, len(mappings))
 		for i, m := range mappings {
 			if m.CSVIndex < len(record) {
 				v := record[m.CSVIndex]
-				if v == "" || strings.EqualFold(v, "NULL") {
+				if opts.isNull(v) {
 					vals[i] = nil
 				} else {
 					vals[i] = v
@@ -120,118 +248,399 @@ func ImportCSV(ctx context.Context, db *sql.DB, dbName, tableName, filePath stri
 			}
 		}
 
-		if _, err := db.ExecContext(ctx, insertSQL, vals...); err != nil {
-			return imported, fmt.Errorf("insert error at row %d: %w", imported+1, err)
-		}
-		imported++
-
-		if progress != nil && imported%500 == 0 {
-			if !progress(imported, -1) {
-				return imported, fmt.Errorf("cancelled")
+		batch = append(batch, vals)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return summary, err
 			}
 		}
 	}
 
+	if err := flush(); err != nil {
+		return summary, err
+	}
+
 	if progress != nil {
-		progress(imported, imported)
+		progress(summary.Imported, summary.Imported)
 	}
 
-	return imported, nil
+	return summary, nil
 }
 
-// ImportSQLFile executes a SQL file against the database.
-// It splits on semicolons and executes each statement.
-func ImportSQLFile(ctx context.Context, db *sql.DB, filePath string, progress ProgressFunc) (int64, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return 0, err
+// importBatchSize returns the rows per INSERT for a requested batch size,
+// kept within the server's placeholder limit.
+func importBatchSize(batchSize, columns int) int {
+	if batchSize <= 0 {
+		batchSize = DefaultCSVBatchSize
 	}
-	defer f.Close()
+	if batchSize*columns > maxPlaceholders {
+		batchSize = maxPlaceholders / columns
+	}
+	return batchSize
+}
 
-	// Read statements separated by semicolons, handling quoted strings.
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0), 10*1024*1024) // 10MB max line
+// insertStatement builds the pieces of a multi-row INSERT into columns for
+// insertBatch, applying an OnDuplicate strategy.
+func insertStatement(dbName, tableName string, columns []string, onDuplicate string) (insertPrefix, rowPlaceholder, suffix string, err error) {
+	colNames := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, name := range columns {
+		colNames[i] = quoteIdent(name)
+		placeholders[i] = "?"
+		updates[i] = fmt.Sprintf("%s = VALUES(%s)", colNames[i], colNames[i])
+	}
 
-	var buf strings.Builder
-	var executed int64
-	inQuote := false
-	quoteChar := byte(0)
+	verb := "INSERT"
+	switch onDuplicate {
+	case "", DuplicateFail:
+	case DuplicateIgnore:
+		verb = "INSERT IGNORE"
+	case DuplicateUpdate:
+		suffix = " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	default:
+		return "", "", "", fmt.Errorf("unknown duplicate key strategy: %s", onDuplicate)
+	}
 
-	for scanner.Scan() {
-		if ctx.Err() != nil {
-			return executed, ctx.Err()
+	insertPrefix = fmt.Sprintf("%s INTO %s (%s) VALUES ",
+		verb, qualifiedName(dbName, tableName),
+		strings.Join(colNames, ", "),
+	)
+	rowPlaceholder = "(" + strings.Join(placeholders, ", ") + ")"
+	return insertPrefix, rowPlaceholder, suffix, nil
+}
+
+// insertBatch inserts rows with a single extended INSERT inside its own
+// transaction. suffix is appended after the VALUES list.
+func insertBatch(ctx context.Context, db *sql.DB, insertPrefix, rowPlaceholder, suffix string, rows [][]interface{}) error {
+	var q strings.Builder
+	q.WriteString(insertPrefix)
+	args := make([]interface{}, 0, len(rows)*len(rows[0]))
+	for i, row := range rows {
+		if i > 0 {
+			q.WriteString(", ")
 		}
+		q.WriteString(rowPlaceholder)
+		args = append(args, row...)
+	}
+	q.WriteString(suffix)
 
-		line := scanner.Text()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, q.String(), args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// SQLImportOptions controls how ImportSQLFile runs a file.
+type SQLImportOptions struct {
+	// SingleTransaction runs the whole file in one transaction and rolls
+	// it back if a statement fails. MySQL commits implicitly around DDL
+	// and similar statements, so everything up to the last of those stays
+	// applied; the summary warns when that happens.
+	SingleTransaction bool `json:"singleTransaction"`
+
+	// Terminator is the statement delimiter the file starts with; "" means
+	// ";". DELIMITER directives in the file still change it.
+	Terminator string `json:"terminator"`
+
+	// BatchSeparator, e.g. "GO", ends the current statement when it stands
+	// alone on a line, in any case, as in dumps from tools that don't use
+	// DELIMITER. Terminators inside a batch still split it.
+	BatchSeparator string `json:"batchSeparator"`
+}
 
-		// Skip comment-only lines (outside of quotes).
-		trimmed := strings.TrimSpace(line)
-		if !inQuote && (strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "#")) {
-			continue
+// splitter returns a statement splitter configured by the options.
+func (o SQLImportOptions) splitter() (*stmtSplitter, error) {
+	s := newStmtSplitter()
+	if o.Terminator != "" {
+		if strings.ContainsAny(o.Terminator, " \t\r\n'\"`") {
+			return nil, fmt.Errorf("invalid statement terminator: %q", o.Terminator)
 		}
+		s.delim = o.Terminator
+	}
+	s.batch = strings.TrimSpace(o.BatchSeparator)
+	return s, nil
+}
 
-		for i := 0; i < len(line); i++ {
-			ch := line[i]
+// SQLImportSummary reports the outcome of ImportSQLFile.
+type SQLImportSummary struct {
+	Statements      int64    `json:"statements"`                // statements that ran successfully
+	FailedStatement string   `json:"failedStatement,omitempty"` // the statement that stopped the import
+	RolledBack      bool     `json:"rolledBack"`
+	Committed       int64    `json:"committed"` // statements up to the last implicit commit, which a rollback can't undo
+	Warnings        []string `json:"warnings,omitempty"`
+}
 
-			if inQuote {
-				buf.WriteByte(ch)
-				if ch == '\\' && i+1 < len(line) {
-					i++
-					buf.WriteByte(line[i])
-					continue
-				}
-				if ch == quoteChar {
-					inQuote = false
-				}
-				continue
-			}
+// ImportSQLFile executes a SQL file against the database.
+// It splits on semicolons, or the delimiter set by DELIMITER directives,
+// and executes each statement. Without SingleTransaction each statement
+// commits on its own, as in the mysql client.
+func ImportSQLFile(ctx context.Context, db *sql.DB, filePath string, opts SQLImportOptions, progress ProgressFunc) (*SQLImportSummary, error) {
+	summary := &SQLImportSummary{}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return summary, err
+	}
+	defer f.Close()
 
-			if ch == '\'' || ch == '"' || ch == '`' {
-				inQuote = true
-				quoteChar = ch
-				buf.WriteByte(ch)
-				continue
-			}
+	var q Querier = db
+	var conn *sql.Conn
+	if opts.SingleTransaction {
+		// The transaction is driven with plain statements on one connection
+		// so it can be reopened after an implicit commit, which database/sql
+		// transactions can't express.
+		if conn, err = db.Conn(ctx); err != nil {
+			return summary, err
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "START TRANSACTION"); err != nil {
+			return summary, err
+		}
+		q = conn
+	}
 
-			if ch == ';' {
-				stmt := strings.TrimSpace(buf.String())
-				buf.Reset()
-				if stmt == "" {
-					continue
-				}
-				if _, err := db.ExecContext(ctx, stmt); err != nil {
-					return executed, fmt.Errorf("error at statement %d: %w", executed+1, err)
-				}
-				executed++
-				if progress != nil && executed%100 == 0 {
-					if !progress(executed, -1) {
-						return executed, fmt.Errorf("cancelled")
-					}
+	var firstCommit string // first statement that committed the import's transaction
+	var commits int64
+	committedAt := func(n int64, stmt string) {
+		summary.Committed = n
+		commits++
+		if firstCommit == "" {
+			firstCommit = fmt.Sprintf("statement %d (%s)", n, abbreviate(stmt, 60))
+		}
+	}
+
+	run := func(stmt string) error {
+		if _, err := q.ExecContext(ctx, stmt); err != nil {
+			summary.FailedStatement = stmt
+			if conn != nil && causesImplicitCommit(stmt) {
+				// MySQL commits before running the statement, even one that fails.
+				committedAt(summary.Statements, stmt)
+			}
+			return fmt.Errorf("error at statement %d: %w", summary.Statements+1, err)
+		}
+		summary.Statements++
+		if conn != nil {
+			switch transactionEffect(stmt) {
+			case txBegin:
+				// The file's own BEGIN commits ours and opens a new one.
+				committedAt(summary.Statements, stmt)
+			case txEnd:
+				committedAt(summary.Statements, stmt)
+				if _, err := conn.ExecContext(ctx, "START TRANSACTION"); err != nil {
+					return err
 				}
-				continue
 			}
+		}
+		if progress != nil && summary.Statements%100 == 0 {
+			if !progress(summary.Statements, -1) {
+				return fmt.Errorf("cancelled")
+			}
+		}
+		return nil
+	}
 
-			buf.WriteByte(ch)
+	err = runSQLFile(ctx, f, opts, run)
+	if conn != nil {
+		commitDesc := firstCommit + " commits"
+		if commits > 1 {
+			commitDesc = fmt.Sprintf("%s and %d later statements commit", firstCommit, commits-1)
+		}
+		if err != nil {
+			// ctx may be what stopped the import, so roll back without it.
+			if _, rbErr := conn.ExecContext(context.Background(), "ROLLBACK"); rbErr != nil {
+				summary.Warnings = append(summary.Warnings, "rollback failed: "+rbErr.Error())
+			} else {
+				summary.RolledBack = true
+			}
+			if summary.Committed > 0 {
+				summary.Warnings = append(summary.Warnings, fmt.Sprintf(
+					"the rollback could not undo the first %d statements: %s implicitly",
+					summary.Committed, commitDesc))
+			}
+		} else {
+			if _, err = conn.ExecContext(ctx, "COMMIT"); err != nil {
+				return summary, err
+			}
+			if commits > 0 {
+				summary.Warnings = append(summary.Warnings, fmt.Sprintf(
+					"%s implicitly; a failure would only have rolled back the statements after the last commit",
+					commitDesc))
+			}
 		}
-		buf.WriteByte('\n')
 	}
+	if err != nil {
+		return summary, err
+	}
+
+	if progress != nil {
+		progress(summary.Statements, summary.Statements)
+	}
+	return summary, nil
+}
 
+// runSQLFile splits r into statements and passes each to run, stopping at
+// the first error.
+func runSQLFile(ctx context.Context, r io.Reader, opts SQLImportOptions, run func(stmt string) error) error {
+	splitter, err := opts.splitter()
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0), 10*1024*1024) // 10MB max line
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, stmt := range splitter.feedLine(scanner.Text()) {
+			if err := run(stmt); err != nil {
+				return err
+			}
+		}
+	}
 	if err := scanner.Err(); err != nil {
-		return executed, err
+		return err
 	}
 
-	// Execute any remaining statement without trailing semicolon.
-	remaining := strings.TrimSpace(buf.String())
-	if remaining != "" {
-		if _, err := db.ExecContext(ctx, remaining); err != nil {
-			return executed, fmt.Errorf("error at statement %d: %w", executed+1, err)
+	// Execute any remaining statement without a trailing delimiter.
+	if remaining := splitter.flush(); remaining != "" {
+		return run(remaining)
+	}
+	return nil
+}
+
+// abbreviate shortens s to at most n runes on one line for messages.
+func abbreviate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+// SQLFilePreview summarizes a SQL file without running it.
+type SQLFilePreview struct {
+	Statements int64            `json:"statements"`
+	Categories map[string]int64 `json:"categories"` // statement count by leading keyword
+	First      []string         `json:"first"`      // the first few statements
+}
+
+// PreviewSQLFile splits a SQL file the way ImportSQLFile does with opts and
+// counts its statements by leading keyword (CREATE, INSERT, ALTER, DROP,
+// ...), keeping the first sampleSize statements.
+func PreviewSQLFile(filePath string, sampleSize int, opts SQLImportOptions) (*SQLFilePreview, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	preview := &SQLFilePreview{Categories: map[string]int64{}, First: []string{}}
+	err = runSQLFile(context.Background(), f, opts, func(stmt string) error {
+		preview.Statements++
+		keyword := "OTHER"
+		if words := topLevelWords(stmt); len(words) > 0 {
+			keyword = words[0]
 		}
-		executed++
+		preview.Categories[keyword]++
+		if len(preview.First) < sampleSize {
+			preview.First = append(preview.First, stmt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return preview, nil
+}
 
-	if progress != nil {
-		progress(executed, executed)
+// SQLStatementIssue is a statement of a SQL file that the server rejected
+// during a dry run. Index counts statements from 1.
+type SQLStatementIssue struct {
+	Index int64  `json:"index"`
+	SQL   string `json:"sql"` // abbreviated
+	Error string `json:"error"`
+}
+
+// SQLDryRunResult reports the outcome of DryRunSQLFile.
+type SQLDryRunResult struct {
+	Checked   int64               `json:"checked"`
+	Unchecked int64               `json:"unchecked"` // statements the server couldn't check without running earlier ones
+	Issues    []SQLStatementIssue `json:"issues"`    // at most maxImportErrors entries
+	Truncated bool                `json:"truncated"`
+}
+
+// dryRunSkipErrors are server errors from preparing a statement that don't
+// mean the statement is wrong: it can't be prepared at all, or it refers to
+// objects that earlier statements in the file would create.
+var dryRunSkipErrors = map[uint16]bool{
+	1295: true, // ER_UNSUPPORTED_PS
+	1049: true, // ER_BAD_DB_ERROR
+	1146: true, // ER_NO_SUCH_TABLE
+	1054: true, // ER_BAD_FIELD_ERROR
+	1305: true, // ER_SP_DOES_NOT_EXIST
+}
+
+// DryRunSQLFile checks each statement of a SQL file by preparing it on the
+// server, which parses it without running it. Statements that can't be
+// prepared, or that depend on objects created earlier in the file, are
+// counted as unchecked rather than reported. Statements are split as
+// ImportSQLFile would with opts.
+func DryRunSQLFile(ctx context.Context, db *sql.DB, filePath string, opts SQLImportOptions, progress ProgressFunc) (*SQLDryRunResult, error) {
+	result := &SQLDryRunResult{Issues: []SQLStatementIssue{}}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return result, err
 	}
+	defer f.Close()
 
-	return executed, nil
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	var index int64
+	err = runSQLFile(ctx, f, opts, func(stmt string) error {
+		index++
+		ps, err := conn.PrepareContext(ctx, stmt)
+		if err == nil {
+			ps.Close()
+			result.Checked++
+		} else {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var myErr *mysql.MySQLError
+			if !errors.As(err, &myErr) {
+				return err
+			}
+			if dryRunSkipErrors[myErr.Number] {
+				result.Unchecked++
+			} else {
+				result.Checked++
+				if len(result.Issues) < maxImportErrors {
+					result.Issues = append(result.Issues, SQLStatementIssue{Index: index, SQL: abbreviate(stmt, 200), Error: err.Error()})
+				} else {
+					result.Truncated = true
+				}
+			}
+		}
+		if progress != nil && index%100 == 0 && !progress(index, -1) {
+			return fmt.Errorf("cancelled")
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	if progress != nil {
+		progress(index, index)
+	}
+	return result, nil
 }
@@ -0,0 +1,290 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// JSONPreview holds the keys and first few records of a JSON file for
+// column mapping, like CSVPreview. SampleRows line up with Keys; missing
+// keys and JSON nulls are nil, and nested objects and arrays are JSON text.
+type JSONPreview struct {
+	Keys       []string        `json:"keys"` // in order of first appearance
+	SampleRows [][]interface{} `json:"sampleRows"`
+	TotalRows  int             `json:"totalRows"`
+	Format     string          `json:"format"` // "array" or "lines"
+}
+
+// JSONMapping maps a top-level key of the JSON records to a column.
+type JSONMapping struct {
+	Key        string `json:"key"`
+	ColumnName string `json:"columnName"`
+}
+
+// jsonRecordReader reads objects from a JSON array or from a sequence of
+// objects, one per line (JSON Lines) or simply concatenated.
+type jsonRecordReader struct {
+	dec   *json.Decoder
+	array bool
+	n     int // records read so far
+}
+
+func newJSONRecordReader(r io.Reader) (*jsonRecordReader, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+
+	rr := &jsonRecordReader{dec: dec}
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return rr, nil
+			}
+			return nil, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.Discard(1)
+			continue
+		case '[':
+			rr.array = true
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+		}
+		return rr, nil
+	}
+}
+
+func (r *jsonRecordReader) format() string {
+	if r.array {
+		return "array"
+	}
+	return "lines"
+}
+
+// Read returns the next record's keys in document order and its values
+// keyed by name, or io.EOF after the last one.
+func (r *jsonRecordReader) Read() ([]string, map[string]json.RawMessage, error) {
+	if r.array && !r.dec.More() {
+		if _, err := r.dec.Token(); err != nil { // the closing ]
+			return nil, nil, err
+		}
+		return nil, nil, io.EOF
+	}
+	r.n++
+	tok, err := r.dec.Token()
+	if err != nil {
+		if err == io.EOF && !r.array {
+			return nil, nil, io.EOF
+		}
+		return nil, nil, fmt.Errorf("record %d: %w", r.n, err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, nil, fmt.Errorf("record %d is not an object", r.n)
+	}
+
+	var keys []string
+	values := make(map[string]json.RawMessage)
+	for r.dec.More() {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("record %d: %w", r.n, err)
+		}
+		key := tok.(string)
+		var raw json.RawMessage
+		if err := r.dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("record %d: %w", r.n, err)
+		}
+		if _, dup := values[key]; !dup {
+			keys = append(keys, key)
+		}
+		values[key] = raw
+	}
+	if _, err := r.dec.Token(); err != nil { // the closing }
+		return nil, nil, fmt.Errorf("record %d: %w", r.n, err)
+	}
+	return keys, values, nil
+}
+
+// jsonScalar converts a raw JSON value for display: strings, numbers and
+// booleans as themselves, null as nil, objects and arrays as compact JSON
+// text.
+func jsonScalar(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	switch raw[0] {
+	case 'n':
+		return nil
+	case '{', '[':
+		var b bytes.Buffer
+		if err := json.Compact(&b, raw); err != nil {
+			return string(raw)
+		}
+		return b.String()
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return string(raw)
+	}
+	return v
+}
+
+// jsonArg converts a raw JSON value to an INSERT argument. Booleans become
+// 1 and 0, since MySQL won't read true/false strings as numbers.
+func jsonArg(raw json.RawMessage) interface{} {
+	switch v := jsonScalar(raw).(type) {
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return v
+	}
+}
+
+// PreviewJSON reads a JSON file holding an array of objects, or one object
+// per line, and returns the keys and first sampleSize records.
+func PreviewJSON(filePath string, sampleSize int) (*JSONPreview, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := newJSONRecordReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	preview := &JSONPreview{Keys: []string{}, Format: r.format()}
+	seen := make(map[string]bool)
+	var samples []map[string]json.RawMessage
+	for {
+		keys, values, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		preview.TotalRows++
+		if len(samples) < sampleSize {
+			samples = append(samples, values)
+			for _, k := range keys {
+				if !seen[k] {
+					seen[k] = true
+					preview.Keys = append(preview.Keys, k)
+				}
+			}
+		}
+	}
+
+	preview.SampleRows = make([][]interface{}, len(samples))
+	for i, values := range samples {
+		row := make([]interface{}, len(preview.Keys))
+		for j, k := range preview.Keys {
+			row[j] = jsonScalar(values[k])
+		}
+		preview.SampleRows[i] = row
+	}
+	return preview, nil
+}
+
+// ImportJSON imports the records of a JSON file (see PreviewJSON) into a
+// table. Each mapping names the key feeding a column; missing keys and
+// JSON nulls are stored as NULL, and nested objects and arrays as JSON
+// text. Rows are inserted in batches of DefaultCSVBatchSize, one
+// transaction per batch, with progress reported as in ImportCSV.
+func ImportJSON(ctx context.Context, db *sql.DB, dbName, tableName, filePath string, mappings []JSONMapping, progress ProgressFunc) (int64, error) {
+	if len(mappings) == 0 {
+		return 0, fmt.Errorf("no columns mapped")
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r, err := newJSONRecordReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	columns := make([]string, len(mappings))
+	for i, m := range mappings {
+		columns[i] = m.ColumnName
+	}
+	insertPrefix, rowPlaceholder, suffix, err := insertStatement(dbName, tableName, columns, DuplicateFail)
+	if err != nil {
+		return 0, err
+	}
+	batchSize := importBatchSize(0, len(mappings))
+
+	var imported, rowNum int64
+	batch := make([][]interface{}, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := insertBatch(ctx, db, insertPrefix, rowPlaceholder, suffix, batch); err != nil {
+			return fmt.Errorf("insert error in records %d-%d: %w", rowNum-int64(len(batch))+1, rowNum, err)
+		}
+		imported += int64(len(batch))
+		batch = batch[:0]
+		if progress != nil && !progress(imported, -1) {
+			return fmt.Errorf("cancelled")
+		}
+		return nil
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return imported, ctx.Err()
+		}
+		_, values, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+		rowNum++
+
+		vals := make([]interface{}, len(mappings))
+		for i, m := range mappings {
+			vals[i] = jsonArg(values[m.Key])
+		}
+		batch = append(batch, vals)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return imported, err
+	}
+
+	if progress != nil {
+		progress(imported, imported)
+	}
+	return imported, nil
+}
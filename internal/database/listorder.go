@@ -0,0 +1,91 @@
+package database
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// The List* functions (ListDatabases, ListTables, ListRoutines,
+// ListTriggers, ListEvents, ListUsers) sort in Go rather than with ORDER
+// BY, so their order doesn't depend on the server's collation: names are
+// in Unicode code point order, as under a _bin collation, which is what a
+// plain string comparison on the client gives too. Within that, each
+// function documents what it groups by first. Sort* re-sorts a list in
+// another NameOrder.
+
+// NameOrder selects how names are compared when sorting a list.
+type NameOrder string
+
+const (
+	// OrderBinary compares names by code point, so "B" sorts before "a".
+	OrderBinary NameOrder = ""
+	// OrderNoCase ignores case and sorts accented letters next to their
+	// base letter, the way a person would alphabetize. Names that differ
+	// only in case keep code point order.
+	OrderNoCase NameOrder = "nocase"
+)
+
+// nameCompare returns a comparison function for names in order.
+func nameCompare(order NameOrder) func(a, b string) int {
+	if order != OrderNoCase {
+		return strings.Compare
+	}
+	// A Collator isn't safe for concurrent use, so each sort gets its own.
+	col := collate.New(language.Und, collate.IgnoreCase)
+	return func(a, b string) int {
+		if c := col.CompareString(a, b); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	}
+}
+
+// sortByKeys stably sorts items by the names keys returns, compared in
+// order: the first name groups, the next decides within a group, and so on.
+func sortByKeys[T any](items []T, order NameOrder, keys func(T) []string) {
+	cmp := nameCompare(order)
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := keys(items[i]), keys(items[j])
+		for k := range a {
+			if c := cmp(a[k], b[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// SortDatabases orders databases by name.
+func SortDatabases(dbs []DatabaseInfo, order NameOrder) {
+	sortByKeys(dbs, order, func(d DatabaseInfo) []string { return []string{d.Name} })
+}
+
+// SortTables orders tables by type (BASE TABLE, then SYSTEM VIEW, then
+// VIEW) and then by name.
+func SortTables(tables []TableInfo, order NameOrder) {
+	sortByKeys(tables, order, func(t TableInfo) []string { return []string{t.Type, t.Name} })
+}
+
+// SortRoutines orders routines by type (FUNCTION, then PROCEDURE) and then
+// by name.
+func SortRoutines(routines []RoutineInfo, order NameOrder) {
+	sortByKeys(routines, order, func(r RoutineInfo) []string { return []string{r.Type, r.Name} })
+}
+
+// SortTriggers orders triggers by their table and then by name.
+func SortTriggers(triggers []TriggerInfo, order NameOrder) {
+	sortByKeys(triggers, order, func(t TriggerInfo) []string { return []string{t.Table, t.Name} })
+}
+
+// SortEvents orders events by name.
+func SortEvents(events []EventInfo, order NameOrder) {
+	sortByKeys(events, order, func(e EventInfo) []string { return []string{e.Name} })
+}
+
+// SortUsers orders accounts by user name and then by host.
+func SortUsers(users []UserInfo, order NameOrder) {
+	sortByKeys(users, order, func(u UserInfo) []string { return []string{u.User, u.Host} })
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// MaintenanceResult is one status row from ANALYZE, OPTIMIZE, or CHECK
+// TABLE.
+type MaintenanceResult struct {
+	Table   string `json:"table"` // db.table
+	Op      string `json:"op"`
+	MsgType string `json:"msgType"` // status, info, note, warning, or error
+	MsgText string `json:"msgText"`
+}
+
+// AnalyzeTables refreshes index statistics for the given tables.
+func AnalyzeTables(db *sql.DB, database string, tables []string) ([]MaintenanceResult, error) {
+	return maintainTables(db, "ANALYZE", database, tables)
+}
+
+// OptimizeTables rebuilds the given tables to reclaim space. InnoDB
+// reports this as a recreate plus analyze.
+func OptimizeTables(db *sql.DB, database string, tables []string) ([]MaintenanceResult, error) {
+	return maintainTables(db, "OPTIMIZE", database, tables)
+}
+
+// CheckTables checks the given tables for errors.
+func CheckTables(db *sql.DB, database string, tables []string) ([]MaintenanceResult, error) {
+	return maintainTables(db, "CHECK", database, tables)
+}
+
+// maintainTables runs one maintenance statement over all the tables; the
+// server answers with at least one row per table.
+func maintainTables(db *sql.DB, op, database string, tables []string) ([]MaintenanceResult, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables given")
+	}
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = qualifiedName(database, t)
+	}
+
+	rows, err := db.Query(op + " TABLE " + strings.Join(names, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []MaintenanceResult
+	for rows.Next() {
+		var r MaintenanceResult
+		if err := rows.Scan(&r.Table, &r.Op, &r.MsgType, &r.MsgText); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -1,10 +1,20 @@
 package database
 
 import (
-	"crypto/tls"
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -12,26 +22,273 @@ import (
 
 // ConnConfig holds the parameters needed to open a MySQL connection.
 type ConnConfig struct {
-	Host      string
-	Port      int
-	Username  string
-	Password  string
-	Database  string
-	UseSSL    bool
+	Host string
+	Port int
+	// SocketPath, when set, connects over a Unix socket instead of Host:Port.
+	SocketPath string
+	Username   string
+	Password   string
+	Database   string
+	UseSSL     bool   // legacy flag, equivalent to SSLMode "require"
+	SSLMode    string // "disable", "require", "verify-ca", or "verify-full"
+	SSLCAPath  string // optional PEM bundle for the verify modes
+
+	// Client certificate for servers that require mutual TLS.
+	SSLCertPath string
+	SSLKeyPath  string
+
+	SSHEnabled bool
+	SSHHost    string
+	SSHPort    int
+	SSHUser    string
+	SSHAuth    string // "key" or "password"
+	SSHKeyPath string
+	SSHPass    string // password; also tried as the key passphrase when SSHPassphrase is ""
+
+	// SSHPassphrase unlocks an encrypted SSHKeyPath key.
+	SSHPassphrase string
+
+	// SSHHostKeys holds host keys accepted in the app, trusted in addition
+	// to ~/.ssh/known_hosts. Nil trusts known_hosts alone.
+	SSHHostKeys SSHHostKeyStore
+
+	// Charset and Collation are sent with SET NAMES when each connection
+	// opens. Charset defaults to utf8mb4; an empty Collation leaves the
+	// charset's default.
+	Charset   string
+	Collation string
+
+	// TimeZone pins the connection to a zone; see connectionZone. "" keeps
+	// the server's session zone.
+	TimeZone string
+
+	// InitialSQL runs on every new connection of the pool, after login,
+	// typically SET statements for sql_mode and the like. A failing
+	// statement fails the connection.
+	InitialSQL string
+
+	// ReadOnly limits ExecuteQuery to statements that only read; see
+	// ExecOptions.ReadOnly. Every connection of the pool also starts its
+	// transactions read-only, so the server refuses writes that slip past.
+	ReadOnly bool
+
+	// Pool tuning; zero values fall back to the defaults below.
+	MaxOpenConns           int
+	MaxIdleConns           int
+	ConnMaxLifetimeSeconds int
+
+	// ConnectTimeout bounds dialing the server and the SSH host; zero
+	// means defaultConnectTimeout.
+	ConnectTimeout time.Duration
+}
+
+// defaultPort is the MySQL port used when a profile leaves it unset.
+const defaultPort = 3306
+
+// normalize tidies the address fields of cfg as typed into a profile:
+// surrounding whitespace is trimmed, a mysql:// or tcp:// prefix and a port
+// written into the host are taken apart, and a zero port becomes 3306.
+// Addresses that still can't be dialed are reported in the profile's terms.
+func (cfg ConnConfig) normalize() (ConnConfig, error) {
+	cfg.Host = strings.TrimSpace(cfg.Host)
+	cfg.SocketPath = strings.TrimSpace(cfg.SocketPath)
+	cfg.SSHHost = strings.TrimSpace(cfg.SSHHost)
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return cfg, fmt.Errorf("port %d is out of range (1-65535)", cfg.Port)
+	}
+	if cfg.SocketPath != "" {
+		return cfg, nil
+	}
+
+	host := cfg.Host
+	if i := strings.Index(host, "://"); i >= 0 {
+		u, err := url.Parse(host)
+		switch {
+		case err != nil:
+			return cfg, fmt.Errorf("host %q is not a valid address", cfg.Host)
+		case u.Scheme != "mysql" && u.Scheme != "tcp":
+			return cfg, fmt.Errorf("host %q has an unsupported %s:// prefix; enter just the host name or address", cfg.Host, u.Scheme)
+		case u.User != nil || strings.Trim(u.Path, "/") != "" || u.RawQuery != "":
+			return cfg, fmt.Errorf("host %q is a connection URL; enter the user, password, and database in their own fields", cfg.Host)
+		}
+		host = u.Host
+	}
+
+	// A port typed into the host, as in "db.example.com:3307" or
+	// "[::1]:3307". A bare IPv6 address has too many colons to split.
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return cfg, fmt.Errorf("host %q has an invalid port", cfg.Host)
+		}
+		if cfg.Port != 0 && cfg.Port != port {
+			return cfg, fmt.Errorf("host %q includes port %d, but the port is set to %d", cfg.Host, port, cfg.Port)
+		}
+		host, cfg.Port = h, port
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	switch {
+	case host == "":
+		return cfg, fmt.Errorf("host is required")
+	case strings.ContainsAny(host, " \t/\\@"):
+		return cfg, fmt.Errorf("host %q is not a valid host name or address", cfg.Host)
+	}
+	cfg.Host = host
+	if cfg.Port == 0 {
+		cfg.Port = defaultPort
+	}
+	return cfg, nil
 }
 
+// defaultConnectTimeout is the dial timeout when ConnConfig doesn't set one.
+const defaultConnectTimeout = 10 * time.Second
+
+func (cfg ConnConfig) connectTimeout() time.Duration {
+	if cfg.ConnectTimeout > 0 {
+		return cfg.ConnectTimeout
+	}
+	return defaultConnectTimeout
+}
+
+// Default pool settings used when a profile doesn't override them.
+const (
+	defaultMaxOpenConns    = 5
+	defaultMaxIdleConns    = 2
+	defaultConnMaxLifetime = 5 * time.Minute
+)
+
+const (
+	// staleAfter is how long a tab may sit unused before Acquire checks
+	// that its connection is still alive.
+	staleAfter  = 30 * time.Second
+	pingTimeout = 5 * time.Second
+)
+
 // Connection wraps a live MySQL connection with metadata.
 type Connection struct {
-	ID       string // matches the tab ID
+	ID        string // matches the tab ID
 	ProfileID string
-	DB       *sql.DB
-	Config   ConnConfig
+	DB        *sql.DB
+	Config    ConnConfig
+
+	tunnel *sshTunnel
+	tlsKey string
+
+	// Connection holding an interactive transaction open; see Session.
+	sessMu     sync.Mutex
+	pinned     *sql.Conn
+	pinnedBusy bool
+
+	// Server metadata that rarely changes, fetched on first use.
+	cacheMu    sync.Mutex
+	privileges []PrivilegeInfo
+	serverInfo *ServerInfo
+
+	completions   *CompletionSchema
+	completionsAt time.Time
+
+	lastUsed atomic.Int64 // unix nanoseconds; see Manager.Acquire
+
+	// Default database, tracked across USE statements; see CurrentDatabase.
+	dbMu       sync.Mutex
+	currentDB  string
+	dbSwitched bool
+
+	// Outcome of the most recent ping, for ConnectionState.
+	pingMu  sync.Mutex
+	pingAt  time.Time
+	pingErr string
+}
+
+// ConnectionState describes a connected tab, so the UI can rebuild its view
+// of open connections after a reload.
+type ConnectionState struct {
+	TabID         string     `json:"tabId"`
+	ProfileID     string     `json:"profileId"`
+	Host          string     `json:"host"`
+	Port          int        `json:"port"`
+	Database      string     `json:"database"`
+	InTransaction bool       `json:"inTransaction"`
+	LastPing      *time.Time `json:"lastPing"` // nil until the first ping
+	Alive         bool       `json:"alive"`    // the last ping succeeded, or none has failed yet
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// State reports the connection's metadata and last ping outcome.
+func (c *Connection) State() ConnectionState {
+	st := ConnectionState{
+		TabID:         c.ID,
+		ProfileID:     c.ProfileID,
+		Host:          c.Config.Host,
+		Port:          c.Config.Port,
+		Database:      c.CurrentDatabase(),
+		InTransaction: c.InTransaction(),
+	}
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	if !c.pingAt.IsZero() {
+		at := c.pingAt
+		st.LastPing = &at
+	}
+	st.Alive = c.pingErr == ""
+	st.LastError = c.pingErr
+	return st
+}
+
+func (c *Connection) notePing(err error) {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	c.pingAt = time.Now()
+	c.pingErr = ""
+	if err != nil {
+		c.pingErr = err.Error()
+	}
+}
+
+func (c *Connection) touch() {
+	c.lastUsed.Store(time.Now().UnixNano())
+}
+
+func (c *Connection) idleFor() time.Duration {
+	return time.Since(time.Unix(0, c.lastUsed.Load()))
+}
+
+// ping checks the pool with a bounded wait, so a connection hung by a
+// laptop sleep fails fast instead of blocking the request.
+func (c *Connection) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return c.DB.PingContext(ctx)
+}
+
+// close releases the connection pool, its TLS registration, and any SSH
+// tunnel behind it.
+func (c *Connection) close() error {
+	c.releasePinned()
+	err := c.DB.Close()
+	if c.tlsKey != "" {
+		mysql.DeregisterTLSConfig(c.tlsKey)
+	}
+	c.tunnel.Close()
+	return err
 }
 
 // Manager tracks all active MySQL connections.
 type Manager struct {
 	mu    sync.RWMutex
 	conns map[string]*Connection // keyed by tab ID
+
+	// reconnectMu serializes reconnects so concurrent requests on a
+	// dropped tab rebuild it only once.
+	reconnectMu sync.Mutex
+
+	// OnEvent, when set, is told about connection changes the caller
+	// didn't ask for, such as a transparent "reconnected".
+	OnEvent func(tabID, event string, data interface{})
+
+	heartbeatMu   sync.Mutex
+	stopHeartbeat chan struct{}
 }
 
 // NewManager creates a connection manager.
@@ -41,43 +298,97 @@ func NewManager() *Manager {
 	}
 }
 
+// registrySeq makes the network names registered with the mysql driver
+// unique, so reconnecting a tab never clobbers a registration still in use.
+var registrySeq atomic.Uint64
+
 // Connect opens a MySQL connection for a given tab.
+// When cfg.SSHEnabled is set, the connection is routed through an SSH tunnel.
 func (m *Manager) Connect(tabID, profileID string, cfg ConnConfig) error {
-	dsn, err := buildDSN(cfg)
+	conn, err := openConnection(context.Background(), tabID, profileID, cfg)
 	if err != nil {
 		return err
 	}
 
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return fmt.Errorf("failed to open connection: %w", err)
-	}
-
-	db.SetMaxOpenConns(5)
-	db.SetMaxIdleConns(2)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return fmt.Errorf("failed to connect: %w", err)
-	}
-
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Close existing connection for this tab if any.
 	if old, ok := m.conns[tabID]; ok {
-		old.DB.Close()
+		old.close()
+	}
+
+	m.conns[tabID] = conn
+
+	return nil
+}
+
+// openConnection dials cfg and verifies the server answers before ctx is
+// done.
+func openConnection(ctx context.Context, tabID, profileID string, cfg ConnConfig) (*Connection, error) {
+	cfg, err := cfg.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var tunnel *sshTunnel
+	if cfg.SSHEnabled {
+		netName := fmt.Sprintf("ssh-%s-%d", tabID, registrySeq.Add(1))
+		t, err := openSSHTunnel(cfg, netName)
+		if err != nil {
+			return nil, &sshError{err}
+		}
+		tunnel = t
 	}
 
-	m.conns[tabID] = &Connection{
+	conn := &Connection{
 		ID:        tabID,
 		ProfileID: profileID,
-		DB:        db,
 		Config:    cfg,
+		tunnel:    tunnel,
+		currentDB: cfg.Database,
+	}
+	if cfg.sslMode() != SSLDisable {
+		conn.tlsKey = fmt.Sprintf("tls-%s-%d", tabID, registrySeq.Add(1))
 	}
 
-	return nil
+	mc, err := driverConfig(cfg, tunnel, conn.tlsKey)
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+
+	connector, err := mysql.NewConnector(mc)
+	if err != nil {
+		mysql.DeregisterTLSConfig(conn.tlsKey)
+		tunnel.Close()
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	stmts := splitStatements(cfg.InitialSQL)
+	if cfg.ReadOnly {
+		stmts = append([]string{"SET SESSION TRANSACTION READ ONLY"}, stmts...)
+	}
+	if len(stmts) > 0 {
+		connector = &initConnector{Connector: connector, stmts: stmts}
+	}
+	db := sql.OpenDB(connector)
+	conn.DB = db
+
+	configurePool(db, cfg)
+
+	if err := db.PingContext(ctx); err != nil {
+		conn.close()
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && myErr.Number == 1298 {
+			err = fmt.Errorf("%w (the server's time zone tables may not be loaded; use UTC or an offset such as +02:00)", err)
+		}
+		if tunnel != nil {
+			return nil, fmt.Errorf("SSH tunnel is up, but MySQL connection failed: %w", err)
+		}
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	conn.touch()
+	return conn, nil
 }
 
 // Disconnect closes the connection for a tab.
@@ -90,7 +401,7 @@ func (m *Manager) Disconnect(tabID string) error {
 		return nil
 	}
 
-	err := conn.DB.Close()
+	err := conn.close()
 	delete(m.conns, tabID)
 	return err
 }
@@ -102,25 +413,174 @@ func (m *Manager) Get(tabID string) *Connection {
 	return m.conns[tabID]
 }
 
-// Ping checks if a tab's connection is still alive.
-func (m *Manager) Ping(tabID string) error {
-	m.mu.RLock()
-	conn, ok := m.conns[tabID]
-	m.mu.RUnlock()
+// Acquire returns the connection for a tab, for use by an operation about
+// to run. A connection left idle for longer than staleAfter is pinged
+// first and, if the server dropped it, reopened once from its config.
+func (m *Manager) Acquire(tabID string) (*Connection, error) {
+	conn := m.Get(tabID)
+	if conn == nil {
+		return nil, fmt.Errorf("not connected on tab %s", tabID)
+	}
+	if conn.idleFor() < staleAfter {
+		conn.touch()
+		return conn, nil
+	}
+	return m.check(conn)
+}
 
-	if !ok {
+// Ping checks if a tab's connection is still alive, reconnecting once if
+// it was dropped.
+func (m *Manager) Ping(tabID string) error {
+	conn := m.Get(tabID)
+	if conn == nil {
 		return fmt.Errorf("no connection for tab %s", tabID)
 	}
-	return conn.DB.Ping()
+	_, err := m.check(conn)
+	return err
+}
+
+// check pings conn, replacing it with a fresh connection when the old one
+// was lost. Errors the server itself returned are passed through as is.
+func (m *Manager) check(conn *Connection) (*Connection, error) {
+	err := conn.ping()
+	if err == nil {
+		conn.notePing(nil)
+		conn.touch()
+		return conn, nil
+	}
+	if !isConnectionLost(err) {
+		conn.notePing(err)
+		return nil, err
+	}
+	fresh, err := m.reconnect(conn, err)
+	if err != nil {
+		conn.notePing(err)
+		return nil, err
+	}
+	fresh.notePing(nil)
+	return fresh, nil
+}
+
+// reconnect makes a single attempt to replace a dropped connection with a
+// new one built from the same config. Any interactive transaction on the
+// old connection is gone; the "reconnected" event says so.
+func (m *Manager) reconnect(old *Connection, cause error) (*Connection, error) {
+	m.reconnectMu.Lock()
+	defer m.reconnectMu.Unlock()
+
+	// A request that waited on the lock finds the tab already replaced.
+	if cur := m.Get(old.ID); cur != old {
+		if cur == nil {
+			return nil, fmt.Errorf("not connected on tab %s", old.ID)
+		}
+		return cur, nil
+	}
+
+	conn, err := openConnection(context.Background(), old.ID, old.ProfileID, old.Config)
+	if err != nil {
+		return nil, fmt.Errorf("connection lost (%v) and reconnecting failed: %w", cause, err)
+	}
+
+	m.mu.Lock()
+	if m.conns[old.ID] != old {
+		// Disconnected while we were dialing.
+		m.mu.Unlock()
+		conn.close()
+		return nil, fmt.Errorf("not connected on tab %s", old.ID)
+	}
+	m.conns[old.ID] = conn
+	m.mu.Unlock()
+
+	transactionLost := old.InTransaction()
+	old.close()
+
+	m.emit(old.ID, "reconnected", map[string]interface{}{
+		"transactionLost": transactionLost,
+	})
+	return conn, nil
+}
+
+func (m *Manager) emit(tabID, event string, data interface{}) {
+	if m.OnEvent != nil {
+		m.OnEvent(tabID, event, data)
+	}
+}
+
+// SetHeartbeat starts a background keep-alive that pings every connection
+// once per interval, replacing any heartbeat already running. An interval
+// of zero or less turns it off.
+func (m *Manager) SetHeartbeat(interval time.Duration) {
+	m.heartbeatMu.Lock()
+	defer m.heartbeatMu.Unlock()
+
+	if m.stopHeartbeat != nil {
+		close(m.stopHeartbeat)
+		m.stopHeartbeat = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	m.stopHeartbeat = stop
+	go m.heartbeat(interval, stop)
+}
+
+func (m *Manager) heartbeat(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.pingAll()
+		}
+	}
+}
+
+// pingAll checks each tab's connection, reconnecting dropped ones, and
+// emits a "connection-status" event per tab with the outcome.
+func (m *Manager) pingAll() {
+	for _, tabID := range m.ActiveConnections() {
+		conn := m.Get(tabID)
+		if conn == nil {
+			continue
+		}
+		status := map[string]interface{}{"status": "alive"}
+		if _, err := m.check(conn); err != nil {
+			status = map[string]interface{}{"status": "dead", "error": err.Error()}
+		}
+		m.emit(tabID, "connection-status", status)
+	}
+}
+
+// isConnectionLost reports whether err means the link to the server went
+// away (stale pooled connection, broken pipe, dead tunnel) rather than the
+// server refusing us, e.g. bad credentials, which reconnecting can't fix.
+func isConnectionLost(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
 }
 
-// CloseAll closes all connections. Called on app shutdown.
+// CloseAll stops the heartbeat and closes all connections. Called on app
+// shutdown.
 func (m *Manager) CloseAll() {
+	m.SetHeartbeat(0)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for id, conn := range m.conns {
-		conn.DB.Close()
+		conn.close()
 		delete(m.conns, id)
 	}
 }
@@ -137,28 +597,184 @@ func (m *Manager) ActiveConnections() []string {
 	return ids
 }
 
-func buildDSN(cfg ConnConfig) (string, error) {
+// States returns the state of every connected tab, ordered by tab ID.
+func (m *Manager) States() []ConnectionState {
+	m.mu.RLock()
+	conns := make([]*Connection, 0, len(m.conns))
+	for _, conn := range m.conns {
+		conns = append(conns, conn)
+	}
+	m.mu.RUnlock()
+
+	states := make([]ConnectionState, len(conns))
+	for i, conn := range conns {
+		states[i] = conn.State()
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].TabID < states[j].TabID })
+	return states
+}
+
+// configurePool applies the profile's pool settings, or the defaults.
+func configurePool(db *sql.DB, cfg ConnConfig) {
+	maxOpen := defaultMaxOpenConns
+	if cfg.MaxOpenConns > 0 {
+		maxOpen = cfg.MaxOpenConns
+	}
+	maxIdle := defaultMaxIdleConns
+	if cfg.MaxIdleConns > 0 {
+		maxIdle = cfg.MaxIdleConns
+	}
+	lifetime := defaultConnMaxLifetime
+	if cfg.ConnMaxLifetimeSeconds > 0 {
+		lifetime = time.Duration(cfg.ConnMaxLifetimeSeconds) * time.Second
+	}
+
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
+}
+
+// initConnector runs a profile's InitialSQL on each connection it opens, so
+// session settings hold on every connection of the pool rather than just
+// the first.
+type initConnector struct {
+	driver.Connector
+	stmts []string
+}
+
+func (c *initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	execer, ok := dc.(driver.ExecerContext)
+	if !ok {
+		dc.Close()
+		return nil, fmt.Errorf("driver connection cannot run initial SQL")
+	}
+	for i, stmt := range c.stmts {
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			dc.Close()
+			return nil, fmt.Errorf("initial SQL statement %d (%s) failed: %w", i+1, abbreviate(stmt, 60), err)
+		}
+	}
+	return dc, nil
+}
+
+// defaultCharset is the connection charset when a profile doesn't set one;
+// it covers all of Unicode, including emoji.
+const defaultCharset = "utf8mb4"
+
+// unsafeInterpolationCharsets are the multibyte character sets in which
+// escaping a quote can produce a byte sequence the server reads differently.
+var unsafeInterpolationCharsets = map[string]bool{
+	"big5": true, "cp932": true, "gb2312": true, "gbk": true, "sjis": true,
+}
+
+var utcOffset = regexp.MustCompile(`^([+-])(\d{2}):(\d{2})$`)
+
+// connectionZone resolves a ConnConfig.TimeZone to the location the driver
+// reads DATETIME and TIMESTAMP values in and the value for the session
+// time_zone variable. tz is "UTC", an offset such as "+02:00", or an IANA
+// name such as "Europe/Berlin", which the server only knows once its time
+// zone tables are loaded.
+//
+// Setting both keeps the server and the client in agreement. The server
+// converts TIMESTAMP values to the session zone, and grid cells show the
+// server's text as is. Typed values (see typedValue) are parsed in the
+// driver's location and carry its offset in their RFC 3339 text. Without
+// a TimeZone the driver assumes UTC whatever the session zone is, so the
+// typed form of a datetime can name a different instant than its grid text.
+func connectionZone(tz string) (*time.Location, string, error) {
+	if strings.EqualFold(tz, "UTC") {
+		return time.UTC, "+00:00", nil
+	}
+	if m := utcOffset.FindStringSubmatch(tz); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if minutes >= 60 || hours*60+minutes > 14*60 {
+			return nil, "", fmt.Errorf("invalid time zone offset: %s", tz)
+		}
+		secs := (hours*60 + minutes) * 60
+		if m[1] == "-" {
+			secs = -secs
+		}
+		return time.FixedZone(tz, secs), tz, nil
+	}
+	// "Local" would load, but the server has no zone by that name.
+	if strings.EqualFold(tz, "Local") {
+		return nil, "", fmt.Errorf("time zone must be UTC, an offset such as +02:00, or a name such as Europe/Berlin")
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, "", fmt.Errorf("unknown time zone: %s", tz)
+	}
+	return loc, tz, nil
+}
+
+// driverConfig builds the driver configuration for cfg. TLS settings, when
+// needed, are registered with the driver under tlsKey.
+func driverConfig(cfg ConnConfig, tunnel *sshTunnel, tlsKey string) (*mysql.Config, error) {
 	mc := mysql.NewConfig()
 	mc.User = cfg.Username
 	mc.Passwd = cfg.Password
 	mc.Net = "tcp"
-	mc.Addr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	mc.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	switch {
+	case cfg.SocketPath != "" && tunnel != nil:
+		return nil, fmt.Errorf("a socket path cannot be used with an SSH tunnel")
+	case cfg.SocketPath != "":
+		mc.Net = "unix"
+		mc.Addr = cfg.SocketPath
+	case tunnel != nil:
+		// Host and port are resolved on the far side of the tunnel.
+		mc.Net = tunnel.netName
+	}
 	mc.DBName = cfg.Database
-	mc.Timeout = 10 * time.Second
-	mc.ReadTimeout = 30 * time.Second
+	mc.Timeout = cfg.connectTimeout()
+	// No ReadTimeout: long queries are legitimate, and the query timeout
+	// setting bounds them through the context instead.
 	mc.WriteTimeout = 30 * time.Second
 	mc.ParseTime = true
 	mc.InterpolateParams = true
 
-	if cfg.UseSSL {
-		mc.TLSConfig = "custom"
-		err := mysql.RegisterTLSConfig("custom", &tls.Config{
-			InsecureSkipVerify: true, // DO managed DBs use self-signed certs
-		})
+	charset := cfg.Charset
+	if charset == "" {
+		charset = defaultCharset
+	}
+	if !isPluginName(charset) {
+		return nil, fmt.Errorf("invalid charset: %s", charset)
+	}
+	if cfg.Collation != "" && !isPluginName(cfg.Collation) {
+		return nil, fmt.Errorf("invalid collation: %s", cfg.Collation)
+	}
+	if err := mc.Apply(mysql.Charset(charset, cfg.Collation)); err != nil {
+		return nil, err
+	}
+	if cfg.TimeZone != "" {
+		loc, session, err := connectionZone(cfg.TimeZone)
 		if err != nil {
-			return "", fmt.Errorf("failed to configure TLS: %w", err)
+			return nil, err
+		}
+		mc.Loc = loc
+		mc.Params = map[string]string{"time_zone": quoteSQLString(session)}
+	}
+	// The driver's client-side escaping isn't safe in character sets
+	// where a multibyte character can end in a backslash byte.
+	if unsafeInterpolationCharsets[strings.ToLower(charset)] {
+		mc.InterpolateParams = false
+	}
+
+	tlsCfg, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	if tlsCfg != nil {
+		if err := mysql.RegisterTLSConfig(tlsKey, tlsCfg); err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
 		}
+		mc.TLSConfig = tlsKey
 	}
 
-	return mc.FormatDSN(), nil
+	return mc, nil
 }
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// queryExportRows starts a query export: query must be one statement that
+// returns rows, and one ExecOptions.ReadOnly allows when readOnly is set.
+// The row count isn't known up front, so exports report progress with a
+// total of -1.
+func queryExportRows(ctx context.Context, db Querier, query string, readOnly bool) (*sql.Rows, error) {
+	stmts := splitStatements(query)
+	if len(stmts) != 1 {
+		return nil, fmt.Errorf("only a single statement can be exported")
+	}
+	if !isSelectQuery(stmts[0]) || containsWord(topLevelWords(stmts[0]), "INTO") {
+		return nil, fmt.Errorf("only a statement that returns rows can be exported")
+	}
+	if readOnly && !readOnlyAllowed(stmts[0]) {
+		return nil, ErrReadOnly
+	}
+	return db.QueryContext(ctx, stmts[0])
+}
+
+// ExportQueryCSV runs a SELECT and streams its rows to CSV as they
+// arrive, without collecting the result first.
+func ExportQueryCSV(ctx context.Context, db Querier, query string, readOnly bool, w io.Writer, opts CSVOptions, progress ProgressFunc) error {
+	rows, err := queryExportRows(ctx, db, query, readOnly)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return writeRowsCSV(ctx, rows, w, opts, progress, -1)
+}
+
+// ExportQuerySQL runs a SELECT and streams its rows as INSERT statements
+// into tableName.
+func ExportQuerySQL(ctx context.Context, db Querier, query string, readOnly bool, tableName string, w io.Writer, opts SQLExportOptions, progress ProgressFunc) error {
+	if tableName == "" {
+		return fmt.Errorf("table name is required")
+	}
+	rows, err := queryExportRows(ctx, db, query, readOnly)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return writeInserts(ctx, rows, tableName, w, opts, progress, -1)
+}
+
+// ExportQueryJSON runs a SELECT and streams its rows as a JSON array of
+// objects keyed by column name. Values are typed as in typed query results
+// (see typedValue), except binary data, which is base64 text.
+func ExportQueryJSON(ctx context.Context, db Querier, query string, readOnly bool, w io.Writer, progress ProgressFunc) error {
+	rows, err := queryExportRows(ctx, db, query, readOnly)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	meta := columnMeta(cols, colTypes)
+	keys := make([]string, len(cols))
+	for i, name := range cols {
+		k, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		keys[i] = string(k)
+	}
+
+	scanVals := make([]interface{}, len(cols))
+	scanPtrs := make([]interface{}, len(cols))
+	for i := range scanVals {
+		scanPtrs[i] = &scanVals[i]
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("[")
+	var written int64
+	for rows.Next() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := rows.Scan(scanPtrs...); err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		if written > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for i, v := range scanVals {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			var val interface{}
+			if raw, ok := v.([]byte); ok && meta[i].Kind == KindBinary {
+				val = raw // marshals as base64
+			} else {
+				val = typedValue(v, meta[i])
+			}
+			enc, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			b.WriteString(keys[i] + ": ")
+			b.Write(enc)
+		}
+		b.WriteString("}")
+		if _, err := bw.WriteString(b.String()); err != nil {
+			return err
+		}
+
+		written++
+		if progress != nil && written%500 == 0 {
+			if !progress(written, -1) {
+				return fmt.Errorf("cancelled")
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if written > 0 {
+		bw.WriteString("\n")
+	}
+	bw.WriteString("]\n")
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	if progress != nil {
+		progress(written, -1)
+	}
+	return nil
+}
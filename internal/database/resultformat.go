@@ -0,0 +1,214 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// ResultFormats lists the formats FormatResults accepts.
+var ResultFormats = []string{"csv", "tsv", "json", "markdown", "html", "insert-sql"}
+
+// ResultFormatOptions supplies what some FormatResults formats need.
+type ResultFormatOptions struct {
+	TableName   string   `json:"tableName"`   // INSERT target for insert-sql; "" means "results"
+	ColumnTypes []string `json:"columnTypes"` // optional database type names
+}
+
+// FormatResults renders grid result data as text for the clipboard, using
+// the same writers as the result exports. nulls marks the NULL cells, as
+// in ExportResultCSV.
+func FormatResults(format string, columns []string, rows [][]string, nulls GridNulls, opts ResultFormatOptions) (string, error) {
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case "csv":
+		err = ExportResultCSV(&b, columns, rows, nulls, DefaultCSVOptions())
+	case "tsv":
+		csvOpts := DefaultCSVOptions()
+		csvOpts.Delimiter = "\t"
+		err = ExportResultCSV(&b, columns, rows, nulls, csvOpts)
+	case "json":
+		err = writeResultJSON(&b, columns, rows, nulls, opts.ColumnTypes)
+	case "markdown":
+		err = ExportResultMarkdown(&b, columns, rows, nulls, opts.ColumnTypes)
+	case "html":
+		err = ExportResultHTML(&b, columns, rows, nulls, opts.ColumnTypes)
+	case "insert-sql":
+		table := opts.TableName
+		if table == "" {
+			table = "results"
+		}
+		err = ExportResultSQL(&b, table, columns, rows, nulls, opts.ColumnTypes)
+	default:
+		return "", fmt.Errorf("unknown result format: %s", format)
+	}
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// columnType returns the type name of column i, or "" when unknown.
+func columnType(columnTypes []string, i int) string {
+	if i < len(columnTypes) {
+		return columnTypes[i]
+	}
+	return ""
+}
+
+// writeResultJSON writes grid result data as an array of objects with keys
+// in column order. Numeric columns (per columnTypes) are JSON numbers and
+// NULL cells are null.
+func writeResultJSON(w io.Writer, columns []string, rows [][]string, nulls GridNulls, columnTypes []string) error {
+	keys := make([][]byte, len(columns))
+	for i, name := range columns {
+		k, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		keys[i] = k
+	}
+
+	isNull := nulls.lookup()
+	var b bytes.Buffer
+	b.WriteString("[")
+	for r, row := range rows {
+		if r > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for i, v := range row {
+			if i >= len(keys) {
+				break
+			}
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.Write(keys[i])
+			b.WriteString(": ")
+			switch {
+			case isNull(r, i, v):
+				b.WriteString("null")
+			case kindOf(columnType(columnTypes, i)) == KindNumber && isJSONNumber(v):
+				b.WriteString(v)
+			default:
+				s, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				b.Write(s)
+			}
+		}
+		b.WriteString("}")
+	}
+	if len(rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// isJSONNumber reports whether s can be written as a bare JSON number.
+func isJSONNumber(s string) bool {
+	return s != "" && (s[0] == '-' || s[0] >= '0' && s[0] <= '9') && json.Valid([]byte(s))
+}
+
+// ExportResultMarkdown writes grid result data as a GitHub-flavored
+// Markdown table. Numeric columns (per columnTypes, when given) are right
+// aligned, NULL cells are an italic NULL, pipes are escaped, and line
+// breaks become <br>.
+func ExportResultMarkdown(w io.Writer, columns []string, rows [][]string, nulls GridNulls, columnTypes []string) error {
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, c := range cells {
+			b.WriteString(" " + c + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	header := make([]string, len(columns))
+	align := make([]string, len(columns))
+	for i, name := range columns {
+		header[i] = markdownCell(name)
+		align[i] = "---"
+		if kindOf(columnType(columnTypes, i)) == KindNumber {
+			align[i] = "---:"
+		}
+	}
+	writeRow(header)
+	writeRow(align)
+
+	isNull := nulls.lookup()
+	for r, row := range rows {
+		cells := make([]string, len(columns))
+		for i := range cells {
+			switch {
+			case i >= len(row):
+			case isNull(r, i, row[i]):
+				cells[i] = "*NULL*"
+			default:
+				cells[i] = markdownCell(row[i])
+			}
+		}
+		writeRow(cells)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownCellReplacer = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(s string) string {
+	return markdownCellReplacer.Replace(s)
+}
+
+// htmlStyle keeps exported tables readable without any other stylesheet.
+const htmlStyle = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 14px; margin: 16px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; white-space: pre-wrap; }
+th { background: #f6f8fa; font-weight: 600; }
+tr:nth-child(even) td { background: #fafbfc; }
+td.num { text-align: right; }
+td.null { color: #8c959f; font-style: italic; }`
+
+// ExportResultHTML writes grid result data as a standalone HTML page holding
+// one styled <table>. Numeric columns (per columnTypes, when given) are
+// right aligned and NULL cells are shown as a gray NULL.
+func ExportResultHTML(w io.Writer, columns []string, rows [][]string, nulls GridNulls, columnTypes []string) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Query results</title>\n<style>\n")
+	b.WriteString(htmlStyle)
+	b.WriteString("\n</style>\n</head>\n<body>\n<table>\n<thead>\n<tr>")
+	for _, name := range columns {
+		b.WriteString("<th>" + html.EscapeString(name) + "</th>")
+	}
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+
+	isNull := nulls.lookup()
+	for r, row := range rows {
+		b.WriteString("<tr>")
+		for i := range columns {
+			switch {
+			case i >= len(row):
+				b.WriteString("<td></td>")
+			case isNull(r, i, row[i]):
+				b.WriteString(`<td class="null">NULL</td>`)
+			case kindOf(columnType(columnTypes, i)) == KindNumber:
+				b.WriteString(`<td class="num">` + html.EscapeString(row[i]) + "</td>")
+			default:
+				b.WriteString("<td>" + html.EscapeString(row[i]) + "</td>")
+			}
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</tbody>\n</table>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
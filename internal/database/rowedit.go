@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// UpdateRow sets one column of the row whose primary key columns equal
+// pkValues. Values are bound as parameters; a nil newValue stores NULL.
+// pkColumns must name the table's complete primary key, so the statement
+// can only ever touch a single row.
+func UpdateRow(db *sql.DB, database, table string, pkColumns []string, pkValues []*string, column string, newValue *string) (int64, error) {
+	where, args, err := primaryKeyFilter(db, database, table, pkColumns, pkValues)
+	if err != nil {
+		return 0, err
+	}
+	if column == "" {
+		return 0, fmt.Errorf("column is required")
+	}
+	stmt := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s", qualifiedName(database, table), quoteIdent(column), where)
+	return execRowEdit(db, stmt, append([]interface{}{nullableArg(newValue)}, args...)...)
+}
+
+// DeleteRow deletes the row whose primary key columns equal pkValues; see
+// UpdateRow.
+func DeleteRow(db *sql.DB, database, table string, pkColumns []string, pkValues []*string) (int64, error) {
+	where, args, err := primaryKeyFilter(db, database, table, pkColumns, pkValues)
+	if err != nil {
+		return 0, err
+	}
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE %s", qualifiedName(database, table), where)
+	return execRowEdit(db, stmt, args...)
+}
+
+func execRowEdit(db *sql.DB, stmt string, args ...interface{}) (int64, error) {
+	res, err := db.Exec(stmt, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// primaryKeyFilter builds the WHERE clause and arguments matching one row
+// by primary key, after checking pkColumns against the table's actual key.
+func primaryKeyFilter(db *sql.DB, database, table string, pkColumns []string, pkValues []*string) (string, []interface{}, error) {
+	if len(pkColumns) == 0 {
+		return "", nil, fmt.Errorf("primary key columns are required")
+	}
+	if len(pkColumns) != len(pkValues) {
+		return "", nil, fmt.Errorf("got %d primary key values for %d columns", len(pkValues), len(pkColumns))
+	}
+
+	cols, err := listColumns(db, database, table)
+	if err != nil {
+		return "", nil, err
+	}
+	given := make(map[string]bool, len(pkColumns))
+	for _, name := range pkColumns {
+		given[strings.ToLower(name)] = true
+	}
+	var key []string
+	matches := len(given) == len(pkColumns)
+	for _, col := range cols {
+		if col.Key == "PRI" {
+			key = append(key, col.Name)
+			matches = matches && given[strings.ToLower(col.Name)]
+		}
+	}
+	if len(key) == 0 {
+		return "", nil, fmt.Errorf("table %s has no primary key", table)
+	}
+	if !matches || len(key) != len(pkColumns) {
+		return "", nil, fmt.Errorf("columns %s are not the primary key of %s (%s)",
+			strings.Join(pkColumns, ", "), table, strings.Join(key, ", "))
+	}
+
+	conds := make([]string, len(pkColumns))
+	args := make([]interface{}, len(pkValues))
+	for i, name := range pkColumns {
+		if pkValues[i] == nil {
+			return "", nil, fmt.Errorf("primary key column %s cannot be NULL", name)
+		}
+		conds[i] = quoteIdent(name) + " = ?"
+		args[i] = *pkValues[i]
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+func nullableArg(v *string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -1,8 +1,12 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // DatabaseInfo holds basic database metadata.
@@ -12,47 +16,51 @@ type DatabaseInfo struct {
 
 // TableInfo holds basic table/view metadata.
 type TableInfo struct {
-	Name      string `json:"name"`
-	Type      string `json:"type"` // "BASE TABLE" or "VIEW"
-	Engine    string `json:"engine"`
-	RowCount  int64  `json:"rowCount"`
-	DataSize  int64  `json:"dataSize"`
-	Collation string `json:"collation"`
+	Name       string `json:"name"`
+	Type       string `json:"type"` // "BASE TABLE" or "VIEW"
+	Engine     string `json:"engine"`
+	RowCount   int64  `json:"rowCount"`
+	IsEstimate bool   `json:"isEstimate"` // RowCount is the engine's approximation; see ExactRowCount
+	DataSize   int64  `json:"dataSize"`   // DATA_LENGTH
+	IndexSize  int64  `json:"indexSize"`  // INDEX_LENGTH
+	TotalSize  int64  `json:"totalSize"`  // DataSize + IndexSize
+	SizeText   string `json:"sizeText"`   // TotalSize formatted by FormatBytes
+	Collation  string `json:"collation"`
 }
 
 // ColumnInfo holds column metadata.
 type ColumnInfo struct {
-	Name         string  `json:"name"`
-	Position     int     `json:"position"`
-	Default      *string `json:"default"`
-	Nullable     bool    `json:"nullable"`
-	DataType     string  `json:"dataType"`
-	ColumnType   string  `json:"columnType"`
-	MaxLength    *int64  `json:"maxLength"`
-	CharSet      *string `json:"charSet"`
-	Collation    *string `json:"collation"`
-	Key          string  `json:"key"` // PRI, UNI, MUL, or ""
-	Extra        string  `json:"extra"`
-	Comment      string  `json:"comment"`
+	Name       string  `json:"name"`
+	Position   int     `json:"position"`
+	Default    *string `json:"default"`
+	Nullable   bool    `json:"nullable"`
+	DataType   string  `json:"dataType"`
+	ColumnType string  `json:"columnType"`
+	MaxLength  *int64  `json:"maxLength"`
+	CharSet    *string `json:"charSet"`
+	Collation  *string `json:"collation"`
+	Key        string  `json:"key"` // PRI, UNI, MUL, or ""
+	Extra      string  `json:"extra"`
+	Comment    string  `json:"comment"`
 }
 
 // IndexInfo holds index metadata.
 type IndexInfo struct {
-	Name       string `json:"name"`
-	Columns    string `json:"columns"`
-	Unique     bool   `json:"unique"`
-	Type       string `json:"type"` // BTREE, FULLTEXT, HASH, etc.
-	Comment    string `json:"comment"`
+	Name    string `json:"name"`
+	Columns string `json:"columns"`
+	Unique  bool   `json:"unique"`
+	Type    string `json:"type"` // BTREE, FULLTEXT, HASH, etc.
+	Comment string `json:"comment"`
 }
 
 // ForeignKeyInfo holds foreign key metadata.
 type ForeignKeyInfo struct {
-	Name             string `json:"name"`
-	Column           string `json:"column"`
-	RefTable         string `json:"refTable"`
-	RefColumn        string `json:"refColumn"`
-	UpdateRule       string `json:"updateRule"`
-	DeleteRule       string `json:"deleteRule"`
+	Name       string `json:"name"`
+	Column     string `json:"column"`
+	RefTable   string `json:"refTable"`
+	RefColumn  string `json:"refColumn"`
+	UpdateRule string `json:"updateRule"`
+	DeleteRule string `json:"deleteRule"`
 }
 
 // RoutineInfo holds stored procedure/function metadata.
@@ -65,21 +73,81 @@ type RoutineInfo struct {
 // TriggerInfo holds trigger metadata.
 type TriggerInfo struct {
 	Name      string `json:"name"`
-	Event     string `json:"event"` // INSERT, UPDATE, DELETE
+	Event     string `json:"event"`  // INSERT, UPDATE, DELETE
 	Timing    string `json:"timing"` // BEFORE, AFTER
 	Table     string `json:"table"`
 	Statement string `json:"statement"`
 }
 
+// EventInfo holds scheduled event metadata.
+type EventInfo struct {
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`     // "ONE TIME" or "RECURRING"
+	Schedule      string     `json:"schedule"` // e.g. "EVERY 1 DAY" or "AT 2024-01-01 00:00:00"
+	Status        string     `json:"status"`   // ENABLED, DISABLED, or SLAVESIDE_DISABLED
+	LastExecuted  *time.Time `json:"lastExecuted"`
+	NextExecution *time.Time `json:"nextExecution"` // nil when the event won't run again
+}
+
+// RoutineDetail is the source and characteristics of a stored routine.
+type RoutineDetail struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"` // "PROCEDURE" or "FUNCTION"
+	Definer       string `json:"definer"`
+	Deterministic bool   `json:"deterministic"`
+	Security      string `json:"security"`   // DEFINER or INVOKER
+	DataAccess    string `json:"dataAccess"` // CONTAINS SQL, READS SQL DATA, etc.
+	Comment       string `json:"comment"`
+	CreateSQL     string `json:"createSql"`
+}
+
+// TriggerDetail is the source of a trigger.
+type TriggerDetail struct {
+	Name      string `json:"name"`
+	Event     string `json:"event"`
+	Timing    string `json:"timing"`
+	Table     string `json:"table"`
+	Definer   string `json:"definer"`
+	CreateSQL string `json:"createSql"`
+}
+
 // TableDetail is the full detail view for a single table.
 type TableDetail struct {
 	Columns     []ColumnInfo     `json:"columns"`
 	Indexes     []IndexInfo      `json:"indexes"`
 	ForeignKeys []ForeignKeyInfo `json:"foreignKeys"`
+	Partitions  []PartitionInfo  `json:"partitions"` // empty unless the table is partitioned
 	CreateSQL   string           `json:"createSql"`
 }
 
-// ListDatabases returns all databases visible to the connection.
+// PartitionInfo describes one partition, or subpartition, of a table.
+type PartitionInfo struct {
+	Name               string  `json:"name"`
+	Subpartition       *string `json:"subpartition"`
+	Method             string  `json:"method"`      // RANGE, LIST, HASH, KEY, or a COLUMNS/LINEAR variant
+	Expression         string  `json:"expression"`  // the partitioning expression or column list
+	Description        *string `json:"description"` // RANGE upper bound or LIST values
+	SubpartitionMethod *string `json:"subpartitionMethod"`
+	SubpartitionExpr   *string `json:"subpartitionExpression"`
+	RowCount           int64   `json:"rowCount"` // an estimate, as in TableInfo
+	DataSize           int64   `json:"dataSize"`
+	IndexSize          int64   `json:"indexSize"`
+}
+
+// ViewDetail is the definition of a view.
+type ViewDetail struct {
+	Name       string `json:"name"`
+	CreateSQL  string `json:"createSql"`
+	Definition string `json:"definition"` // the SELECT after AS
+	Algorithm  string `json:"algorithm"`  // UNDEFINED, MERGE, or TEMPTABLE
+	Definer    string `json:"definer"`
+	Security   string `json:"security"` // DEFINER or INVOKER
+	CharSet    string `json:"charSet"`
+	Collation  string `json:"collation"`
+}
+
+// ListDatabases returns all databases visible to the connection, by name
+// (see SortDatabases).
 func ListDatabases(db *sql.DB) ([]DatabaseInfo, error) {
 	rows, err := db.Query("SHOW DATABASES")
 	if err != nil {
@@ -95,18 +163,22 @@ func ListDatabases(db *sql.DB) ([]DatabaseInfo, error) {
 		}
 		dbs = append(dbs, DatabaseInfo{Name: name})
 	}
-	return dbs, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortDatabases(dbs, OrderBinary)
+	return dbs, nil
 }
 
-// ListTables returns tables and views in a database.
+// ListTables returns tables and views in a database, by type and then
+// name (see SortTables).
 func ListTables(db *sql.DB, database string) ([]TableInfo, error) {
 	query := `
 		SELECT TABLE_NAME, TABLE_TYPE, IFNULL(ENGINE, ''),
-		       IFNULL(TABLE_ROWS, 0), IFNULL(DATA_LENGTH, 0),
+		       IFNULL(TABLE_ROWS, 0), IFNULL(DATA_LENGTH, 0), IFNULL(INDEX_LENGTH, 0),
 		       IFNULL(TABLE_COLLATION, '')
 		FROM INFORMATION_SCHEMA.TABLES
 		WHERE TABLE_SCHEMA = ?
-		ORDER BY TABLE_TYPE, TABLE_NAME
 	`
 	rows, err := db.Query(query, database)
 	if err != nil {
@@ -117,12 +189,52 @@ func ListTables(db *sql.DB, database string) ([]TableInfo, error) {
 	var tables []TableInfo
 	for rows.Next() {
 		var t TableInfo
-		if err := rows.Scan(&t.Name, &t.Type, &t.Engine, &t.RowCount, &t.DataSize, &t.Collation); err != nil {
+		if err := rows.Scan(&t.Name, &t.Type, &t.Engine, &t.RowCount, &t.DataSize, &t.IndexSize, &t.Collation); err != nil {
 			return nil, err
 		}
+		t.TotalSize = t.DataSize + t.IndexSize
+		t.SizeText = FormatBytes(t.TotalSize)
+		t.IsEstimate = t.Type == "BASE TABLE" && !exactRowCountEngines[strings.ToUpper(t.Engine)]
 		tables = append(tables, t)
 	}
-	return tables, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortTables(tables, OrderBinary)
+	return tables, nil
+}
+
+// exactRowCountEngines keep an exact TABLE_ROWS; other engines estimate it.
+var exactRowCountEngines = map[string]bool{
+	"MYISAM": true,
+	"ARIA":   true,
+	"MEMORY": true,
+}
+
+// FormatBytes renders a byte count the way sizes are shown in the UI,
+// e.g. "512 B", "1.5 KB", "20.3 MB". Units are powers of 1024.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// CountRows counts the rows of a table matching where, a raw SQL condition
+// as in ExportFilter.Where; "" counts them all. It scans the table, so
+// callers run it on demand rather than for every listed table, and cancel
+// ctx to give up on a slow count.
+func CountRows(ctx context.Context, db Querier, database, table, where string) (int64, error) {
+	var n int64
+	query := "SELECT COUNT(*) FROM " + qualifiedName(database, table) + ExportFilter{Where: where}.whereClause()
+	err := db.QueryRowContext(ctx, query).Scan(&n)
+	return n, err
 }
 
 // GetTableDetail returns full details for a table: columns, indexes, FKs, DDL.
@@ -150,6 +262,13 @@ func GetTableDetail(db *sql.DB, database, table string) (*TableDetail, error) {
 	}
 	detail.ForeignKeys = fks
 
+	// Partitions
+	partitions, err := ListPartitions(db, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("partitions: %w", err)
+	}
+	detail.Partitions = partitions
+
 	// DDL
 	ddl, err := getCreateTable(db, database, table)
 	if err != nil {
@@ -160,13 +279,47 @@ func GetTableDetail(db *sql.DB, database, table string) (*TableDetail, error) {
 	return detail, nil
 }
 
-// ListRoutines returns stored procedures and functions in a database.
+// GetViewDefinition returns a view's DDL along with the options from its
+// CREATE VIEW header.
+func GetViewDefinition(db *sql.DB, database, view string) (*ViewDetail, error) {
+	v := &ViewDetail{}
+	query := fmt.Sprintf("SHOW CREATE VIEW %s", qualifiedName(database, view))
+	if err := db.QueryRow(query).Scan(&v.Name, &v.CreateSQL, &v.CharSet, &v.Collation); err != nil {
+		return nil, err
+	}
+
+	// CREATE ALGORITHM=... DEFINER=... SQL SECURITY ... VIEW `v` AS select ...
+	at := topLevelWordAt(v.CreateSQL, "VIEW")
+	if at < 0 {
+		return v, nil
+	}
+	header := v.CreateSQL[:at]
+	if as := topLevelWordAt(v.CreateSQL[at:], "AS"); as >= 0 {
+		v.Definition = strings.TrimSpace(v.CreateSQL[at+as+2:])
+	}
+	for _, f := range strings.Fields(header) {
+		switch {
+		case strings.HasPrefix(strings.ToUpper(f), "ALGORITHM="):
+			v.Algorithm = f[len("ALGORITHM="):]
+		case strings.HasPrefix(strings.ToUpper(f), "DEFINER="):
+			v.Definer = f[len("DEFINER="):]
+		}
+	}
+	if i := strings.Index(strings.ToUpper(header), "SQL SECURITY "); i >= 0 {
+		if f := strings.Fields(header[i+len("SQL SECURITY "):]); len(f) > 0 {
+			v.Security = f[0]
+		}
+	}
+	return v, nil
+}
+
+// ListRoutines returns stored procedures and functions in a database, by
+// type and then name (see SortRoutines).
 func ListRoutines(db *sql.DB, database string) ([]RoutineInfo, error) {
 	query := `
 		SELECT ROUTINE_NAME, ROUTINE_TYPE, CREATED
 		FROM INFORMATION_SCHEMA.ROUTINES
 		WHERE ROUTINE_SCHEMA = ?
-		ORDER BY ROUTINE_TYPE, ROUTINE_NAME
 	`
 	rows, err := db.Query(query, database)
 	if err != nil {
@@ -182,17 +335,21 @@ func ListRoutines(db *sql.DB, database string) ([]RoutineInfo, error) {
 		}
 		routines = append(routines, r)
 	}
-	return routines, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortRoutines(routines, OrderBinary)
+	return routines, nil
 }
 
-// ListTriggers returns triggers in a database.
+// ListTriggers returns triggers in a database, by table and then name
+// (see SortTriggers).
 func ListTriggers(db *sql.DB, database string) ([]TriggerInfo, error) {
 	query := `
 		SELECT TRIGGER_NAME, EVENT_MANIPULATION, ACTION_TIMING,
 		       EVENT_OBJECT_TABLE, ACTION_STATEMENT
 		FROM INFORMATION_SCHEMA.TRIGGERS
 		WHERE TRIGGER_SCHEMA = ?
-		ORDER BY EVENT_OBJECT_TABLE, TRIGGER_NAME
 	`
 	rows, err := db.Query(query, database)
 	if err != nil {
@@ -208,7 +365,170 @@ func ListTriggers(db *sql.DB, database string) ([]TriggerInfo, error) {
 		}
 		triggers = append(triggers, t)
 	}
-	return triggers, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortTriggers(triggers, OrderBinary)
+	return triggers, nil
+}
+
+// GetRoutineDefinition returns the CREATE statement and characteristics of
+// a procedure or function.
+func GetRoutineDefinition(db *sql.DB, database, name, routineType string) (*RoutineDetail, error) {
+	routineType = strings.ToUpper(routineType)
+	if routineType != "PROCEDURE" && routineType != "FUNCTION" {
+		return nil, fmt.Errorf("invalid routine type: %s", routineType)
+	}
+	r := &RoutineDetail{Name: name, Type: routineType}
+
+	var deterministic string
+	err := db.QueryRow(`
+		SELECT DEFINER, IS_DETERMINISTIC, SECURITY_TYPE, SQL_DATA_ACCESS, ROUTINE_COMMENT
+		FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ? AND ROUTINE_TYPE = ?`,
+		database, name, routineType,
+	).Scan(&r.Definer, &deterministic, &r.Security, &r.DataAccess, &r.Comment)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%s not found: %s", strings.ToLower(routineType), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Deterministic = deterministic == "YES"
+
+	query := fmt.Sprintf("SHOW CREATE %s %s", routineType, qualifiedName(database, name))
+	if r.CreateSQL, err = showCreate(context.Background(), db, query, 2); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetTriggerDefinition returns the full CREATE TRIGGER statement for a
+// trigger.
+func GetTriggerDefinition(db *sql.DB, database, name string) (*TriggerDetail, error) {
+	t := &TriggerDetail{Name: name}
+	err := db.QueryRow(`
+		SELECT EVENT_MANIPULATION, ACTION_TIMING, EVENT_OBJECT_TABLE, DEFINER
+		FROM INFORMATION_SCHEMA.TRIGGERS
+		WHERE TRIGGER_SCHEMA = ? AND TRIGGER_NAME = ?`,
+		database, name,
+	).Scan(&t.Event, &t.Timing, &t.Table, &t.Definer)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("trigger not found: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SHOW CREATE TRIGGER " + qualifiedName(database, name)
+	if t.CreateSQL, err = showCreate(context.Background(), db, query, 2); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListEvents returns the scheduled events in a database, by name (see
+// SortEvents). Times are in each event's own time zone.
+func ListEvents(db *sql.DB, database string) ([]EventInfo, error) {
+	query := `
+		SELECT EVENT_NAME, EVENT_TYPE, EXECUTE_AT, IFNULL(INTERVAL_VALUE, ''),
+		       IFNULL(INTERVAL_FIELD, ''), STARTS, ENDS, STATUS, LAST_EXECUTED,
+		       IFNULL(CONVERT_TZ(NOW(), @@session.time_zone, TIME_ZONE), NOW())
+		FROM INFORMATION_SCHEMA.EVENTS
+		WHERE EVENT_SCHEMA = ?
+	`
+	rows, err := db.Query(query, database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []EventInfo
+	for rows.Next() {
+		var e EventInfo
+		var executeAt, starts, ends, last sql.NullTime
+		var value, field string
+		var now time.Time
+		if err := rows.Scan(&e.Name, &e.Type, &executeAt, &value, &field, &starts, &ends, &e.Status, &last, &now); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			e.LastExecuted = &last.Time
+		}
+		if e.Type == "ONE TIME" {
+			if executeAt.Valid {
+				e.Schedule = "AT " + executeAt.Time.Format("2006-01-02 15:04:05")
+				if e.Status == "ENABLED" && executeAt.Time.After(now) {
+					e.NextExecution = &executeAt.Time
+				}
+			}
+		} else {
+			e.Schedule = "EVERY " + value + " " + field
+			if e.Status == "ENABLED" && starts.Valid {
+				e.NextExecution = nextEventRun(now, starts.Time, ends, value, field)
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortEvents(events, OrderBinary)
+	return events, nil
+}
+
+// nextEventRun returns the first run of a recurring event after now.
+// Compound intervals such as DAY_HOUR aren't worked out and give nil.
+func nextEventRun(now, starts time.Time, ends sql.NullTime, value, field string) *time.Time {
+	n, err := strconv.Atoi(strings.Trim(value, "'"))
+	if err != nil || n <= 0 {
+		return nil
+	}
+
+	next := starts
+	if !next.After(now) {
+		var step time.Duration
+		months := 0
+		switch field {
+		case "SECOND":
+			step = time.Second
+		case "MINUTE":
+			step = time.Minute
+		case "HOUR":
+			step = time.Hour
+		case "DAY":
+			step = 24 * time.Hour
+		case "WEEK":
+			step = 7 * 24 * time.Hour
+		case "MONTH":
+			months = 1
+		case "QUARTER":
+			months = 3
+		case "YEAR":
+			months = 12
+		default:
+			return nil
+		}
+		if step > 0 {
+			step *= time.Duration(n)
+			next = starts.Add((now.Sub(starts)/step + 1) * step)
+		} else {
+			months *= n
+			k := ((now.Year()-starts.Year())*12 + int(now.Month()-starts.Month())) / months
+			for next = starts.AddDate(0, k*months, 0); !next.After(now); k++ {
+				next = starts.AddDate(0, (k+1)*months, 0)
+			}
+		}
+	}
+	if ends.Valid && next.After(ends.Time) {
+		return nil
+	}
+	return &next
+}
+
+// GetEventDefinition returns the CREATE EVENT statement for an event.
+func GetEventDefinition(db *sql.DB, database, name string) (string, error) {
+	return showCreate(context.Background(), db, "SHOW CREATE EVENT "+qualifiedName(database, name), 3)
 }
 
 func listColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
@@ -244,15 +564,16 @@ func listColumns(db *sql.DB, database, table string) ([]ColumnInfo, error) {
 }
 
 func listIndexes(db *sql.DB, database, table string) ([]IndexInfo, error) {
-	// Group columns per index since STATISTICS has one row per column.
+	// STATISTICS has one row per index column. The column lists are joined
+	// here rather than with GROUP_CONCAT, which cuts them off at
+	// group_concat_max_len.
 	query := `
-		SELECT INDEX_NAME, GROUP_CONCAT(COLUMN_NAME ORDER BY SEQ_IN_INDEX),
+		SELECT INDEX_NAME, COLUMN_NAME,
 		       CASE WHEN NON_UNIQUE = 0 THEN 1 ELSE 0 END,
 		       INDEX_TYPE, IFNULL(INDEX_COMMENT, '')
 		FROM INFORMATION_SCHEMA.STATISTICS
 		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
-		GROUP BY INDEX_NAME, NON_UNIQUE, INDEX_TYPE, INDEX_COMMENT
-		ORDER BY INDEX_NAME
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
 	`
 	rows, err := db.Query(query, database, table)
 	if err != nil {
@@ -261,14 +582,28 @@ func listIndexes(db *sql.DB, database, table string) ([]IndexInfo, error) {
 	defer rows.Close()
 
 	var indexes []IndexInfo
+	var columns []string
 	for rows.Next() {
 		var idx IndexInfo
+		var column sql.NullString // NULL for a functional key part
 		var unique int
-		if err := rows.Scan(&idx.Name, &idx.Columns, &unique, &idx.Type, &idx.Comment); err != nil {
+		if err := rows.Scan(&idx.Name, &column, &unique, &idx.Type, &idx.Comment); err != nil {
 			return nil, err
 		}
-		idx.Unique = unique == 1
-		indexes = append(indexes, idx)
+		if n := len(indexes); n == 0 || indexes[n-1].Name != idx.Name {
+			if n > 0 {
+				indexes[n-1].Columns = strings.Join(columns, ",")
+			}
+			idx.Unique = unique == 1
+			indexes = append(indexes, idx)
+			columns = columns[:0]
+		}
+		if column.Valid {
+			columns = append(columns, column.String)
+		}
+	}
+	if n := len(indexes); n > 0 {
+		indexes[n-1].Columns = strings.Join(columns, ",")
 	}
 	return indexes, rows.Err()
 }
@@ -303,9 +638,41 @@ func listForeignKeys(db *sql.DB, database, table string) ([]ForeignKeyInfo, erro
 	return fks, rows.Err()
 }
 
+// ListPartitions returns a table's partitions in definition order. A table
+// that isn't partitioned has none.
+func ListPartitions(db *sql.DB, database, table string) ([]PartitionInfo, error) {
+	query := `
+		SELECT PARTITION_NAME, SUBPARTITION_NAME,
+		       IFNULL(PARTITION_METHOD, ''), IFNULL(PARTITION_EXPRESSION, ''),
+		       PARTITION_DESCRIPTION, SUBPARTITION_METHOD, SUBPARTITION_EXPRESSION,
+		       IFNULL(TABLE_ROWS, 0), IFNULL(DATA_LENGTH, 0), IFNULL(INDEX_LENGTH, 0)
+		FROM INFORMATION_SCHEMA.PARTITIONS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		  AND PARTITION_NAME IS NOT NULL
+		ORDER BY PARTITION_ORDINAL_POSITION, SUBPARTITION_ORDINAL_POSITION
+	`
+	rows, err := db.Query(query, database, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := []PartitionInfo{}
+	for rows.Next() {
+		var p PartitionInfo
+		if err := rows.Scan(&p.Name, &p.Subpartition, &p.Method, &p.Expression,
+			&p.Description, &p.SubpartitionMethod, &p.SubpartitionExpr,
+			&p.RowCount, &p.DataSize, &p.IndexSize); err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, p)
+	}
+	return partitions, rows.Err()
+}
+
 func getCreateTable(db *sql.DB, database, table string) (string, error) {
 	var tbl, ddl string
-	query := fmt.Sprintf("SHOW CREATE TABLE `%s`.`%s`", database, table)
+	query := "SHOW CREATE TABLE " + qualifiedName(database, table)
 	err := db.QueryRow(query).Scan(&tbl, &ddl)
 	if err != nil {
 		return "", err
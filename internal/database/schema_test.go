@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cannedDB is a database/sql connector whose connections answer every
+// query with the same rows, for testing code that reads query results
+// without a server. It records the queries it was sent.
+type cannedDB struct {
+	columns []string
+	rows    [][]driver.Value
+	queries []string
+}
+
+func (d *cannedDB) open() *sql.DB                                { return sql.OpenDB(d) }
+func (d *cannedDB) Connect(context.Context) (driver.Conn, error) { return cannedConn{d}, nil }
+func (d *cannedDB) Driver() driver.Driver                        { return cannedDriver{d} }
+
+type cannedDriver struct{ d *cannedDB }
+
+func (d cannedDriver) Open(string) (driver.Conn, error) { return cannedConn(d), nil }
+
+type cannedConn struct{ d *cannedDB }
+
+func (c cannedConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c cannedConn) Close() error                        { return nil }
+func (c cannedConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c cannedConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.queries = append(c.d.queries, query)
+	return &cannedRows{columns: c.d.columns, rows: c.d.rows}, nil
+}
+
+type cannedRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *cannedRows) Columns() []string { return r.columns }
+func (r *cannedRows) Close() error      { return nil }
+
+func (r *cannedRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestListIndexesLongColumnList(t *testing.T) {
+	// Enough key parts that the joined list passes GROUP_CONCAT's default
+	// 1024-byte limit.
+	var want []string
+	for i := 1; i <= 40; i++ {
+		want = append(want, fmt.Sprintf("a_rather_long_column_name_number_%02d_xyz", 41-i))
+	}
+	d := &cannedDB{columns: []string{"INDEX_NAME", "COLUMN_NAME", "UNIQUE", "INDEX_TYPE", "INDEX_COMMENT"}}
+	for _, col := range want {
+		d.rows = append(d.rows, []driver.Value{"idx_wide", col, int64(0), "BTREE", ""})
+	}
+	d.rows = append(d.rows,
+		[]driver.Value{"PRIMARY", "id", int64(1), "BTREE", ""},
+		[]driver.Value{"idx_expr", nil, int64(0), "BTREE", "functional"},
+		[]driver.Value{"idx_expr", "b", int64(0), "BTREE", "functional"},
+	)
+	db := d.open()
+	defer db.Close()
+
+	indexes, err := listIndexes(db, "shop", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.queries) != 1 || !strings.Contains(d.queries[0], "ORDER BY INDEX_NAME, SEQ_IN_INDEX") {
+		t.Fatalf("query does not order key parts by SEQ_IN_INDEX: %q", d.queries)
+	}
+	if strings.Contains(strings.ToUpper(d.queries[0]), "GROUP_CONCAT") {
+		t.Errorf("query joins the column list with GROUP_CONCAT, which truncates it")
+	}
+
+	wantIndexes := []IndexInfo{
+		{Name: "idx_wide", Columns: strings.Join(want, ","), Type: "BTREE"},
+		{Name: "PRIMARY", Columns: "id", Unique: true, Type: "BTREE"},
+		{Name: "idx_expr", Columns: "b", Type: "BTREE", Comment: "functional"},
+	}
+	if len(wantIndexes[0].Columns) <= 1024 {
+		t.Fatalf("test column list is only %d bytes", len(wantIndexes[0].Columns))
+	}
+	if !reflect.DeepEqual(indexes, wantIndexes) {
+		t.Errorf("listIndexes() = %+v, want %+v", indexes, wantIndexes)
+	}
+}
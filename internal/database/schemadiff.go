@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaObject is the CREATE statement of one object in a SchemaSnapshot.
+type SchemaObject struct {
+	Type string `json:"type"` // TABLE, VIEW, PROCEDURE, FUNCTION, or TRIGGER
+	Name string `json:"name"`
+	DDL  string `json:"ddl"`
+}
+
+// SchemaSnapshot holds the definitions of everything in a database, for
+// saving and comparing with CompareSchemas.
+type SchemaSnapshot struct {
+	Database string         `json:"database"`
+	TakenAt  time.Time      `json:"takenAt"`
+	Objects  []SchemaObject `json:"objects"`
+}
+
+// GetDatabaseDDL collects the CREATE statements of the tables, views,
+// routines, and triggers in a database, in that order.
+func GetDatabaseDDL(ctx context.Context, db *sql.DB, database string) (*SchemaSnapshot, error) {
+	tables, err := ListTables(db, database)
+	if err != nil {
+		return nil, err
+	}
+	routines, err := ListRoutines(db, database)
+	if err != nil {
+		return nil, err
+	}
+	triggers, err := ListTriggers(db, database)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &SchemaSnapshot{Database: database, TakenAt: time.Now().UTC(), Objects: []SchemaObject{}}
+	for _, t := range tables {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		obj := SchemaObject{Type: "TABLE", Name: t.Name}
+		if t.Type == "VIEW" {
+			obj.Type = "VIEW"
+			obj.DDL, err = showCreate(ctx, db, "SHOW CREATE VIEW "+qualifiedName(database, t.Name), 1)
+		} else {
+			obj.DDL, err = getCreateTable(db, database, t.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		snap.Objects = append(snap.Objects, obj)
+	}
+	for _, r := range routines {
+		ddl, err := showCreate(ctx, db, fmt.Sprintf("SHOW CREATE %s %s", r.Type, qualifiedName(database, r.Name)), 2)
+		if err != nil {
+			return nil, err
+		}
+		snap.Objects = append(snap.Objects, SchemaObject{Type: r.Type, Name: r.Name, DDL: ddl})
+	}
+	for _, t := range triggers {
+		ddl, err := showCreate(ctx, db, "SHOW CREATE TRIGGER "+qualifiedName(database, t.Name), 2)
+		if err != nil {
+			return nil, err
+		}
+		snap.Objects = append(snap.Objects, SchemaObject{Type: "TRIGGER", Name: t.Name, DDL: ddl})
+	}
+	return snap, nil
+}
+
+// SchemaChange is one object that differs between two snapshots.
+type SchemaChange struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Change string `json:"change"` // "added", "removed", or "changed"
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Diff   string `json:"diff,omitempty"` // line diff of a changed object
+}
+
+// SchemaDiff is the result of CompareSchemas.
+type SchemaDiff struct {
+	Changes   []SchemaChange `json:"changes"`
+	Unchanged int            `json:"unchanged"`
+}
+
+var (
+	autoIncrementOption = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+	definerClause       = regexp.MustCompile("DEFINER=(`[^`]*`|'[^']*'|[^@\\s]+)@(`[^`]*`|'[^']*'|\\S+)\\s+")
+)
+
+// comparableDDL drops the parts of a definition that differ between
+// servers without the schema differing: the table's next AUTO_INCREMENT
+// value and the DEFINER account.
+func comparableDDL(ddl string) string {
+	ddl = autoIncrementOption.ReplaceAllString(ddl, "")
+	return definerClause.ReplaceAllString(ddl, "")
+}
+
+// CompareSchemas reports the objects added, removed, or changed going from
+// one snapshot to another. Objects match by type and name; changed ones
+// carry a line diff of their definitions.
+func CompareSchemas(from, to *SchemaSnapshot) *SchemaDiff {
+	type key struct{ typ, name string }
+	before := make(map[key]string, len(from.Objects))
+	for _, o := range from.Objects {
+		before[key{o.Type, o.Name}] = o.DDL
+	}
+
+	diff := &SchemaDiff{Changes: []SchemaChange{}}
+	seen := make(map[key]bool, len(to.Objects))
+	for _, o := range to.Objects {
+		k := key{o.Type, o.Name}
+		seen[k] = true
+		old, ok := before[k]
+		switch {
+		case !ok:
+			diff.Changes = append(diff.Changes, SchemaChange{Type: o.Type, Name: o.Name, Change: "added", After: o.DDL})
+		case comparableDDL(old) == comparableDDL(o.DDL):
+			diff.Unchanged++
+		default:
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Type: o.Type, Name: o.Name, Change: "changed",
+				Before: old, After: o.DDL,
+				Diff: lineDiff(comparableDDL(old), comparableDDL(o.DDL)),
+			})
+		}
+	}
+	for _, o := range from.Objects {
+		if !seen[key{o.Type, o.Name}] {
+			diff.Changes = append(diff.Changes, SchemaChange{Type: o.Type, Name: o.Name, Change: "removed", Before: o.DDL})
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i], diff.Changes[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return diff
+}
+
+// lineDiff renders the differences between two texts line by line, with
+// "- " for removed lines, "+ " for added ones, and "  " for the rest.
+func lineDiff(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] is the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			out.WriteString("  " + x[i] + "\n")
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + x[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + y[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
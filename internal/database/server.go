@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ProcessInfo is one server session, as listed by SHOW FULL PROCESSLIST.
+type ProcessInfo struct {
+	ID      int64   `json:"id"`
+	User    string  `json:"user"`
+	Host    string  `json:"host"`
+	DB      *string `json:"db"`
+	Command string  `json:"command"`
+	Time    int64   `json:"time"` // seconds in the current state
+	State   *string `json:"state"`
+	Info    *string `json:"info"` // the running statement, if any
+}
+
+// ListProcesses returns the sessions on the server. Without the PROCESS
+// privilege the server only reports the account's own sessions.
+func ListProcesses(db *sql.DB) ([]ProcessInfo, error) {
+	query := `
+		SELECT ID, USER, HOST, DB, COMMAND, TIME, STATE, INFO
+		FROM INFORMATION_SCHEMA.PROCESSLIST
+		ORDER BY ID
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var procs []ProcessInfo
+	for rows.Next() {
+		var p ProcessInfo
+		if err := rows.Scan(&p.ID, &p.User, &p.Host, &p.DB, &p.Command, &p.Time, &p.State, &p.Info); err != nil {
+			return nil, err
+		}
+		procs = append(procs, p)
+	}
+	return procs, rows.Err()
+}
+
+// KillProcessQuery stops the statement a server session from the process
+// list is running, leaving the session connected.
+func KillProcessQuery(db *sql.DB, processID int64) error {
+	return explainKillError(KillQuery(db, processID), processID)
+}
+
+// KillProcessConnection terminates a server session from the process list.
+func KillProcessConnection(db *sql.DB, processID int64) error {
+	return explainKillError(KillConnection(db, processID), processID)
+}
+
+// explainKillError rewords the server's errors for a KILL of another
+// session, keeping the original error wrapped.
+func explainKillError(err error, processID int64) error {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return err
+	}
+	switch myErr.Number {
+	case 1094: // ER_NO_SUCH_THREAD
+		return fmt.Errorf("process %d no longer exists: %w", processID, err)
+	case 1095: // ER_KILL_DENIED_ERROR
+		return fmt.Errorf("not allowed to kill process %d; sessions of other accounts need CONNECTION_ADMIN or SUPER: %w", processID, err)
+	}
+	return err
+}
+
+// Variable is a server system or status variable.
+type Variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ServerVariables returns the session's system variables, filtered by a
+// LIKE pattern when one is given.
+func ServerVariables(db *sql.DB, pattern string) ([]Variable, error) {
+	return showVariables(db, "SHOW VARIABLES", pattern)
+}
+
+// ServerStatus returns the server's global status counters, filtered by a
+// LIKE pattern when one is given.
+func ServerStatus(db *sql.DB, pattern string) ([]Variable, error) {
+	return showVariables(db, "SHOW GLOBAL STATUS", pattern)
+}
+
+func showVariables(db *sql.DB, query, pattern string) ([]Variable, error) {
+	var args []interface{}
+	if pattern != "" {
+		query += " LIKE ?"
+		args = append(args, pattern)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vars []Variable
+	for rows.Next() {
+		var v Variable
+		var value sql.NullString
+		if err := rows.Scan(&v.Name, &value); err != nil {
+			return nil, err
+		}
+		v.Value = value.String
+		vars = append(vars, v)
+	}
+	return vars, rows.Err()
+}
+
+// SetVariable sets a system variable for the session, or globally when
+// global is set. Numbers and DEFAULT are passed as-is; any other value is
+// sent as a string.
+//
+// Session settings only reach the pooled connection that runs the SET, so
+// global is the useful mode for tuning.
+func SetVariable(db *sql.DB, name, value string, global bool) error {
+	if !isPluginName(name) {
+		return fmt.Errorf("invalid variable name: %s", name)
+	}
+	literal := quoteSQLString(value)
+	if _, err := strconv.ParseFloat(value, 64); err == nil || strings.EqualFold(value, "DEFAULT") {
+		literal = value
+	}
+	scope := "SESSION"
+	if global {
+		scope = "GLOBAL"
+	}
+
+	_, err := db.Exec(fmt.Sprintf("SET %s %s = %s", scope, name, literal))
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == 1227 { // ER_SPECIFIC_ACCESS_DENIED_ERROR
+		return fmt.Errorf("not allowed to set %s %s; it needs SUPER or SYSTEM_VARIABLES_ADMIN: %w", strings.ToLower(scope), name, err)
+	}
+	return err
+}
+
+// ServerInfo describes the connected server and which version-dependent
+// features it has.
+type ServerInfo struct {
+	Version        string `json:"version"`        // VERSION(), e.g. "8.0.36" or "10.11.6-MariaDB"
+	VersionComment string `json:"versionComment"` // e.g. "MySQL Community Server - GPL"
+	IsMariaDB      bool   `json:"isMariaDB"`
+
+	Features ServerFeatures `json:"features"`
+}
+
+// ServerFeatures flags capabilities that depend on the server version.
+type ServerFeatures struct {
+	Roles            bool `json:"roles"`
+	CheckConstraints bool `json:"checkConstraints"` // enforced, not just parsed
+	ExplainAnalyze   bool `json:"explainAnalyze"`
+	CTEs             bool `json:"ctes"`
+	WindowFunctions  bool `json:"windowFunctions"`
+	JSONType         bool `json:"jsonType"`
+	RenameColumn     bool `json:"renameColumn"`
+}
+
+// GetServerInfo queries the server's version and derives its features.
+func GetServerInfo(ctx context.Context, db Querier) (*ServerInfo, error) {
+	version, err := serverVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	info := &ServerInfo{Version: version, IsMariaDB: isMariaDB(version)}
+	// version_comment is informational; some proxies don't expose it.
+	db.QueryRowContext(ctx, "SELECT @@version_comment").Scan(&info.VersionComment)
+
+	v := strings.TrimPrefix(version, "5.5.5-") // MariaDB's replication-compatible prefix
+	f := &info.Features
+	if info.IsMariaDB {
+		f.Roles = versionAtLeast(v, 10, 0, 5)
+		f.CheckConstraints = versionAtLeast(v, 10, 2, 1)
+		f.CTEs = versionAtLeast(v, 10, 2, 1)
+		f.WindowFunctions = versionAtLeast(v, 10, 2, 0)
+		f.JSONType = versionAtLeast(v, 10, 2, 7)
+		f.RenameColumn = versionAtLeast(v, 10, 5, 2)
+	} else {
+		f.Roles = versionAtLeast(v, 8, 0, 0)
+		f.CheckConstraints = versionAtLeast(v, 8, 0, 16)
+		f.ExplainAnalyze = versionAtLeast(v, 8, 0, 18)
+		f.CTEs = versionAtLeast(v, 8, 0, 0)
+		f.WindowFunctions = versionAtLeast(v, 8, 0, 0)
+		f.JSONType = versionAtLeast(v, 5, 7, 8)
+		f.RenameColumn = versionAtLeast(v, 8, 0, 0)
+	}
+	return info, nil
+}
+
+// ServerInfo returns GetServerInfo for the connection, cached for its
+// lifetime.
+func (c *Connection) ServerInfo() (*ServerInfo, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if c.serverInfo == nil {
+		info, err := GetServerInfo(context.Background(), c.DB)
+		if err != nil {
+			return nil, err
+		}
+		c.serverInfo = info
+	}
+	return c.serverInfo, nil
+}
+
+func isMariaDB(version string) bool {
+	return strings.Contains(strings.ToLower(version), "mariadb")
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errSessionBusy is returned when a tab's pinned transaction connection is
+// already running a statement.
+var errSessionBusy = errors.New("a query is already running in this tab's open transaction")
+
+// Session returns the connection a tab's next batch should run on. While
+// an interactive transaction is open (BEGIN ... COMMIT spread across
+// several executions) that is the pinned connection holding it; otherwise
+// it is a fresh connection from the pool. Every Session must be handed back
+// with Release.
+func (c *Connection) Session(ctx context.Context) (*sql.Conn, error) {
+	c.sessMu.Lock()
+	defer c.sessMu.Unlock()
+
+	if c.pinned != nil {
+		if c.pinnedBusy {
+			return nil, errSessionBusy
+		}
+		c.pinnedBusy = true
+		return c.pinned, nil
+	}
+	session, err := c.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.applyDatabase(ctx, session); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// Release hands back a connection obtained from Session. stmts are the
+// statements that completed on it; if they leave a transaction open the
+// connection stays pinned to the tab, otherwise it returns to the pool.
+func (c *Connection) Release(session *sql.Conn, stmts []string) {
+	c.sessMu.Lock()
+	defer c.sessMu.Unlock()
+
+	c.NoteStatements(stmts)
+
+	open := c.pinned == session
+	for _, stmt := range stmts {
+		switch transactionEffect(stmt) {
+		case txBegin:
+			open = true
+		case txEnd:
+			open = false
+		}
+	}
+
+	// A cancelled or timed-out statement kills the connection, and the
+	// server rolls back whatever transaction it held.
+	if open && session.PingContext(context.Background()) != nil {
+		open = false
+	}
+
+	if open {
+		c.pinned = session
+		c.pinnedBusy = false
+		return
+	}
+	if c.pinned == session {
+		c.pinned = nil
+		c.pinnedBusy = false
+	}
+	session.Close()
+}
+
+// InTransaction reports whether the tab has an open pinned transaction.
+func (c *Connection) InTransaction() bool {
+	c.sessMu.Lock()
+	defer c.sessMu.Unlock()
+	return c.pinned != nil
+}
+
+// releasePinned closes a pinned connection on disconnect. The server rolls
+// back the open transaction when the session ends.
+func (c *Connection) releasePinned() {
+	c.sessMu.Lock()
+	defer c.sessMu.Unlock()
+	if c.pinned != nil {
+		c.pinned.Close()
+		c.pinned = nil
+		c.pinnedBusy = false
+	}
+}
+
+// NoteStatements updates cached state after stmts ran on one of the tab's
+// connections: schema changes invalidate completions, and a USE switches
+// the tab's current database.
+func (c *Connection) NoteStatements(stmts []string) {
+	c.NoteSchemaChanges(stmts)
+	for _, stmt := range stmts {
+		if name, ok := useTarget(stmt); ok {
+			c.setCurrentDatabase(name)
+		}
+	}
+}
+
+// CurrentDatabase returns the tab's default database: the profile's, or
+// the last one switched to with USE. "" means none is selected.
+func (c *Connection) CurrentDatabase() string {
+	c.dbMu.Lock()
+	defer c.dbMu.Unlock()
+	return c.currentDB
+}
+
+func (c *Connection) setCurrentDatabase(name string) {
+	c.dbMu.Lock()
+	changed := name != c.currentDB
+	c.currentDB = name
+	c.dbSwitched = true
+	c.dbMu.Unlock()
+
+	// Bare table names in completions resolve against the current database.
+	if changed {
+		c.InvalidateSchemaCache()
+	}
+}
+
+// UseDatabase switches the tab's current database, as running USE in the
+// editor would. The pinned connection of an open transaction switches too.
+func (c *Connection) UseDatabase(ctx context.Context, name string) error {
+	stmt := "USE " + quoteIdent(name)
+
+	c.sessMu.Lock()
+	defer c.sessMu.Unlock()
+	if c.pinned != nil {
+		if c.pinnedBusy {
+			return errSessionBusy
+		}
+		if _, err := c.pinned.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	} else if _, err := c.DB.ExecContext(ctx, stmt); err != nil {
+		return err
+	}
+	c.setCurrentDatabase(name)
+	return nil
+}
+
+// applyDatabase points a pooled connection at the tab's current database.
+// Pooled connections start in the profile's database and may have been
+// left elsewhere by an earlier batch, so once the tab has switched every
+// session re-issues USE.
+func (c *Connection) applyDatabase(ctx context.Context, session *sql.Conn) error {
+	c.dbMu.Lock()
+	name, switched := c.currentDB, c.dbSwitched
+	c.dbMu.Unlock()
+	if !switched || name == "" {
+		return nil
+	}
+	if _, err := session.ExecContext(ctx, "USE "+quoteIdent(name)); err != nil {
+		return fmt.Errorf("failed to switch to database %s: %w", name, err)
+	}
+	return nil
+}
+
+// useTarget returns the database a USE statement switches to.
+func useTarget(stmt string) (string, bool) {
+	if words := topLevelWords(stmt); len(words) == 0 || words[0] != "USE" {
+		return "", false
+	}
+	i := topLevelWordAt(stmt, "USE")
+	if i < 0 {
+		return "", false
+	}
+	rest := strings.TrimSpace(stmt[i+len("USE"):])
+	if rest == "" {
+		return "", false
+	}
+	if q := rest[0]; q == '`' || q == '"' || q == '\'' {
+		end := skipQuoted(rest, 0)
+		return unquoteIdent(rest[:end+1]), true
+	}
+	j := 0
+	for j < len(rest) && isWordChar(rest[j]) {
+		j++
+	}
+	if j == 0 {
+		return "", false
+	}
+	return rest[:j], true
+}
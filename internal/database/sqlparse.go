@@ -0,0 +1,343 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+)
+
+// topLevelWords returns the upper-cased keywords and identifiers of a
+// statement that sit outside parentheses, quotes, and comments. It is a
+// lightweight lexer, not a parser, and is meant for questions like "does
+// this SELECT already have a LIMIT?".
+func topLevelWords(query string) []string {
+	var words []string
+	depth := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "-- ")):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+			i += end + 3
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case isWordStart(c):
+			j := i
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			if depth == 0 {
+				words = append(words, strings.ToUpper(query[i:j]))
+			}
+			i = j - 1
+		}
+	}
+	return words
+}
+
+// mainStatement drops the CTE list from the top-level words of a WITH
+// statement, returning them from the main statement's keyword on. The CTE
+// bodies are parenthesized, so that is the first statement keyword at the
+// top level. Words of other statements are returned as they are.
+func mainStatement(words []string) []string {
+	if len(words) == 0 || words[0] != "WITH" {
+		return words
+	}
+	for i, w := range words {
+		switch w {
+		case "SELECT", "TABLE", "VALUES", "UPDATE", "DELETE", "INSERT", "REPLACE":
+			return words[i:]
+		}
+	}
+	return nil
+}
+
+// skipQuoted returns the index of the closing quote for the quoted string
+// or identifier starting at query[start].
+func skipQuoted(query string, start int) int {
+	q := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case query[i] == '\\' && q != '`':
+			i++
+		case query[i] == q:
+			if i+1 < len(query) && query[i+1] == q {
+				i++ // doubled quote
+				continue
+			}
+			return i
+		}
+	}
+	return len(query) - 1
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || c == '$' || (c >= '0' && c <= '9')
+}
+
+// Effects a statement can have on an interactive transaction.
+const (
+	txNone = iota
+	txBegin
+	txEnd
+)
+
+// transactionEffect reports whether stmt opens or closes a transaction.
+// Statements that commit implicitly also close one.
+func transactionEffect(stmt string) int {
+	words := topLevelWords(stmt)
+	if len(words) == 0 {
+		return txNone
+	}
+	switch words[0] {
+	case "BEGIN":
+		return txBegin
+	case "START":
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			return txBegin
+		}
+	case "COMMIT":
+		return txEnd
+	case "ROLLBACK":
+		// ROLLBACK TO SAVEPOINT keeps the transaction open.
+		if len(words) > 1 && words[1] == "TO" {
+			return txNone
+		}
+		return txEnd
+	}
+	if causesImplicitCommit(stmt) {
+		return txEnd
+	}
+	return txNone
+}
+
+// causesImplicitCommit reports whether MySQL implicitly commits the current
+// transaction when running stmt (DDL, account management, table locks, ...).
+func causesImplicitCommit(stmt string) bool {
+	words := topLevelWords(stmt)
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "ALTER", "RENAME", "TRUNCATE", "GRANT", "REVOKE", "LOCK", "UNLOCK",
+		"BEGIN", "START", "ANALYZE", "OPTIMIZE", "REPAIR", "CACHE", "FLUSH", "INSTALL", "UNINSTALL":
+		return true
+	case "CREATE", "DROP":
+		// Temporary tables are the exception.
+		return len(words) < 2 || words[1] != "TEMPORARY"
+	case "SET":
+		return len(words) > 1 && words[1] == "PASSWORD"
+	case "CHECK":
+		return len(words) > 1 && words[1] == "TABLE"
+	}
+	return false
+}
+
+// callUserVariables returns the arguments of a CALL that are plain user
+// variables, such as @total or @`order count`, in order and without
+// repeats. Those are how OUT and INOUT parameters hand values back.
+func callUserVariables(stmt string) []string {
+	open := strings.IndexByte(stmt, '(')
+	if open < 0 {
+		return nil
+	}
+
+	var args []string
+	depth, argStart := 0, open+1
+	for i := open + 1; i < len(stmt) && depth >= 0; i++ {
+		switch stmt[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(stmt, i)
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				args = append(args, stmt[argStart:i])
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, stmt[argStart:i])
+				argStart = i + 1
+			}
+		}
+	}
+
+	var vars []string
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if !userVariable.MatchString(arg) || seen[arg] {
+			continue
+		}
+		seen[arg] = true
+		vars = append(vars, arg)
+	}
+	return vars
+}
+
+// userVariable matches a user variable reference, but not @@ system
+// variables.
+var userVariable = regexp.MustCompile("^@([A-Za-z0-9_$.]+|`[^`]+`|'[^']+'|\"[^\"]+\")$")
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// stmtSplitter splits SQL text into statements on the active delimiter,
+// following mysql client DELIMITER directives so routine and trigger
+// bodies with internal semicolons stay whole. Delimiters inside quotes and
+// comments are ignored, and comments before a statement are dropped so it
+// still starts with its keyword. Feed it one line at a time.
+type stmtSplitter struct {
+	delim   string
+	batch   string // a line holding only this word (e.g. GO) also ends a statement
+	buf     strings.Builder
+	quote   byte // open quote character, or 0
+	comment bool // inside a /* */ block comment
+	hasCode bool // the current statement has more than comments
+}
+
+func newStmtSplitter() *stmtSplitter {
+	return &stmtSplitter{delim: ";"}
+}
+
+// feedLine consumes one line (without its newline) and returns the
+// statements it completed, without their delimiters.
+func (s *stmtSplitter) feedLine(line string) []string {
+	if s.quote == 0 && !s.comment {
+		if s.batch != "" && strings.EqualFold(strings.TrimSpace(line), s.batch) {
+			if stmt := s.flush(); stmt != "" {
+				return []string{stmt}
+			}
+			return nil
+		}
+		if !s.hasCode {
+			if delim, ok := parseDelimiter(line); ok {
+				s.delim = delim
+				s.buf.Reset()
+				return nil
+			}
+		}
+	}
+
+	var stmts []string
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		if s.comment {
+			if strings.HasPrefix(line[i:], "*/") {
+				s.comment = false
+				s.write("*/")
+				i++
+			} else {
+				s.write(line[i : i+1])
+			}
+			continue
+		}
+
+		if s.quote != 0 {
+			s.buf.WriteByte(c)
+			if c == '\\' && s.quote != '`' && i+1 < len(line) {
+				i++
+				s.buf.WriteByte(line[i])
+			} else if c == s.quote {
+				s.quote = 0
+			}
+			continue
+		}
+
+		if c == '#' || isDashComment(line[i:]) {
+			s.write(line[i:])
+			break
+		}
+
+		if strings.HasPrefix(line[i:], "/*") {
+			// /*! ... */ is a versioned comment the server executes.
+			if strings.HasPrefix(line[i:], "/*!") {
+				s.hasCode = true
+			}
+			s.comment = true
+			s.write("/*")
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(line[i:], s.delim) {
+			if stmt := strings.TrimSpace(s.buf.String()); stmt != "" && s.hasCode {
+				stmts = append(stmts, stmt)
+			}
+			s.buf.Reset()
+			s.hasCode = false
+			i += len(s.delim) - 1
+			continue
+		}
+
+		if c == '\'' || c == '"' || c == '`' {
+			s.quote = c
+		}
+		if c != ' ' && c != '\t' && c != '\r' {
+			s.hasCode = true
+		}
+		s.write(line[i : i+1])
+	}
+	s.write("\n")
+	return stmts
+}
+
+// write appends text to the current statement, dropping comments and
+// blank lines that come before its first token.
+func (s *stmtSplitter) write(text string) {
+	if s.hasCode {
+		s.buf.WriteString(text)
+	}
+}
+
+// flush returns the trailing statement that had no delimiter, if any.
+func (s *stmtSplitter) flush() string {
+	stmt := strings.TrimSpace(s.buf.String())
+	s.buf.Reset()
+	s.hasCode = false
+	return stmt
+}
+
+// isDashComment reports whether text starts a "-- " comment. MySQL needs
+// whitespace or a control character after the dashes, so "1--1" is math.
+func isDashComment(text string) bool {
+	if !strings.HasPrefix(text, "--") {
+		return false
+	}
+	return len(text) == 2 || text[2] <= ' '
+}
+
+// parseDelimiter recognizes a "DELIMITER $$" directive line.
+func parseDelimiter(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "DELIMITER") {
+		return "", false
+	}
+	return fields[1], true
+}
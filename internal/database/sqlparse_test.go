@@ -0,0 +1,92 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"single", "SELECT 1", []string{"SELECT 1"}},
+		{"one line", "SELECT 1; SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"trailing delimiter", "SELECT 1;\nSELECT 2;\n", []string{"SELECT 1", "SELECT 2"}},
+		{"empty statements", "  \n;;\n", nil},
+		{"multi-line statement", "SELECT a,\n  b\nFROM t;", []string{"SELECT a,\n  b\nFROM t"}},
+		{
+			"procedure body",
+			"DELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND //\nDELIMITER ;\nCALL p();",
+			[]string{"CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND", "CALL p()"},
+		},
+		{
+			"trigger body",
+			"DELIMITER $$\nCREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET NEW.a = 1; END$$\nSELECT 1$$\nDELIMITER ;\nSELECT 2;",
+			[]string{"CREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET NEW.a = 1; END", "SELECT 1", "SELECT 2"},
+		},
+		{"lower-case directive", "delimiter //\nSELECT 1//", []string{"SELECT 1"}},
+		{"no delimiter at end", "DELIMITER //\nSELECT 1", []string{"SELECT 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.sql); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatementsCommentsAndQuotes(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"block comment", "SELECT 1 /* ; */; SELECT 2", []string{"SELECT 1 /* ; */", "SELECT 2"}},
+		{"multi-line block comment", "SELECT 1 /* multi\nline ; */; SELECT 2", []string{"SELECT 1 /* multi\nline ; */", "SELECT 2"}},
+		{"nested-looking block comment", "SELECT 1 /* a /* b */; SELECT 2", []string{"SELECT 1 /* a /* b */", "SELECT 2"}},
+		{"block comment after delimiter", "SELECT 1;/* c */SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"dash comment after delimiter", "SELECT 1;-- c\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"hash comment after delimiter", "SELECT 1;# c\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"dash comment", "SELECT 1 -- ;\n; SELECT 2", []string{"SELECT 1 -- ;", "SELECT 2"}},
+		{"hash comment", "SELECT 1 # ;\n; SELECT 2", []string{"SELECT 1 # ;", "SELECT 2"}},
+		{"double dash without space", "SELECT 1--1; SELECT 2", []string{"SELECT 1--1", "SELECT 2"}},
+		{"versioned comment", "SELECT /*!40101 1; */ 2; SELECT 3", []string{"SELECT /*!40101 1; */ 2", "SELECT 3"}},
+		{"leading versioned comment", "/*!40101 SET NAMES utf8 */;", []string{"/*!40101 SET NAMES utf8 */"}},
+		{"leading comments dropped", "-- header\n/* note */\nSELECT 1", []string{"SELECT 1"}},
+		{"comment-only statement", "SELECT 1; -- done\n", []string{"SELECT 1"}},
+		{"quotes", "SELECT ';', \"a;b\", `c;d` FROM t; SELECT 2", []string{"SELECT ';', \"a;b\", `c;d` FROM t", "SELECT 2"}},
+		{"doubled quote", "SELECT 'it''s;'; SELECT 2", []string{"SELECT 'it''s;'", "SELECT 2"}},
+		{"escaped quote", `SELECT 'a\';b'; SELECT 2`, []string{`SELECT 'a\';b'`, "SELECT 2"}},
+		{"multi-line string", "SELECT 'multi\nline ; string'; SELECT 2", []string{"SELECT 'multi\nline ; string'", "SELECT 2"}},
+		{"comment markers in quotes", "SELECT '-- x; /*'; SELECT 2", []string{"SELECT '-- x; /*'", "SELECT 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.sql); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopLevelWordsSkipsComments(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT a /* LIMIT */ FROM t", []string{"SELECT", "A", "FROM", "T"}},
+		{"SELECT a -- LIMIT\nFROM t", []string{"SELECT", "A", "FROM", "T"}},
+		{"SELECT a # LIMIT\nFROM t", []string{"SELECT", "A", "FROM", "T"}},
+		{"SELECT 1--1 LIMIT 1", []string{"SELECT", "LIMIT"}},
+		{"SELECT 'LIMIT', `limit` FROM t", []string{"SELECT", "FROM", "T"}},
+		{"SELECT (SELECT b LIMIT 1) FROM t", []string{"SELECT", "FROM", "T"}},
+	}
+	for _, tt := range tests {
+		if got := topLevelWords(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("topLevelWords(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// sshTunnel is an SSH client used to reach a MySQL server that isn't
+// directly reachable. Connections are dialed through it via a custom
+// network registered with the mysql driver.
+type sshTunnel struct {
+	client  *ssh.Client
+	netName string
+}
+
+// sshError marks a failure to reach or log in to the SSH host, as opposed
+// to the MySQL server behind it.
+type sshError struct{ err error }
+
+func (e *sshError) Error() string { return e.err.Error() }
+func (e *sshError) Unwrap() error { return e.err }
+
+// openSSHTunnel connects and authenticates to the SSH host in cfg and
+// registers a dialer for the tunnel under netName. The host's key must be
+// in ~/.ssh/known_hosts or cfg.SSHHostKeys; see hostKeyVerifier.
+func openSSHTunnel(cfg ConnConfig, netName string) (*sshTunnel, error) {
+	auth, keyErr, closeAgent, err := sshAuthMethod(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAgent()
+
+	port := cfg.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(cfg.SSHHost, fmt.Sprintf("%d", port))
+
+	hostKeys, err := newHostKeyVerifier(cfg, addr)
+	if err != nil {
+		return nil, err
+	}
+	clientCfg := &ssh.ClientConfig{
+		User:              cfg.SSHUser,
+		Auth:              []ssh.AuthMethod{auth},
+		HostKeyCallback:   hostKeys.check,
+		HostKeyAlgorithms: hostKeys.algorithms(),
+		Timeout:           cfg.connectTimeout(),
+	}
+
+	client, err := ssh.Dial("tcp", addr, clientCfg)
+	if err != nil {
+		if keyErr != nil {
+			// The agent stood in for the key; say why the key wasn't used.
+			return nil, fmt.Errorf("SSH connection to %s failed using the SSH agent (%v): %w", addr, keyErr, err)
+		}
+		return nil, fmt.Errorf("SSH connection to %s failed: %w", addr, err)
+	}
+
+	t := &sshTunnel{client: client, netName: netName}
+	mysql.RegisterDialContext(netName, func(ctx context.Context, addr string) (net.Conn, error) {
+		return t.client.DialContext(ctx, "tcp", addr)
+	})
+	return t, nil
+}
+
+// Close tears down the SSH client and deregisters the tunnel's dialer.
+func (t *sshTunnel) Close() error {
+	if t == nil {
+		return nil
+	}
+	mysql.DeregisterDialContext(t.netName)
+	return t.client.Close()
+}
+
+// sshAuthMethod picks how to log in to the SSH host. In key mode it uses
+// the profile's key file, falling back to the keys of a running ssh-agent
+// (SSH_AUTH_SOCK) when no key file is set, the file is missing, or the key
+// is encrypted and no passphrase was given; keyErr then says why the key
+// wasn't used. closeAgent releases the agent once the login is done.
+func sshAuthMethod(cfg ConnConfig) (auth ssh.AuthMethod, keyErr error, closeAgent func(), err error) {
+	noop := func() {}
+	switch cfg.SSHAuth {
+	case "password":
+		return ssh.Password(cfg.SSHPass), nil, noop, nil
+	case "key", "":
+		signer, loadErr := sshKeySigner(cfg)
+		if loadErr == nil {
+			return ssh.PublicKeys(signer), nil, noop, nil
+		}
+		var missing *ssh.PassphraseMissingError
+		if cfg.SSHKeyPath != "" && !errors.Is(loadErr, fs.ErrNotExist) && !errors.As(loadErr, &missing) {
+			return nil, nil, noop, loadErr
+		}
+		ag, conn, agentErr := sshAgent()
+		if agentErr != nil {
+			if cfg.SSHKeyPath == "" {
+				return nil, nil, noop, fmt.Errorf("SSH key path is required for key authentication when no SSH agent is available: %w", agentErr)
+			}
+			return nil, nil, noop, loadErr
+		}
+		return ssh.PublicKeysCallback(ag.Signers), loadErr, func() { conn.Close() }, nil
+	default:
+		return nil, nil, noop, fmt.Errorf("unsupported SSH auth method: %s", cfg.SSHAuth)
+	}
+}
+
+// sshKeySigner loads the profile's private key, decrypting it with
+// SSHPassphrase, or SSHPass for profiles saved before the passphrase had
+// its own field.
+func sshKeySigner(cfg ConnConfig) (ssh.Signer, error) {
+	if cfg.SSHKeyPath == "" {
+		return nil, errors.New("no SSH key path is set")
+	}
+	path := expandHome(cfg.SSHKeyPath)
+	pemBytes, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("SSH key file %s does not exist: %w", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH key %s: %w", path, err)
+		}
+		return signer, nil
+	}
+
+	passphrase := cfg.SSHPassphrase
+	if passphrase == "" {
+		passphrase = cfg.SSHPass
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("SSH key %s is encrypted and no passphrase is set: %w", path, err)
+	}
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, fmt.Errorf("wrong passphrase for SSH key %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt SSH key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// sshAgent connects to the ssh-agent named by SSH_AUTH_SOCK.
+func sshAgent() (agent.ExtendedAgent, net.Conn, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reach the SSH agent: %w", err)
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
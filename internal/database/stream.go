@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// defaultStreamChunk is the number of rows per chunk when the caller
+// doesn't pick one.
+const defaultStreamChunk = 1000
+
+// RowChunk is a slice of a streamed result. The first chunk also carries
+// the column names.
+type RowChunk struct {
+	Columns        []string   `json:"columns,omitempty"`
+	Offset         int        `json:"offset"` // index of the chunk's first row in the result
+	Rows           [][]string `json:"rows"`
+	TruncatedCells [][2]int   `json:"truncatedCells,omitempty"` // [row, column], row relative to Offset
+	NullCells      [][2]int   `json:"nullCells,omitempty"`      // as TruncatedCells
+}
+
+// StreamQuery runs a single SELECT and hands its rows to emit in chunks of
+// chunkSize instead of collecting them, so a large result never sits in
+// memory whole. The returned result has the columns and totals but no
+// rows. An error from emit stops the query; cancelling ctx does too.
+// Statements that don't return rows run as in ExecuteQuery.
+func StreamQuery(ctx context.Context, db Querier, query string, chunkSize int, opts ExecOptions, emit func(RowChunk) error) *QueryResult {
+	stmts := splitStatements(query)
+	if len(stmts) > 1 {
+		return &QueryResult{Error: "only a single statement can be streamed"}
+	}
+	if len(stmts) == 1 {
+		query = stmts[0]
+	}
+	query = strings.TrimSpace(query)
+	if !isSelectQuery(query) || opts.ReadOnly && !readOnlyAllowed(query) {
+		return ExecuteQuery(ctx, db, query, opts)
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultStreamChunk
+	}
+
+	start := time.Now()
+	result := &QueryResult{IsSelect: true}
+	defer func() { result.Duration = time.Since(start).String() }()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		result.setError(err)
+		return result
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		result.setError(err)
+		return result
+	}
+	colTypes, _ := rows.ColumnTypes()
+	result.Columns = cols
+	scanner := newGridScanner(colTypes, len(cols), opts.MaxCellLength)
+
+	chunk := RowChunk{Columns: cols}
+	flush := func() error {
+		if len(chunk.Rows) == 0 && result.RowCount > 0 {
+			return nil
+		}
+		err := emit(chunk)
+		chunk = RowChunk{Offset: result.RowCount}
+		return err
+	}
+
+	for rows.Next() {
+		row, cut, nulls, err := scanner.scan(rows)
+		if err != nil {
+			result.setError(err)
+			break
+		}
+		for _, i := range cut {
+			chunk.TruncatedCells = append(chunk.TruncatedCells, [2]int{len(chunk.Rows), i})
+		}
+		for _, i := range nulls {
+			chunk.NullCells = append(chunk.NullCells, [2]int{len(chunk.Rows), i})
+		}
+		chunk.Rows = append(chunk.Rows, row)
+		result.RowCount++
+		if len(chunk.Rows) == chunkSize {
+			if err := flush(); err != nil {
+				result.setError(err)
+				return result
+			}
+		}
+	}
+	if err := rows.Err(); err != nil && result.Error == "" {
+		result.setError(err)
+	}
+	if err := flush(); err != nil && result.Error == "" {
+		result.setError(err)
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SSL modes accepted in ConnConfig.SSLMode.
+const (
+	SSLDisable    = "disable"
+	SSLRequire    = "require"     // encrypt, but don't verify the server certificate
+	SSLVerifyCA   = "verify-ca"   // verify the certificate chain only
+	SSLVerifyFull = "verify-full" // verify the chain and the hostname
+)
+
+// sslMode resolves the effective SSL mode, treating a bare UseSSL flag
+// from older profiles as "require".
+func (cfg ConnConfig) sslMode() string {
+	if cfg.SSLMode != "" {
+		return cfg.SSLMode
+	}
+	if cfg.UseSSL {
+		return SSLRequire
+	}
+	return SSLDisable
+}
+
+// buildTLSConfig returns the TLS settings for cfg, or nil when SSL is disabled.
+func buildTLSConfig(cfg ConnConfig) (*tls.Config, error) {
+	tlsCfg, err := serverVerification(cfg)
+	if err != nil || tlsCfg == nil {
+		return tlsCfg, err
+	}
+
+	if cfg.SSLCertPath != "" || cfg.SSLKeyPath != "" {
+		cert, err := loadClientCert(cfg.SSLCertPath, cfg.SSLKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// serverVerification returns a TLS config that checks the server
+// certificate as strictly as the SSL mode asks for.
+func serverVerification(cfg ConnConfig) (*tls.Config, error) {
+	mode := cfg.sslMode()
+	switch mode {
+	case SSLDisable:
+		return nil, nil
+	case SSLRequire:
+		// DO managed DBs use self-signed certs
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	case SSLVerifyCA, SSLVerifyFull:
+	default:
+		return nil, fmt.Errorf("unknown SSL mode: %s", mode)
+	}
+
+	roots, err := loadRootCAs(cfg.SSLCAPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == SSLVerifyFull {
+		return &tls.Config{RootCAs: roots, ServerName: cfg.Host}, nil
+	}
+
+	// verify-ca: check the chain ourselves and skip the hostname check.
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		},
+	}, nil
+}
+
+// loadClientCert reads a PEM certificate and private key for mutual TLS.
+func loadClientCert(certPath, keyPath string) (tls.Certificate, error) {
+	if certPath == "" || keyPath == "" {
+		return tls.Certificate{}, errors.New("client certificate and key must both be set")
+	}
+
+	certPEM, err := os.ReadFile(expandHome(certPath))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(expandHome(keyPath))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client key: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("client certificate %s and key %s are not a valid pair: %w", certPath, keyPath, err)
+	}
+	return cert, nil
+}
+
+// loadRootCAs reads a PEM CA bundle, falling back to the system pool when
+// no path is given.
+func loadRootCAs(path string) (*x509.CertPool, error) {
+	if path == "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system CA certificates: %w", err)
+		}
+		return pool, nil
+	}
+
+	pemBytes, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("no valid certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -18,15 +18,122 @@ type UserDetail struct {
 	User   string   `json:"user"`
 	Host   string   `json:"host"`
 	Plugin string   `json:"plugin"`
-	Grants []string `json:"grants"`
+	Grants []string `json:"grants"` // raw SHOW GRANTS lines
+
+	// ParsedGrants holds Grants broken down by privilege and target.
+	ParsedGrants []Grant `json:"parsedGrants"`
+
+	// Account status; left zero on servers whose mysql.user lacks the
+	// columns (MySQL before 5.7.6).
+	AccountLocked    bool `json:"accountLocked"`
+	PasswordExpired  bool `json:"passwordExpired"`
+	PasswordLifetime *int `json:"passwordLifetime"` // days; nil means the server default
+
+	Resources UserResources `json:"resources"`
+}
+
+// Values for UserResources.Require.
+const (
+	RequireNone = "NONE"
+	RequireSSL  = "SSL"
+	RequireX509 = "X509"
+)
+
+// UserResources holds an account's per-hour limits and TLS requirement.
+// Zero limits mean unlimited.
+type UserResources struct {
+	MaxQueriesPerHour     int64 `json:"maxQueriesPerHour"`
+	MaxUpdatesPerHour     int64 `json:"maxUpdatesPerHour"`
+	MaxConnectionsPerHour int64 `json:"maxConnectionsPerHour"`
+	MaxUserConnections    int64 `json:"maxUserConnections"`
+
+	// Require is "NONE", "SSL", or "X509"; "" leaves it unchanged. GetUserDetail
+	// reports "SPECIFIED" for cipher/issuer/subject requirements set elsewhere.
+	Require string `json:"require"`
+}
+
+// maxResourceLimit is the largest value MySQL stores for a resource limit.
+const maxResourceLimit = 1<<32 - 1
+
+// clause renders the REQUIRE and WITH parts of CREATE/ALTER USER.
+func (r UserResources) clause() (string, error) {
+	limits := []struct {
+		name string
+		n    int64
+	}{
+		{"MAX_QUERIES_PER_HOUR", r.MaxQueriesPerHour},
+		{"MAX_UPDATES_PER_HOUR", r.MaxUpdatesPerHour},
+		{"MAX_CONNECTIONS_PER_HOUR", r.MaxConnectionsPerHour},
+		{"MAX_USER_CONNECTIONS", r.MaxUserConnections},
+	}
+
+	var b strings.Builder
+	switch strings.ToUpper(r.Require) {
+	case "":
+	case RequireNone, RequireSSL, RequireX509:
+		b.WriteString(" REQUIRE " + strings.ToUpper(r.Require))
+	default:
+		return "", fmt.Errorf("unsupported REQUIRE option: %s", r.Require)
+	}
+
+	b.WriteString(" WITH")
+	for _, l := range limits {
+		if l.n < 0 || l.n > maxResourceLimit {
+			return "", fmt.Errorf("%s must be between 0 and %d, got %d", l.name, int64(maxResourceLimit), l.n)
+		}
+		fmt.Fprintf(&b, " %s %d", l.name, l.n)
+	}
+	return b.String(), nil
+}
+
+// PrivilegeInfo describes a privilege the server supports, as listed by
+// SHOW PRIVILEGES.
+type PrivilegeInfo struct {
+	Name        string `json:"name"`
+	Context     string `json:"context"` // e.g. "Tables", "Databases,Tables", "Server Admin"
+	Description string `json:"description"`
+}
+
+// ListAvailablePrivileges returns the privileges the server supports.
+func ListAvailablePrivileges(db *sql.DB) ([]PrivilegeInfo, error) {
+	rows, err := db.Query("SHOW PRIVILEGES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var privs []PrivilegeInfo
+	for rows.Next() {
+		var p PrivilegeInfo
+		if err := rows.Scan(&p.Name, &p.Context, &p.Description); err != nil {
+			return nil, err
+		}
+		privs = append(privs, p)
+	}
+	return privs, rows.Err()
+}
+
+// AvailablePrivileges returns ListAvailablePrivileges for the connection,
+// cached for its lifetime.
+func (c *Connection) AvailablePrivileges() ([]PrivilegeInfo, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if c.privileges == nil {
+		privs, err := ListAvailablePrivileges(c.DB)
+		if err != nil {
+			return nil, err
+		}
+		c.privileges = privs
+	}
+	return c.privileges, nil
 }
 
-// ListUsers returns all MySQL users.
+// ListUsers returns all MySQL users, by user name and then host (see
+// SortUsers).
 func ListUsers(db *sql.DB) ([]UserInfo, error) {
 	rows, err := db.Query(`
 		SELECT User, Host, IFNULL(plugin, '')
 		FROM mysql.user
-		ORDER BY User, Host
 	`)
 	if err != nil {
 		return nil, err
@@ -41,24 +148,71 @@ func ListUsers(db *sql.DB) ([]UserInfo, error) {
 		}
 		users = append(users, u)
 	}
-	return users, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortUsers(users, OrderBinary)
+	return users, nil
 }
 
 // GetUserDetail returns a user's full info including grants.
 func GetUserDetail(db *sql.DB, user, host string) (*UserDetail, error) {
 	detail := &UserDetail{User: user, Host: host}
 
-	// Get plugin
-	err := db.QueryRow(
-		"SELECT IFNULL(plugin, '') FROM mysql.user WHERE User = ? AND Host = ?",
+	// Get plugin and account status
+	var locked, expired string
+	var lifetime sql.NullInt64
+	err := db.QueryRow(`
+		SELECT IFNULL(plugin, ''), IFNULL(account_locked, 'N'),
+		       IFNULL(password_expired, 'N'), password_lifetime
+		FROM mysql.user WHERE User = ? AND Host = ?`,
 		user, host,
-	).Scan(&detail.Plugin)
-	if err != nil {
+	).Scan(&detail.Plugin, &locked, &expired, &lifetime)
+	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	if err != nil {
+		// Older servers lack the status columns.
+		err = db.QueryRow(
+			"SELECT IFNULL(plugin, '') FROM mysql.user WHERE User = ? AND Host = ?",
+			user, host,
+		).Scan(&detail.Plugin)
+		if err != nil {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+	}
+	detail.AccountLocked = locked == "Y"
+	detail.PasswordExpired = expired == "Y"
+	if lifetime.Valid {
+		days := int(lifetime.Int64)
+		detail.PasswordLifetime = &days
+	}
+
+	var sslType string
+	res := &detail.Resources
+	err = db.QueryRow(`
+		SELECT max_questions, max_updates, max_connections, max_user_connections, ssl_type
+		FROM mysql.user WHERE User = ? AND Host = ?`,
+		user, host,
+	).Scan(&res.MaxQueriesPerHour, &res.MaxUpdatesPerHour, &res.MaxConnectionsPerHour, &res.MaxUserConnections, &sslType)
+	if err == nil {
+		switch sslType {
+		case "":
+			res.Require = RequireNone
+		case "ANY":
+			res.Require = RequireSSL
+		default: // X509, SPECIFIED
+			res.Require = sslType
+		}
+	}
+
+	account, err := accountName(user, host)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get grants
-	rows, err := db.Query(fmt.Sprintf("SHOW GRANTS FOR '%s'@'%s'", user, host))
+	rows, err := db.Query("SHOW GRANTS FOR " + account)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get grants: %w", err)
 	}
@@ -70,42 +224,113 @@ func GetUserDetail(db *sql.DB, user, host string) (*UserDetail, error) {
 			return nil, err
 		}
 		detail.Grants = append(detail.Grants, grant)
+		detail.ParsedGrants = append(detail.ParsedGrants, ParseGrant(grant))
 	}
 
 	return detail, rows.Err()
 }
 
-// CreateUser creates a new MySQL user.
-func CreateUser(db *sql.DB, user, host, password, plugin string) error {
+// CreateUser creates a new MySQL user with the given limits and TLS
+// requirement.
+func CreateUser(db *sql.DB, user, host, password, plugin string, res UserResources) error {
 	if host == "" {
 		host = "%"
 	}
 	if plugin == "" {
 		plugin = "caching_sha2_password"
 	}
+	if !isPluginName(plugin) {
+		return fmt.Errorf("invalid authentication plugin: %q", plugin)
+	}
+	account, err := accountName(user, host)
+	if err != nil {
+		return err
+	}
+
+	resClause, err := res.clause()
+	if err != nil {
+		return err
+	}
 
 	query := fmt.Sprintf(
-		"CREATE USER '%s'@'%s' IDENTIFIED WITH %s BY '%s'",
-		escapeQuote(user), escapeQuote(host), plugin, escapeQuote(password),
+		"CREATE USER %s IDENTIFIED WITH %s BY %s%s",
+		account, plugin, quoteSQLString(password), resClause,
 	)
-	_, err := db.Exec(query)
+	_, err = db.Exec(query)
 	return err
 }
 
 // DropUser drops a MySQL user.
 func DropUser(db *sql.DB, user, host string) error {
-	query := fmt.Sprintf("DROP USER '%s'@'%s'", escapeQuote(user), escapeQuote(host))
-	_, err := db.Exec(query)
+	account, err := accountName(user, host)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DROP USER " + account)
 	return err
 }
 
 // ChangePassword changes a user's password.
 func ChangePassword(db *sql.DB, user, host, newPassword string) error {
-	query := fmt.Sprintf(
-		"ALTER USER '%s'@'%s' IDENTIFIED BY '%s'",
-		escapeQuote(user), escapeQuote(host), escapeQuote(newPassword),
-	)
-	_, err := db.Exec(query)
+	return alterUser(db, user, host, "IDENTIFIED BY "+quoteSQLString(newPassword))
+}
+
+// AlterUserResources replaces an account's resource limits and, unless
+// res.Require is empty, its TLS requirement.
+func AlterUserResources(db *sql.DB, user, host string, res UserResources) error {
+	clause, err := res.clause()
+	if err != nil {
+		return err
+	}
+	return alterUser(db, user, host, strings.TrimSpace(clause))
+}
+
+// LockUser locks an account so it can no longer log in.
+func LockUser(db *sql.DB, user, host string) error {
+	return alterUser(db, user, host, "ACCOUNT LOCK")
+}
+
+// UnlockUser unlocks an account locked with LockUser.
+func UnlockUser(db *sql.DB, user, host string) error {
+	return alterUser(db, user, host, "ACCOUNT UNLOCK")
+}
+
+// Password expiry policies accepted by SetPasswordExpiry.
+const (
+	ExpireNow      = "now"      // expire the current password immediately
+	ExpireInterval = "interval" // expire every n days
+	ExpireNever    = "never"
+	ExpireDefault  = "default" // follow default_password_lifetime
+)
+
+// SetPasswordExpiry sets an account's password expiry policy. days is only
+// used with ExpireInterval.
+func SetPasswordExpiry(db *sql.DB, user, host, policy string, days int) error {
+	var clause string
+	switch policy {
+	case ExpireNow:
+		clause = "PASSWORD EXPIRE"
+	case ExpireInterval:
+		if days < 1 || days > 65535 {
+			return fmt.Errorf("password expiry interval must be between 1 and 65535 days, got %d", days)
+		}
+		clause = fmt.Sprintf("PASSWORD EXPIRE INTERVAL %d DAY", days)
+	case ExpireNever:
+		clause = "PASSWORD EXPIRE NEVER"
+	case ExpireDefault:
+		clause = "PASSWORD EXPIRE DEFAULT"
+	default:
+		return fmt.Errorf("unknown password expiry policy: %s", policy)
+	}
+	return alterUser(db, user, host, clause)
+}
+
+func alterUser(db *sql.DB, user, host, clause string) error {
+	account, err := accountName(user, host)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("ALTER USER " + account + " " + clause)
 	return err
 }
 
@@ -114,14 +339,20 @@ func GrantPrivileges(db *sql.DB, user, host, privileges, on string) error {
 	if on == "" {
 		on = "*.*"
 	}
-	query := fmt.Sprintf(
-		"GRANT %s ON %s TO '%s'@'%s'",
-		privileges, on, escapeQuote(user), escapeQuote(host),
-	)
-	_, err := db.Exec(query)
+	if err := checkGrantFragment("privileges", privileges); err != nil {
+		return err
+	}
+	if err := checkGrantFragment("grant target", on); err != nil {
+		return err
+	}
+	account, err := accountName(user, host)
 	if err != nil {
 		return err
 	}
+	query := fmt.Sprintf("GRANT %s ON %s TO %s", privileges, on, account)
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
 	_, err = db.Exec("FLUSH PRIVILEGES")
 	return err
 }
@@ -131,18 +362,68 @@ func RevokePrivileges(db *sql.DB, user, host, privileges, on string) error {
 	if on == "" {
 		on = "*.*"
 	}
-	query := fmt.Sprintf(
-		"REVOKE %s ON %s FROM '%s'@'%s'",
-		privileges, on, escapeQuote(user), escapeQuote(host),
-	)
-	_, err := db.Exec(query)
+	if err := checkGrantFragment("privileges", privileges); err != nil {
+		return err
+	}
+	if err := checkGrantFragment("grant target", on); err != nil {
+		return err
+	}
+	account, err := accountName(user, host)
 	if err != nil {
 		return err
 	}
+	query := fmt.Sprintf("REVOKE %s ON %s FROM %s", privileges, on, account)
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
 	_, err = db.Exec("FLUSH PRIVILEGES")
 	return err
 }
 
-func escapeQuote(s string) string {
-	return strings.ReplaceAll(s, "'", "\\'")
+// accountName renders user@host as a quoted MySQL account name, rejecting
+// hosts that can't be a hostname, IP address, netmask, or wildcard pattern.
+func accountName(user, host string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("host must not be empty (use %% for any host)")
+	}
+	for _, c := range host {
+		if !(c == '.' || c == '-' || c == '_' || c == '%' || c == ':' || c == '/' ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return "", fmt.Errorf("invalid character %q in host %q", c, host)
+		}
+	}
+	if strings.ContainsRune(user, 0) {
+		return "", fmt.Errorf("invalid user name %q", user)
+	}
+	return quoteSQLString(user) + "@" + quoteSQLString(host), nil
+}
+
+func isPluginName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordChar(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// checkGrantFragment rejects privilege lists and grant targets that could
+// smuggle in more SQL: quotes, comments, or statement separators outside
+// backtick-quoted identifiers.
+func checkGrantFragment(what, s string) error {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '`':
+			end := skipQuoted(s, i)
+			if end == i || s[end] != '`' {
+				return fmt.Errorf("unterminated identifier in %s: %s", what, s)
+			}
+			i = end
+		case c == ';' || c == '\'' || c == '"' || c == '#' || c == '\\' || c == 0,
+			c == '-' && strings.HasPrefix(s[i:], "--"),
+			c == '/' && strings.HasPrefix(s[i:], "/*"):
+			return fmt.Errorf("invalid %s: %s", what, s)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnMeta describes a result column for typed results.
+type ColumnMeta struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // database type name, e.g. "VARCHAR", "UNSIGNED BIGINT"
+	Kind     string `json:"kind"` // "number", "string", "datetime", "json", or "binary"
+	Nullable bool   `json:"nullable"`
+}
+
+// Column kinds reported in ColumnMeta.Kind.
+const (
+	KindNumber   = "number"
+	KindString   = "string"
+	KindDatetime = "datetime"
+	KindJSON     = "json"
+	KindBinary   = "binary"
+)
+
+// columnMeta builds typed metadata from the driver's column types.
+func columnMeta(cols []string, colTypes []*sql.ColumnType) []ColumnMeta {
+	meta := make([]ColumnMeta, len(cols))
+	for i, name := range cols {
+		meta[i] = ColumnMeta{Name: name, Kind: KindString}
+		if i < len(colTypes) && colTypes[i] != nil {
+			ct := colTypes[i]
+			meta[i].Type = ct.DatabaseTypeName()
+			meta[i].Kind = kindOf(meta[i].Type)
+			meta[i].Nullable, _ = ct.Nullable()
+		}
+	}
+	return meta
+}
+
+// kindOf maps a MySQL type name to the JSON kind it is emitted as.
+// BOOLEAN is an alias for TINYINT(1) and is indistinguishable from other
+// TINYINTs on the wire, so it is reported as a number.
+func kindOf(typeName string) string {
+	t := strings.TrimPrefix(strings.ToUpper(typeName), "UNSIGNED ")
+	switch t {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR",
+		"DECIMAL", "FLOAT", "DOUBLE", "BIT":
+		return KindNumber
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return KindDatetime
+	case "JSON":
+		return KindJSON
+	case "GEOMETRY":
+		return KindBinary
+	}
+	if strings.Contains(t, "BLOB") || strings.Contains(t, "BINARY") {
+		return KindBinary
+	}
+	return KindString
+}
+
+// typedValue converts a scanned driver value into a JSON-friendly value:
+// numbers as JSON numbers (exact server text for DECIMAL and BIGINT), NULL
+// as nil, JSON columns as embedded JSON, and datetimes as RFC 3339 strings
+// in the connection's zone (see connectionZone).
+func typedValue(v interface{}, meta ColumnMeta) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		if strings.EqualFold(meta.Type, "DATE") {
+			return val.Format("2006-01-02")
+		}
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		switch meta.Kind {
+		case KindBinary:
+			return binaryPlaceholder(val)
+		case KindNumber:
+			if strings.EqualFold(meta.Type, "BIT") {
+				return bitValue(val)
+			}
+			return json.Number(string(val))
+		case KindJSON:
+			if json.Valid(val) {
+				return json.RawMessage(append([]byte(nil), val...))
+			}
+		}
+		return string(val)
+	}
+	return v
+}
+
+// bitValue decodes a big-endian BIT(n) value.
+func bitValue(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// binaryPlaceholder is the grid text shown in place of binary data.
+func binaryPlaceholder(b []byte) string {
+	if len(b) == 0 {
+		return "(empty)"
+	}
+	return fmt.Sprintf("(binary %d bytes)", len(b))
+}
+
+// mysqlDateTimeLayout formats a time as a MySQL DATETIME literal, with
+// fractional seconds only when there are any.
+const mysqlDateTimeLayout = "2006-01-02 15:04:05.999999"
+
+// sqlLiteral renders a scanned driver value as a MySQL literal for SQL
+// dumps: numbers unquoted, binary data as hex, and everything else as an
+// escaped string.
+func sqlLiteral(v interface{}, typeName string) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		if strings.EqualFold(typeName, "DATE") {
+			return quoteSQLString(val.Format("2006-01-02"))
+		}
+		return quoteSQLString(val.Format(mysqlDateTimeLayout))
+	case []byte:
+		switch kindOf(typeName) {
+		case KindNumber:
+			if strings.EqualFold(typeName, "BIT") {
+				return strconv.FormatUint(bitValue(val), 10)
+			}
+			return string(val)
+		case KindBinary:
+			if len(val) == 0 {
+				return "''"
+			}
+			return "0x" + hex.EncodeToString(val)
+		}
+		return quoteSQLString(string(val))
+	}
+	return quoteSQLString(fmt.Sprint(v))
+}
+
+// GridNulls says which cells of a grid result (QueryResult.Rows) are SQL
+// NULL, from the [row, column] positions in QueryResult.NullCells. Grid
+// text shows NULL as "NULL"; without positions (nil) that text is taken
+// as NULL, as clients that don't send them expect.
+type GridNulls [][2]int
+
+// lookup returns a test for whether a cell is NULL.
+func (n GridNulls) lookup() func(row, col int, v string) bool {
+	if n == nil {
+		return func(_, _ int, v string) bool { return v == "NULL" }
+	}
+	set := make(map[[2]int]bool, len(n))
+	for _, cell := range n {
+		set[cell] = true
+	}
+	return func(row, col int, _ string) bool { return set[[2]int{row, col}] }
+}
+
+// sqlTextLiteral renders a non-NULL grid cell as a MySQL literal. typeName
+// may be empty when the column type is unknown, in which case every value
+// is quoted.
+func sqlTextLiteral(v, typeName string) string {
+	if v != "" && kindOf(typeName) == KindNumber {
+		return v
+	}
+	return quoteSQLString(v)
+}
+
+// quoteIdent quotes s as a MySQL identifier.
+func quoteIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+// quoteSQLString quotes s as a MySQL string literal, escaping the
+// characters mysql_real_escape_string does.
+func quoteSQLString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '"':
+			b.WriteString(`\"`)
+		case 0x1a:
+			b.WriteString(`\Z`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
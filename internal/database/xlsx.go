@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxSheet = "Sheet1"
+
+// xlsxSheetWriter streams rows into a workbook with a styled header row.
+type xlsxSheetWriter struct {
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+func newXLSXSheetWriter(columns []string) (*xlsxSheetWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Fill:   excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#E0E0E0"}},
+		Border: []excelize.Border{{Type: "bottom", Color: "#999999", Style: 1}},
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// Freeze the header row so it stays visible while scrolling.
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, c := range columns {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: c}
+	}
+	x := &xlsxSheetWriter{file: f, sw: sw, row: 1}
+	if err := x.writeRow(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxSheetWriter) writeRow(cells []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	x.row++
+	return x.sw.SetRow(cell, cells)
+}
+
+// finish writes the workbook to w and releases it.
+func (x *xlsxSheetWriter) finish(w io.Writer) error {
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(w)
+	return err
+}
+
+// ExportResultXLSX writes query result data to an Excel workbook. Cells in
+// numeric columns (per columnTypes, when given) become numbers and NULL
+// cells are left blank.
+func ExportResultXLSX(w io.Writer, columns []string, rows [][]string, nulls GridNulls, columnTypes []string) error {
+	x, err := newXLSXSheetWriter(columns)
+	if err != nil {
+		return err
+	}
+
+	isNull := nulls.lookup()
+	for r, row := range rows {
+		cells := make([]interface{}, len(row))
+		for i, v := range row {
+			if isNull(r, i, v) {
+				continue
+			}
+			typeName := ""
+			if i < len(columnTypes) {
+				typeName = columnTypes[i]
+			}
+			cells[i] = xlsxTextCell(v, typeName)
+		}
+		if err := x.writeRow(cells); err != nil {
+			x.file.Close()
+			return err
+		}
+	}
+	return x.finish(w)
+}
+
+// ExportTableXLSX streams a table, or the rows and columns filter selects,
+// to an Excel workbook.
+func ExportTableXLSX(ctx context.Context, db *sql.DB, dbName, tableName string, filter ExportFilter, w io.Writer, progress ProgressFunc) error {
+	totalRows := filter.countRows(ctx, db, dbName, tableName)
+
+	rows, err := db.QueryContext(ctx, filter.selectQuery(dbName, tableName))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	x, err := newXLSXSheetWriter(cols)
+	if err != nil {
+		return err
+	}
+
+	scanVals := make([]interface{}, len(cols))
+	scanPtrs := make([]interface{}, len(cols))
+	for i := range scanVals {
+		scanPtrs[i] = &scanVals[i]
+	}
+
+	var written int64
+	for rows.Next() {
+		if ctx.Err() != nil {
+			x.file.Close()
+			return ctx.Err()
+		}
+		if err := rows.Scan(scanPtrs...); err != nil {
+			x.file.Close()
+			return err
+		}
+
+		cells := make([]interface{}, len(cols))
+		for i, v := range scanVals {
+			cells[i] = xlsxValue(v, colTypes[i].DatabaseTypeName())
+		}
+		if err := x.writeRow(cells); err != nil {
+			x.file.Close()
+			return err
+		}
+
+		written++
+		if progress != nil && written%500 == 0 {
+			if !progress(written, totalRows) {
+				x.file.Close()
+				return fmt.Errorf("cancelled")
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		x.file.Close()
+		return err
+	}
+
+	if progress != nil {
+		progress(written, totalRows)
+	}
+	return x.finish(w)
+}
+
+// xlsxValue converts a scanned driver value into a spreadsheet cell value:
+// numbers stay numeric, datetimes become Excel dates, and NULL is blank.
+func xlsxValue(v interface{}, typeName string) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		return val
+	case int64:
+		return xlsxTextCell(strconv.FormatInt(val, 10), typeName)
+	case uint64:
+		return xlsxTextCell(strconv.FormatUint(val, 10), typeName)
+	case []byte:
+		switch kindOf(typeName) {
+		case KindNumber:
+			if strings.EqualFold(typeName, "BIT") {
+				return bitValue(val)
+			}
+			return xlsxTextCell(string(val), typeName)
+		case KindBinary:
+			return binaryPlaceholder(val)
+		}
+		return string(val)
+	}
+	return v
+}
+
+// xlsxTextCell converts a non-NULL grid cell to a spreadsheet cell value.
+func xlsxTextCell(v, typeName string) interface{} {
+	if kindOf(typeName) == KindNumber {
+		// Excel keeps 15 significant digits; longer values stay text so
+		// BIGINT ids and DECIMALs aren't silently rounded.
+		if f, err := strconv.ParseFloat(v, 64); err == nil && len(v) <= 15 {
+			return f
+		}
+	}
+	return v
+}
@@ -8,34 +8,92 @@ import (
 
 // ConnectionProfile represents a saved database connection.
 type ConnectionProfile struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Host       string `json:"host"`
-	Port       int    `json:"port"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	DefaultDB  string `json:"defaultDb"`
-	UseSSL     bool   `json:"useSsl"`
-	SSHEnabled bool   `json:"sshEnabled"`
-	SSHHost    string `json:"sshHost"`
-	SSHPort    int    `json:"sshPort"`
-	SSHUser    string `json:"sshUser"`
-	SSHAuth    string `json:"sshAuth"` // "key" or "password"
-	SSHKeyPath string `json:"sshKeyPath"`
-	SSHPass    string `json:"sshPassword"`
-	SortOrder  int    `json:"sortOrder"`
-	CreatedAt  string `json:"createdAt"`
-	UpdatedAt  string `json:"updatedAt"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	SocketPath  string `json:"socketPath"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DefaultDB   string `json:"defaultDb"`
+	UseSSL      bool   `json:"useSsl"`
+	SSLMode     string `json:"sslMode"` // "", "disable", "require", "verify-ca", "verify-full"
+	SSLCAPath   string `json:"sslCaPath"`
+	SSLCertPath string `json:"sslCertPath"`
+	SSLKeyPath  string `json:"sslKeyPath"`
+	SSHEnabled  bool   `json:"sshEnabled"`
+	SSHHost     string `json:"sshHost"`
+	SSHPort     int    `json:"sshPort"`
+	SSHUser     string `json:"sshUser"`
+	SSHAuth     string `json:"sshAuth"` // "key" or "password"
+	SSHKeyPath  string `json:"sshKeyPath"`
+	SSHPass     string `json:"sshPassword"`
+
+	// SSHPassphrase unlocks an encrypted SSH key; stored encrypted like
+	// the passwords.
+	SSHPassphrase string `json:"sshKeyPassphrase"`
+
+	MaxOpenConns           int `json:"maxOpenConns"`
+	MaxIdleConns           int `json:"maxIdleConns"`
+	ConnMaxLifetimeSeconds int `json:"connMaxLifetimeSeconds"`
+
+	// Charset and Collation are the connection character set ("" means
+	// utf8mb4) and collation ("" means the charset's default).
+	Charset   string `json:"charset"`
+	Collation string `json:"collation"`
+
+	// TimeZone is "UTC", an offset such as "+02:00", or an IANA zone
+	// name; "" keeps the server's zone.
+	TimeZone string `json:"timeZone"`
+
+	// InitialSQL holds statements run on each new connection, such as
+	// SET sql_mode = '...'.
+	InitialSQL string `json:"initialSql"`
+
+	// Color and Environment tag the profile so tabs on production servers
+	// stand out. Color is a CSS color; Environment is a label such as
+	// "dev", "staging", or "prod".
+	Color       string `json:"color"`
+	Environment string `json:"environment"`
+
+	// ReadOnly rejects statements that could modify data before they
+	// reach the server.
+	ReadOnly bool `json:"readOnly"`
+
+	GroupID   string `json:"groupId"`   // "" when not in a group
+	SortOrder int    `json:"sortOrder"` // order within the group
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+const connectionColumns = `
+	id, name, host, port, socket_path, username, password, default_db, use_ssl, ssl_mode, ssl_ca_path, ssl_cert_path, ssl_key_path,
+	ssh_enabled, ssh_host, ssh_port, ssh_user, ssh_auth, ssh_key_path, ssh_password, ssh_key_passphrase,
+	max_open_conns, max_idle_conns, conn_max_lifetime, charset, collation, time_zone, initial_sql,
+	color, environment, read_only, group_id, sort_order, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanConnection(row rowScanner) (ConnectionProfile, error) {
+	var c ConnectionProfile
+	var useSSL, sshEnabled, readOnly int
+	err := row.Scan(
+		&c.ID, &c.Name, &c.Host, &c.Port, &c.SocketPath, &c.Username, &c.Password, &c.DefaultDB, &useSSL, &c.SSLMode, &c.SSLCAPath, &c.SSLCertPath, &c.SSLKeyPath,
+		&sshEnabled, &c.SSHHost, &c.SSHPort, &c.SSHUser, &c.SSHAuth, &c.SSHKeyPath, &c.SSHPass, &c.SSHPassphrase,
+		&c.MaxOpenConns, &c.MaxIdleConns, &c.ConnMaxLifetimeSeconds, &c.Charset, &c.Collation, &c.TimeZone, &c.InitialSQL,
+		&c.Color, &c.Environment, &readOnly, &c.GroupID, &c.SortOrder, &c.CreatedAt, &c.UpdatedAt,
+	)
+	c.UseSSL = useSSL == 1
+	c.SSHEnabled = sshEnabled == 1
+	c.ReadOnly = readOnly == 1
+	return c, err
 }
 
 // ListConnections returns all saved connection profiles ordered by sort_order.
 func (s *Store) ListConnections() ([]ConnectionProfile, error) {
-	rows, err := s.db.Query(`
-		SELECT id, name, host, port, username, password, default_db, use_ssl,
-		       ssh_enabled, ssh_host, ssh_port, ssh_user, ssh_auth, ssh_key_path, ssh_password,
-		       sort_order, created_at, updated_at
-		FROM connections ORDER BY sort_order, name
-	`)
+	rows, err := s.db.Query("SELECT " + connectionColumns + " FROM connections ORDER BY sort_order, name")
 	if err != nil {
 		return nil, err
 	}
@@ -43,17 +101,10 @@ func (s *Store) ListConnections() ([]ConnectionProfile, error) {
 
 	var conns []ConnectionProfile
 	for rows.Next() {
-		var c ConnectionProfile
-		var useSSL, sshEnabled int
-		if err := rows.Scan(
-			&c.ID, &c.Name, &c.Host, &c.Port, &c.Username, &c.Password, &c.DefaultDB, &useSSL,
-			&sshEnabled, &c.SSHHost, &c.SSHPort, &c.SSHUser, &c.SSHAuth, &c.SSHKeyPath, &c.SSHPass,
-			&c.SortOrder, &c.CreatedAt, &c.UpdatedAt,
-		); err != nil {
+		c, err := scanConnection(rows)
+		if err != nil {
 			return nil, err
 		}
-		c.UseSSL = useSSL == 1
-		c.SSHEnabled = sshEnabled == 1
 		conns = append(conns, c)
 	}
 	return conns, rows.Err()
@@ -61,23 +112,10 @@ func (s *Store) ListConnections() ([]ConnectionProfile, error) {
 
 // GetConnection retrieves a single connection profile by ID.
 func (s *Store) GetConnection(id string) (*ConnectionProfile, error) {
-	var c ConnectionProfile
-	var useSSL, sshEnabled int
-	err := s.db.QueryRow(`
-		SELECT id, name, host, port, username, password, default_db, use_ssl,
-		       ssh_enabled, ssh_host, ssh_port, ssh_user, ssh_auth, ssh_key_path, ssh_password,
-		       sort_order, created_at, updated_at
-		FROM connections WHERE id = ?
-	`, id).Scan(
-		&c.ID, &c.Name, &c.Host, &c.Port, &c.Username, &c.Password, &c.DefaultDB, &useSSL,
-		&sshEnabled, &c.SSHHost, &c.SSHPort, &c.SSHUser, &c.SSHAuth, &c.SSHKeyPath, &c.SSHPass,
-		&c.SortOrder, &c.CreatedAt, &c.UpdatedAt,
-	)
+	c, err := scanConnection(s.db.QueryRow("SELECT "+connectionColumns+" FROM connections WHERE id = ?", id))
 	if err != nil {
 		return nil, err
 	}
-	c.UseSSL = useSSL == 1
-	c.SSHEnabled = sshEnabled == 1
 	return &c, nil
 }
 
@@ -87,6 +125,9 @@ func (s *Store) SaveConnection(c *ConnectionProfile) error {
 	now := time.Now().UTC().Format(time.RFC3339)
 	if c.ID == "" {
 		c.ID = uuid.New().String()
+	}
+	// Only used when inserting; the upsert keeps an existing created_at.
+	if c.CreatedAt == "" {
 		c.CreatedAt = now
 	}
 	c.UpdatedAt = now
@@ -99,31 +140,81 @@ func (s *Store) SaveConnection(c *ConnectionProfile) error {
 	if c.SSHEnabled {
 		sshEnabled = 1
 	}
+	readOnly := 0
+	if c.ReadOnly {
+		readOnly = 1
+	}
 
 	_, err := s.db.Exec(`
-		INSERT INTO connections (id, name, host, port, username, password, default_db, use_ssl,
-		                         ssh_enabled, ssh_host, ssh_port, ssh_user, ssh_auth, ssh_key_path, ssh_password,
-		                         sort_order, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO connections (`+connectionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name=excluded.name, host=excluded.host, port=excluded.port,
+			socket_path=excluded.socket_path,
 			username=excluded.username, password=excluded.password,
 			default_db=excluded.default_db, use_ssl=excluded.use_ssl,
+			ssl_mode=excluded.ssl_mode, ssl_ca_path=excluded.ssl_ca_path,
+			ssl_cert_path=excluded.ssl_cert_path, ssl_key_path=excluded.ssl_key_path,
 			ssh_enabled=excluded.ssh_enabled, ssh_host=excluded.ssh_host,
 			ssh_port=excluded.ssh_port, ssh_user=excluded.ssh_user,
 			ssh_auth=excluded.ssh_auth, ssh_key_path=excluded.ssh_key_path,
-			ssh_password=excluded.ssh_password, sort_order=excluded.sort_order,
+			ssh_password=excluded.ssh_password, ssh_key_passphrase=excluded.ssh_key_passphrase,
+			max_open_conns=excluded.max_open_conns, max_idle_conns=excluded.max_idle_conns,
+			conn_max_lifetime=excluded.conn_max_lifetime,
+			charset=excluded.charset, collation=excluded.collation, time_zone=excluded.time_zone,
+			initial_sql=excluded.initial_sql,
+			color=excluded.color, environment=excluded.environment, read_only=excluded.read_only,
+			group_id=excluded.group_id, sort_order=excluded.sort_order,
 			updated_at=excluded.updated_at
 	`,
-		c.ID, c.Name, c.Host, c.Port, c.Username, c.Password, c.DefaultDB, useSSL,
-		sshEnabled, c.SSHHost, c.SSHPort, c.SSHUser, c.SSHAuth, c.SSHKeyPath, c.SSHPass,
-		c.SortOrder, c.CreatedAt, c.UpdatedAt,
+		c.ID, c.Name, c.Host, c.Port, c.SocketPath, c.Username, c.Password, c.DefaultDB, useSSL, c.SSLMode, c.SSLCAPath, c.SSLCertPath, c.SSLKeyPath,
+		sshEnabled, c.SSHHost, c.SSHPort, c.SSHUser, c.SSHAuth, c.SSHKeyPath, c.SSHPass, c.SSHPassphrase,
+		c.MaxOpenConns, c.MaxIdleConns, c.ConnMaxLifetimeSeconds, c.Charset, c.Collation, c.TimeZone, c.InitialSQL,
+		c.Color, c.Environment, readOnly, c.GroupID, c.SortOrder, c.CreatedAt, c.UpdatedAt,
 	)
 	return err
 }
 
-// DeleteConnection removes a connection profile by ID.
+// ReorderConnections sets sort_order to each ID's position in orderedIDs,
+// leaving every other column, including updated_at, untouched.
+func (s *Store) ReorderConnections(orderedIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE connections SET sort_order = ? WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, id := range orderedIDs {
+		if _, err := stmt.Exec(i, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteConnection removes a connection profile by ID, along with its
+// pinned objects and recently used list.
 func (s *Store) DeleteConnection(id string) error {
-	_, err := s.db.Exec("DELETE FROM connections WHERE id = ?", id)
-	return err
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM pinned_objects WHERE profile_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM recent_objects WHERE profile_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM connections WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ConnectionGroup is a folder of connection profiles in the sidebar.
+type ConnectionGroup struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	SortOrder int    `json:"sortOrder"`
+}
+
+// ListGroups returns all connection groups ordered by sort_order.
+func (s *Store) ListGroups() ([]ConnectionGroup, error) {
+	rows, err := s.db.Query("SELECT id, name, sort_order FROM connection_groups ORDER BY sort_order, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []ConnectionGroup
+	for rows.Next() {
+		var g ConnectionGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.SortOrder); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// CreateGroup adds a connection group, placed after the existing ones.
+func (s *Store) CreateGroup(name string) (*ConnectionGroup, error) {
+	g := &ConnectionGroup{ID: uuid.New().String(), Name: name}
+	err := s.db.QueryRow("SELECT IFNULL(MAX(sort_order), -1) + 1 FROM connection_groups").Scan(&g.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(
+		"INSERT INTO connection_groups (id, name, sort_order) VALUES (?, ?, ?)",
+		g.ID, g.Name, g.SortOrder,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// RenameGroup changes a group's name.
+func (s *Store) RenameGroup(id, name string) error {
+	res, err := s.db.Exec("UPDATE connection_groups SET name = ? WHERE id = ?", name, id)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, "group", id)
+}
+
+// DeleteGroup removes a group. Its connections are kept and become
+// ungrouped.
+func (s *Store) DeleteGroup(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE connections SET group_id = '' WHERE group_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM connection_groups WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AssignConnectionGroup moves a connection into a group, or out of any
+// group when groupID is "".
+func (s *Store) AssignConnectionGroup(connID, groupID string) error {
+	if groupID != "" {
+		var exists int
+		err := s.db.QueryRow("SELECT 1 FROM connection_groups WHERE id = ?", groupID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("group not found: %s", groupID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	res, err := s.db.Exec("UPDATE connections SET group_id = ? WHERE id = ?", groupID, connID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, "connection", connID)
+}
+
+// requireRow turns an update that matched nothing into a not-found error.
+func requireRow(res sql.Result, what, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%s not found: %s", what, id)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// SSHHostKey is an SSH host key the user accepted on first connect.
+type SSHHostKey struct {
+	Host      string `json:"host"` // as in known_hosts, e.g. "[db.example.com]:2222"
+	KeyType   string `json:"keyType"`
+	Key       string `json:"key"` // authorized_keys format
+	CreatedAt string `json:"createdAt"`
+}
+
+// ListSSHHostKeys returns every accepted host key, by host.
+func (s *Store) ListSSHHostKeys() ([]SSHHostKey, error) {
+	rows, err := s.db.Query(`
+		SELECT host, key_type, public_key, created_at
+		FROM ssh_host_keys
+		ORDER BY host, key_type
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []SSHHostKey{}
+	for rows.Next() {
+		var k SSHHostKey
+		if err := rows.Scan(&k.Host, &k.KeyType, &k.Key, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// SSHHostKeys returns the accepted keys of one host, in authorized_keys
+// format.
+func (s *Store) SSHHostKeys(host string) ([]string, error) {
+	rows, err := s.db.Query("SELECT public_key FROM ssh_host_keys WHERE host = ?", host)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// SaveSSHHostKey accepts a host key, replacing any accepted key of the
+// same type for the host.
+func (s *Store) SaveSSHHostKey(k *SSHHostKey) error {
+	if k.Host == "" || k.KeyType == "" || k.Key == "" {
+		return fmt.Errorf("a host key needs a host, a key type, and a key")
+	}
+	k.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := s.db.Exec(`
+		INSERT INTO ssh_host_keys (host, key_type, public_key, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(host, key_type) DO UPDATE SET
+			public_key=excluded.public_key, created_at=excluded.created_at
+	`, k.Host, k.KeyType, k.Key, k.CreatedAt)
+	return err
+}
+
+// DeleteSSHHostKey forgets an accepted host key.
+func (s *Store) DeleteSSHHostKey(host, keyType string) error {
+	_, err := s.db.Exec("DELETE FROM ssh_host_keys WHERE host = ? AND key_type = ?", host, keyType)
+	return err
+}
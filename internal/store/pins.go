@@ -0,0 +1,125 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PinnedObject is a database object pinned to the top of a connection
+// profile's sidebar.
+type PinnedObject struct {
+	ID        string `json:"id"`
+	ProfileID string `json:"profileId"`
+	Database  string `json:"database"`
+	Name      string `json:"name"`
+	Type      string `json:"type"` // one of PinTypes
+	SortOrder int    `json:"sortOrder"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// PinTypes are the object types that can be pinned.
+var PinTypes = map[string]bool{
+	"table": true, "view": true, "procedure": true, "function": true, "trigger": true, "event": true,
+}
+
+// ListPins returns a profile's pinned objects in sidebar order.
+func (s *Store) ListPins(profileID string) ([]PinnedObject, error) {
+	rows, err := s.db.Query(`
+		SELECT id, profile_id, database_name, object_name, object_type, sort_order, created_at
+		FROM pinned_objects
+		WHERE profile_id = ?
+		ORDER BY sort_order, database_name, object_name
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []PinnedObject{}
+	for rows.Next() {
+		var p PinnedObject
+		if err := rows.Scan(&p.ID, &p.ProfileID, &p.Database, &p.Name, &p.Type, &p.SortOrder, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		pins = append(pins, p)
+	}
+	return pins, rows.Err()
+}
+
+// AddPin pins an object for a profile, after its existing pins. Pinning an
+// object twice returns the existing pin.
+func (s *Store) AddPin(p *PinnedObject) error {
+	if !PinTypes[p.Type] {
+		return fmt.Errorf("cannot pin objects of type %q", p.Type)
+	}
+	if p.ProfileID == "" || p.Database == "" || p.Name == "" {
+		return fmt.Errorf("a pin needs a profile, a database, and an object name")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		SELECT id, sort_order, created_at FROM pinned_objects
+		WHERE profile_id = ? AND database_name = ? AND object_name = ? AND object_type = ?
+	`, p.ProfileID, p.Database, p.Name, p.Type).Scan(&p.ID, &p.SortOrder, &p.CreatedAt)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	p.ID = uuid.New().String()
+	p.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	err = tx.QueryRow("SELECT IFNULL(MAX(sort_order), -1) + 1 FROM pinned_objects WHERE profile_id = ?", p.ProfileID).Scan(&p.SortOrder)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO pinned_objects (id, profile_id, database_name, object_name, object_type, sort_order, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, p.ID, p.ProfileID, p.Database, p.Name, p.Type, p.SortOrder, p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeletePin removes one of a profile's pins.
+func (s *Store) DeletePin(profileID, id string) error {
+	res, err := s.db.Exec("DELETE FROM pinned_objects WHERE id = ? AND profile_id = ?", id, profileID)
+	if err != nil {
+		return err
+	}
+	return requireRow(res, "pin", id)
+}
+
+// ReorderPins sets sort_order of a profile's pins to each ID's position in
+// orderedIDs.
+func (s *Store) ReorderPins(profileID string, orderedIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE pinned_objects SET sort_order = ? WHERE id = ? AND profile_id = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, id := range orderedIDs {
+		if _, err := stmt.Exec(i, id, profileID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
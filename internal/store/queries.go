@@ -0,0 +1,122 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedQuery is a named, reusable SQL snippet.
+type SavedQuery struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	SQL         string   `json:"sql"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	ProfileID   string   `json:"profileId"` // "" means usable with any connection
+
+	// Parameters names the placeholders in SQL the user fills in before
+	// running it.
+	Parameters []string `json:"parameters"`
+
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+const savedQueryColumns = `id, name, sql, description, tags, profile_id, parameters, created_at, updated_at`
+
+func scanSavedQuery(row rowScanner) (SavedQuery, error) {
+	var q SavedQuery
+	var tags, params string
+	if err := row.Scan(&q.ID, &q.Name, &q.SQL, &q.Description, &tags, &q.ProfileID, &params, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		return q, err
+	}
+	if err := json.Unmarshal([]byte(tags), &q.Tags); err != nil {
+		return q, fmt.Errorf("saved query %s tags: %w", q.ID, err)
+	}
+	if err := json.Unmarshal([]byte(params), &q.Parameters); err != nil {
+		return q, fmt.Errorf("saved query %s parameters: %w", q.ID, err)
+	}
+	return q, nil
+}
+
+// ListSavedQueries returns saved queries ordered by name. With a profileID
+// it returns that profile's queries plus the ones not tied to a profile.
+func (s *Store) ListSavedQueries(profileID string) ([]SavedQuery, error) {
+	query := "SELECT " + savedQueryColumns + " FROM saved_queries"
+	var args []interface{}
+	if profileID != "" {
+		query += " WHERE profile_id IN ('', ?)"
+		args = append(args, profileID)
+	}
+	rows, err := s.db.Query(query+" ORDER BY name COLLATE NOCASE", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []SavedQuery
+	for rows.Next() {
+		q, err := scanSavedQuery(rows)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// GetSavedQuery retrieves a saved query by ID.
+func (s *Store) GetSavedQuery(id string) (*SavedQuery, error) {
+	q, err := scanSavedQuery(s.db.QueryRow("SELECT "+savedQueryColumns+" FROM saved_queries WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// SaveQuery creates or updates a saved query.
+func (s *Store) SaveQuery(q *SavedQuery) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if q.ID == "" {
+		q.ID = uuid.New().String()
+	}
+	if q.CreatedAt == "" {
+		q.CreatedAt = now
+	}
+	q.UpdatedAt = now
+	if q.Tags == nil {
+		q.Tags = []string{}
+	}
+	if q.Parameters == nil {
+		q.Parameters = []string{}
+	}
+	tags, err := json.Marshal(q.Tags)
+	if err != nil {
+		return err
+	}
+	params, err := json.Marshal(q.Parameters)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO saved_queries (`+savedQueryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name=excluded.name, sql=excluded.sql, description=excluded.description,
+			tags=excluded.tags, profile_id=excluded.profile_id,
+			parameters=excluded.parameters, updated_at=excluded.updated_at
+	`,
+		q.ID, q.Name, q.SQL, q.Description, string(tags), q.ProfileID, string(params), q.CreatedAt, q.UpdatedAt,
+	)
+	return err
+}
+
+// DeleteSavedQuery removes a saved query by ID.
+func (s *Store) DeleteSavedQuery(id string) error {
+	_, err := s.db.Exec("DELETE FROM saved_queries WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,84 @@
+package store
+
+import "time"
+
+// RecentLimit is how many databases, and separately how many tables, are
+// kept in each profile's recently used list.
+const RecentLimit = 20
+
+// recentTimeLayout is RFC 3339 with fixed-width nanoseconds, so entries
+// from the same second still sort in order as text.
+const recentTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// RecentObject is a database or table recently opened with a profile.
+type RecentObject struct {
+	Database string `json:"database"`
+	Table    string `json:"table"` // "" for a database
+	UsedAt   string `json:"usedAt"`
+}
+
+// TouchRecent records that a profile opened a database, or a table in it
+// when table isn't "", and drops the oldest entries past RecentLimit.
+func (s *Store) TouchRecent(profileID, database, table string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(recentTimeLayout)
+	_, err = tx.Exec(`
+		INSERT INTO recent_objects (profile_id, database_name, table_name, used_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(profile_id, database_name, table_name) DO UPDATE SET used_at = excluded.used_at
+	`, profileID, database, table, now)
+	if err != nil {
+		return err
+	}
+
+	isTable := table != ""
+	_, err = tx.Exec(`
+		DELETE FROM recent_objects
+		WHERE profile_id = ? AND (table_name != '') = ? AND rowid NOT IN (
+			SELECT rowid FROM recent_objects
+			WHERE profile_id = ? AND (table_name != '') = ?
+			ORDER BY used_at DESC
+			LIMIT ?
+		)
+	`, profileID, isTable, profileID, isTable, RecentLimit)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListRecent returns a profile's recently used databases and tables, most
+// recent first.
+func (s *Store) ListRecent(profileID string) ([]RecentObject, error) {
+	rows, err := s.db.Query(`
+		SELECT database_name, table_name, used_at
+		FROM recent_objects
+		WHERE profile_id = ?
+		ORDER BY used_at DESC
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recent := []RecentObject{}
+	for rows.Next() {
+		var r RecentObject
+		if err := rows.Scan(&r.Database, &r.Table, &r.UsedAt); err != nil {
+			return nil, err
+		}
+		recent = append(recent, r)
+	}
+	return recent, rows.Err()
+}
+
+// ClearRecent forgets a profile's recently used list.
+func (s *Store) ClearRecent(profileID string) error {
+	_, err := s.db.Exec("DELETE FROM recent_objects WHERE profile_id = ?", profileID)
+	return err
+}
@@ -2,8 +2,10 @@ package store
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	_ "modernc.org/sqlite"
 )
@@ -50,13 +52,73 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
+// migrations are the schema steps, applied in order and tracked by the
+// schema_version config key: a database at version n has run the first n.
+// Released steps must never change; add new ones at the end.
+var migrations = []func(tx *sql.Tx) error{
+	migrateBaseline,
+	migrateConnectionCharset,
+	migrateConnectionTimeZone,
+	migrateConnectionInitialSQL,
+	migratePinnedObjects,
+	migrateRecentObjects,
+	migrateConnectionSSHPassphrase,
+	migrateSSHHostKeys,
+}
+
 func (s *Store) migrate() error {
 	_, err := s.db.Exec(`
 		CREATE TABLE IF NOT EXISTS app_config (
 			key   TEXT PRIMARY KEY,
 			value TEXT NOT NULL
-		);
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	version, err := s.GetConfigInt("schema_version", 0)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if err := s.runMigration(i+1, migrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// runMigration applies one step and records its version atomically.
+func (s *Store) runMigration(version int, step func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := step(tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO app_config (key, value) VALUES ('schema_version', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		strconv.Itoa(version),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+// migrateBaseline brings a database from before versioning up to date. It
+// is idempotent because such databases may already have any of these
+// tables and columns.
+func migrateBaseline(tx *sql.Tx) error {
+	_, err := tx.Exec(`
 		CREATE TABLE IF NOT EXISTS connections (
 			id            TEXT PRIMARY KEY,
 			name          TEXT NOT NULL,
@@ -77,10 +139,148 @@ func (s *Store) migrate() error {
 			created_at    TEXT NOT NULL DEFAULT (datetime('now')),
 			updated_at    TEXT NOT NULL DEFAULT (datetime('now'))
 		);
+
+		CREATE TABLE IF NOT EXISTS connection_groups (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			sort_order INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS saved_queries (
+			id          TEXT PRIMARY KEY,
+			name        TEXT NOT NULL,
+			sql         TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			tags        TEXT NOT NULL DEFAULT '[]',
+			profile_id  TEXT NOT NULL DEFAULT '',
+			parameters  TEXT NOT NULL DEFAULT '[]',
+			created_at  TEXT NOT NULL DEFAULT (datetime('now')),
+			updated_at  TEXT NOT NULL DEFAULT (datetime('now'))
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Columns added after the original connections schema.
+	for _, col := range []struct{ name, def string }{
+		{"ssl_mode", "TEXT NOT NULL DEFAULT ''"},
+		{"ssl_ca_path", "TEXT NOT NULL DEFAULT ''"},
+		{"ssl_cert_path", "TEXT NOT NULL DEFAULT ''"},
+		{"ssl_key_path", "TEXT NOT NULL DEFAULT ''"},
+		{"socket_path", "TEXT NOT NULL DEFAULT ''"},
+		{"max_open_conns", "INTEGER NOT NULL DEFAULT 0"},
+		{"max_idle_conns", "INTEGER NOT NULL DEFAULT 0"},
+		{"conn_max_lifetime", "INTEGER NOT NULL DEFAULT 0"},
+		{"group_id", "TEXT NOT NULL DEFAULT ''"},
+		{"color", "TEXT NOT NULL DEFAULT ''"},
+		{"environment", "TEXT NOT NULL DEFAULT ''"},
+		{"read_only", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		if err := addColumn(tx, "connections", col.name, col.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateConnectionCharset adds the per-profile connection charset and
+// collation.
+func migrateConnectionCharset(tx *sql.Tx) error {
+	if err := addColumn(tx, "connections", "charset", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return addColumn(tx, "connections", "collation", "TEXT NOT NULL DEFAULT ''")
+}
+
+// migrateConnectionTimeZone adds the per-profile connection time zone.
+func migrateConnectionTimeZone(tx *sql.Tx) error {
+	return addColumn(tx, "connections", "time_zone", "TEXT NOT NULL DEFAULT ''")
+}
+
+// migrateConnectionInitialSQL adds the per-profile statements run on each
+// new connection.
+func migrateConnectionInitialSQL(tx *sql.Tx) error {
+	return addColumn(tx, "connections", "initial_sql", "TEXT NOT NULL DEFAULT ''")
+}
+
+// migratePinnedObjects adds the per-profile pinned objects.
+func migratePinnedObjects(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE pinned_objects (
+			id            TEXT PRIMARY KEY,
+			profile_id    TEXT NOT NULL,
+			database_name TEXT NOT NULL,
+			object_name   TEXT NOT NULL,
+			object_type   TEXT NOT NULL,
+			sort_order    INTEGER NOT NULL DEFAULT 0,
+			created_at    TEXT NOT NULL DEFAULT (datetime('now')),
+			UNIQUE (profile_id, database_name, object_name, object_type)
+		)
 	`)
 	return err
 }
 
+// migrateRecentObjects adds the per-profile recently used databases and
+// tables.
+func migrateRecentObjects(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE recent_objects (
+			profile_id    TEXT NOT NULL,
+			database_name TEXT NOT NULL,
+			table_name    TEXT NOT NULL DEFAULT '',
+			used_at       TEXT NOT NULL,
+			PRIMARY KEY (profile_id, database_name, table_name)
+		)
+	`)
+	return err
+}
+
+// migrateConnectionSSHPassphrase adds the passphrase of an encrypted SSH
+// key, which used to share ssh_password.
+func migrateConnectionSSHPassphrase(tx *sql.Tx) error {
+	return addColumn(tx, "connections", "ssh_key_passphrase", "TEXT NOT NULL DEFAULT ''")
+}
+
+// migrateSSHHostKeys adds the SSH host keys accepted on first connect.
+func migrateSSHHostKeys(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE ssh_host_keys (
+			host       TEXT NOT NULL,
+			key_type   TEXT NOT NULL,
+			public_key TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (host, key_type)
+		)
+	`)
+	return err
+}
+
+// addColumn adds a column to an existing table unless it is already present.
+func addColumn(tx *sql.Tx, table, column, def string) error {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
+	return err
+}
+
 // GetConfig retrieves a config value by key.
 func (s *Store) GetConfig(key string) (string, error) {
 	var val string
@@ -91,6 +291,19 @@ func (s *Store) GetConfig(key string) (string, error) {
 	return val, err
 }
 
+// GetConfigInt retrieves an integer config value, returning def when unset.
+func (s *Store) GetConfigInt(key string, def int) (int, error) {
+	val, err := s.GetConfig(key)
+	if err != nil || val == "" {
+		return def, err
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return def, fmt.Errorf("config %s: %w", key, err)
+	}
+	return n, nil
+}
+
 // SetConfig sets a config key-value pair.
 func (s *Store) SetConfig(key, value string) error {
 	_, err := s.db.Exec(